@@ -0,0 +1,23 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// NewAuditLogger 创建审计日志，每条事件以一行JSON输出，字段可直接被日志采集系统解析检索。
+// 未配置audit.path时输出到标准错误，与运行日志一起采集
+func NewAuditLogger(cfg *Config) (*slog.Logger, error) {
+	var w io.Writer = os.Stderr
+	if cfg.Audit != nil && cfg.Audit.Path != "" {
+		f, err := os.OpenFile(cfg.Audit.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		w = f
+	}
+
+	return slog.New(slog.NewJSONHandler(w, nil)).With("log_type", "audit"), nil
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"errors"
 	"os"
 	"sync"
 	"time"
@@ -25,14 +26,53 @@ func NewConfig() *Config {
 		if err != nil {
 			panic(err)
 		}
+
+		// 兼容旧配置：未配置storage时使用minio配置块
+		if config.Storage == nil && config.Minio != nil {
+			config.Storage = &StorageConfig{
+				Driver:   "minio",
+				BucketID: config.Minio.BucketID,
+			}
+			if err = config.Storage.Options.Encode(config.Minio); err != nil {
+				panic(err)
+			}
+		}
 	})
 	return config
 }
 
 type Config struct {
-	Server *ServerConfig `yaml:"server"`
-	DB     *DBConfig     `yaml:"db"`
-	Minio  *MinioConfig  `yaml:"minio"`
+	Server  *ServerConfig  `yaml:"server"`
+	DB      *DBConfig      `yaml:"db"`
+	Storage *StorageConfig `yaml:"storage"`
+	Minio   *MinioConfig   `yaml:"minio"` // 已废弃，请使用storage配置
+
+	UploadPolicies map[string]*UploadPolicy `yaml:"uploadPolicies"` // 按桶ID配置的上传策略，未配置的桶使用内置的默认策略
+	Import         *ImportConfig            `yaml:"import"`         // URL导入配置，未配置时使用默认值
+	Idempotency    *IdempotencyConfig       `yaml:"idempotency"`    // 幂等键配置，未配置时使用默认值
+	CacheControl   *CacheControlConfig      `yaml:"cacheControl"`   // 下载和元数据接口返回的Cache-Control
+	Tus            *TusConfig               `yaml:"tus"`            // tus断点续传配置，未配置时使用默认值
+	Audit          *AuditConfig             `yaml:"audit"`          // 审计日志配置
+}
+
+// sampleSignKey 示例配置曾使用的签名密钥，不能用于部署
+const sampleSignKey = "change-me"
+
+// CheckSignKey 存储的预签名URL由FileEngine自身签发时(signsURLs为true)，
+// 签名密钥为空或为示例值时任何人都可以伪造上传和下载URL
+func (c *Config) CheckSignKey(signsURLs bool) error {
+	if !signsURLs {
+		return nil
+	}
+	if c.Server.SignKey == "" || c.Server.SignKey == sampleSignKey {
+		return errors.New("server.signKey must be set to a random secret when storage URLs are signed by FileEngine")
+	}
+	return nil
+}
+
+// DefaultBucketID 返回当前存储后端使用的默认桶ID
+func (c *Config) DefaultBucketID() string {
+	return c.Storage.BucketID
 }
 
 type ServerConfig struct {
@@ -40,10 +80,15 @@ type ServerConfig struct {
 	PrivateAddr     string        `yaml:"privateAddr"`     // 内网地址
 	UploadTimeout   time.Duration `yaml:"uploadTimeout"`   // 上传URL有效时间
 	DownloadTimeout time.Duration `yaml:"downloadTimeout"` // 下载URL有效时间
+	PublicURL       string        `yaml:"publicURL"`       // 对外访问地址，用于生成由FileEngine自身提供的预签名URL
+	SignKey         string        `yaml:"signKey"`         // 预签名URL签名密钥
 }
 
 type DBConfig struct {
-	Type            string        `yaml:"type"`
+	Type            string        `yaml:"type"`        // 数据库类型(mysql、postgres、sqlite、memory)
+	Path            string        `yaml:"path"`        // sqlite数据库文件路径
+	SSLMode         string        `yaml:"sslMode"`     // postgres连接的sslmode，默认disable
+	AutoMigrate     bool          `yaml:"autoMigrate"` // 启动时自动执行未执行的迁移
 	Host            string        `yaml:"host"`
 	Port            int           `yaml:"port"`
 	User            string        `yaml:"user"`
@@ -54,9 +99,81 @@ type DBConfig struct {
 	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
 }
 
+type StorageConfig struct {
+	Driver   string    `yaml:"driver"`   // 存储驱动(minio、local、memory等)
+	BucketID string    `yaml:"bucketID"` // 默认桶ID
+	Dedup    string    `yaml:"dedup"`    // 去重模式，为空不去重，object按整个对象的内容去重，chunk按内容定义的块去重
+	Options  yaml.Node `yaml:"options"`  // 驱动相关配置，由各驱动自行解析
+}
+
+// DecodeOptions 将驱动相关配置解析到v中
+func (s *StorageConfig) DecodeOptions(v interface{}) error {
+	if s.Options.IsZero() {
+		return nil
+	}
+	return s.Options.Decode(v)
+}
+
+// UploadPolicy 上传策略，未配置的字段使用内置默认值
+type UploadPolicy struct {
+	AllowedExtensions    []string `yaml:"allowedExtensions"`    // 允许的扩展名，["*"]表示不限制
+	DeniedExtensions     []string `yaml:"deniedExtensions"`     // 禁止的扩展名，优先于允许列表
+	AllowedMIMETypes     []string `yaml:"allowedMIMETypes"`     // 允许的内容类型，支持image/*形式的通配，为空不限制
+	DeniedMIMETypes      []string `yaml:"deniedMIMETypes"`      // 禁止的内容类型，优先于允许列表
+	MinSize              int64    `yaml:"minSize"`              // 最小文件大小(字节)
+	MaxSize              int64    `yaml:"maxSize"`              // 最大文件大小(字节)，为0时使用各上传方式自身的上限
+	FilenamePattern      string   `yaml:"filenamePattern"`      // 文件名需匹配的正则表达式(RE2语法)
+	UnicodeNormalization string   `yaml:"unicodeNormalization"` // 文件名的Unicode规范化形式(NFC、NFD、NFKC、NFKD)，为空不规范化
+	MaxFilenameBytes     int      `yaml:"maxFilenameBytes"`     // 文件名最大字节数
+}
+
+// URL导入配置
+type ImportConfig struct {
+	AllowedSchemes       []string      `yaml:"allowedSchemes"`       // 允许的源URL协议，默认http、https
+	AllowPrivateNetworks bool          `yaml:"allowPrivateNetworks"` // 允许访问内网、本机等非公网地址，仅用于测试环境
+	Timeout              time.Duration `yaml:"timeout"`              // 单个任务的超时时间(含排队时间)，默认30分钟
+	MaxRedirects         int           `yaml:"maxRedirects"`         // 最大重定向次数，默认5
+	Concurrency          int           `yaml:"concurrency"`          // 同时执行的任务数，默认4
+}
+
+// 幂等键配置
+type IdempotencyConfig struct {
+	TTL         time.Duration `yaml:"ttl"`         // 保存响应的时间，有效期内相同幂等键的请求返回首次请求的响应，默认24小时
+	LockTimeout time.Duration `yaml:"lockTimeout"` // 请求的最长处理时间，超过后视为处理中断，相同幂等键的请求可重新处理，默认1小时
+	WaitTimeout time.Duration `yaml:"waitTimeout"` // 相同幂等键的请求正在处理时的最长等待时间，超时返回409，默认10秒
+}
+
+// tus断点续传配置
+type TusConfig struct {
+	Expiration time.Duration `yaml:"expiration"` // 未完成的上传自最后一次追加数据起的保留时间，过期后清理已上传的分片，默认24小时
+}
+
+// 审计日志配置
+type AuditConfig struct {
+	Path string `yaml:"path"` // 审计日志文件路径，以JSON Lines格式追加写入，为空时输出到标准错误
+}
+
+// Cache-Control配置，下载时按内容类型、桶、默认值的顺序取第一个配置的值
+type CacheControlConfig struct {
+	Default      string            `yaml:"default"`      // 默认值，为空不返回Cache-Control
+	Buckets      map[string]string `yaml:"buckets"`      // 按桶ID配置
+	ContentTypes map[string]string `yaml:"contentTypes"` // 按内容类型配置，支持image/*形式的通配，优先于桶的配置
+	Meta         string            `yaml:"meta"`         // 元数据接口使用的值，默认no-cache
+}
+
+// minio驱动配置
 type MinioConfig struct {
 	Endpoint  string `yaml:"endpoint"`
 	AccessKey string `yaml:"accessKey"`
 	SecretKey string `yaml:"secretKey"`
+	Secure    bool   `yaml:"secure"`
 	BucketID  string `yaml:"bucketID"`
+
+	StreamPartSize uint64 `yaml:"streamPartSize"` // 长度未知的流式上传的分片大小(字节)，默认16MiB
+	StreamThreads  uint   `yaml:"streamThreads"`  // 流式上传并发上传的分片数，默认4
+}
+
+// local驱动配置
+type LocalConfig struct {
+	RootDir string `yaml:"rootDir"` // 本地存储根目录
 }
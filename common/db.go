@@ -5,15 +5,60 @@ import (
 	"fmt"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
-func NewDB(cfg *Config) (dbPool *sql.DB, err error) {
-	dsn := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?parseTime=true", cfg.DB.User, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.DBName)
+// NewDB 创建业务使用的连接池
+func NewDB(cfg *Config) (*sql.DB, error) {
+	return openDB(cfg, false)
+}
+
+// NewMigrationDB 创建执行迁移使用的连接池。MySQL的迁移文件包含多条语句，需要开启multiStatements，
+// 仅在迁移使用的连接上开启，业务连接不允许一次执行多条语句
+func NewMigrationDB(cfg *Config) (*sql.DB, error) {
+	return openDB(cfg, true)
+}
+
+func openDB(cfg *Config, migration bool) (dbPool *sql.DB, err error) {
+	var driverName, dsn string
+	switch cfg.DB.Type {
+	case "sqlite":
+		// 开启WAL和busy_timeout，避免并发写入时报database is locked
+		driverName = "sqlite"
+		dsn = fmt.Sprintf("file:%v?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", cfg.DB.Path)
+	case "postgres":
+		driverName = "pgx"
+		sslMode := cfg.DB.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		// 会话时区固定为UTC，TIMESTAMP列读出的时间与写入时刻一致
+		dsn = fmt.Sprintf("host=%v port=%v user=%v password=%v dbname=%v sslmode=%v timezone=UTC", cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.DBName, sslMode)
+	case "mysql", "":
+		driverName = "mysql"
+		dsn = fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?parseTime=true", cfg.DB.User, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.DBName)
+		if migration {
+			dsn += "&multiStatements=true"
+		}
+	default:
+		return nil, fmt.Errorf("NewDB(): unsupported database type %s", cfg.DB.Type)
+	}
 
-	if dbPool, err = sql.Open("mysql", dsn); err != nil {
+	if dbPool, err = sql.Open(driverName, dsn); err != nil {
 		return nil, fmt.Errorf("NewDB(): failed to open database, error: %w", err)
 	}
 
+	if cfg.DB.MaxOpenConns > 0 {
+		dbPool.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	}
+	if cfg.DB.MaxIdleConns > 0 {
+		dbPool.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	}
+	if cfg.DB.ConnMaxLifetime > 0 {
+		dbPool.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	}
+
 	if err = dbPool.Ping(); err != nil {
 		return nil, fmt.Errorf("NewDB(): failed to ping database, error: %w", err)
 	}
@@ -0,0 +1,159 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObjectURLPath 由FileEngine自身提供的对象读写路径
+const ObjectURLPath = "/api/v1/file-engine/objects"
+
+var (
+	ErrSignatureMissing = errors.New("signature is missing")
+	ErrSignatureInvalid = errors.New("signature is invalid")
+	ErrSignatureExpired = errors.New("signature is expired")
+)
+
+// URLSigner 对FileEngine签发的预签名URL进行HMAC-SHA256签名与校验
+type URLSigner struct {
+	key []byte
+}
+
+func NewURLSigner(key string) *URLSigner {
+	return &URLSigner{
+		key: []byte(key),
+	}
+}
+
+// Sign 为指定的请求方法和对象生成签名，返回包含expires和signature的查询参数
+func (s *URLSigner) Sign(method, bucketID, objectName string, expiresAt time.Time, params url.Values) url.Values {
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = append([]string(nil), v...)
+	}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", s.signature(method, bucketID, objectName, query))
+
+	return query
+}
+
+// Verify 校验查询参数中的签名及有效期
+func (s *URLSigner) Verify(method, bucketID, objectName string, query url.Values) error {
+	signature := query.Get("signature")
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	expected := s.signature(method, bucketID, objectName, query)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrSignatureInvalid
+	}
+
+	if time.Now().Unix() > expires {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+// signature 签名内容：请求方法、桶ID、对象名以及除signature外按键排序的全部查询参数
+func (s *URLSigner) signature(method, bucketID, objectName string, query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if k == "signature" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	builder.WriteString(strings.ToUpper(method) + "\n")
+	builder.WriteString(bucketID + "\n")
+	builder.WriteString(objectName + "\n")
+	for _, k := range keys {
+		for _, v := range query[k] {
+			builder.WriteString(url.QueryEscape(k) + "=" + url.QueryEscape(v) + "\n")
+		}
+	}
+
+	return s.sum(builder.String())
+}
+
+// PostPolicy FileEngine签发的表单上传策略，限定桶、对象名、类型和大小
+type PostPolicy struct {
+	Expiration  int64  `json:"expiration"`
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	MinSize     int64  `json:"min_size"`
+	MaxSize     int64  `json:"max_size"`
+}
+
+// SignPolicy 返回base64编码的策略及其签名
+func (s *URLSigner) SignPolicy(policy *PostPolicy) (encoded, signature string) {
+	content, _ := json.Marshal(policy)
+	encoded = base64.StdEncoding.EncodeToString(content)
+	return encoded, s.sum(encoded)
+}
+
+// VerifyPolicy 校验策略签名及有效期，返回解码后的策略
+func (s *URLSigner) VerifyPolicy(encoded, signature string) (*PostPolicy, error) {
+	if encoded == "" || signature == "" {
+		return nil, ErrSignatureMissing
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sum(encoded))) {
+		return nil, ErrSignatureInvalid
+	}
+
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+	policy := &PostPolicy{}
+	if err = json.Unmarshal(content, policy); err != nil {
+		return nil, ErrSignatureInvalid
+	}
+
+	if time.Now().Unix() > policy.Expiration {
+		return nil, ErrSignatureExpired
+	}
+
+	return policy, nil
+}
+
+func (s *URLSigner) sum(content string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// BuildBucketURL 拼接由FileEngine自身提供的表单上传URL
+func BuildBucketURL(baseURL, bucketID string) string {
+	return fmt.Sprintf("%s%s/%s", strings.TrimSuffix(baseURL, "/"), ObjectURLPath, url.PathEscape(bucketID))
+}
+
+// BuildObjectURL 拼接由FileEngine自身提供的对象访问URL
+func BuildObjectURL(baseURL, bucketID, objectName string, query url.Values) string {
+	segments := strings.Split(objectName, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return fmt.Sprintf("%s%s/%s/%s?%s", strings.TrimSuffix(baseURL, "/"), ObjectURLPath, url.PathEscape(bucketID), strings.Join(segments, "/"), query.Encode())
+}
@@ -0,0 +1,336 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type DBBlob struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewDBBlob() interfaces.DBBlob {
+	return &DBBlob{
+		db:      dbPool,
+		dialect: newDialect(dbType),
+	}
+}
+
+func (d *DBBlob) GetObjectBlob(ctx context.Context, bucketID, objectName string) (*interfaces.ObjectBlob, error) {
+	// 分块存储对象的hash为整个对象的摘要，不指向数据块
+	query := `
+		SELECT
+			o.bucket_id,
+			o.object_name,
+			o.hash,
+			COALESCE(b.storage_key, ''),
+			o.size,
+			o.content_type,
+			o.chunk_count,
+			o.create_time
+		FROM t_object_blob o
+		LEFT JOIN t_blob b ON b.bucket_id = o.bucket_id AND b.hash = o.hash AND o.chunk_count = 0
+		WHERE o.bucket_id = ? AND o.object_name = ?
+	`
+
+	var object interfaces.ObjectBlob
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), bucketID, objectName).Scan(
+		&object.BucketID,
+		&object.ObjectName,
+		&object.Hash,
+		&object.StorageKey,
+		&object.Size,
+		&object.ContentType,
+		&object.ChunkCount,
+		&object.CreateTime)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &object, nil
+}
+
+func (d *DBBlob) GetObjectChunks(ctx context.Context, bucketID, objectName string) ([]*interfaces.ObjectChunk, error) {
+	query := `
+		SELECT
+			c.seq,
+			c.hash,
+			b.storage_key,
+			c.chunk_offset,
+			c.size
+		FROM t_object_chunk c
+		JOIN t_blob b ON b.bucket_id = c.bucket_id AND b.hash = c.hash
+		WHERE c.bucket_id = ? AND c.object_name = ?
+		ORDER BY c.seq
+	`
+
+	rows, err := d.db.QueryContext(ctx, d.dialect.rebind(query), bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*interfaces.ObjectChunk
+	for rows.Next() {
+		var chunk interfaces.ObjectChunk
+		if err = rows.Scan(&chunk.Seq, &chunk.Hash, &chunk.StorageKey, &chunk.Offset, &chunk.Size); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, &chunk)
+	}
+
+	return chunks, rows.Err()
+}
+
+func (d *DBBlob) AcquireBlob(ctx context.Context, bucketID, hash string) (storageKey string, found bool, err error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	storageKey, found, err = d.incrementBlob(ctx, tx, bucketID, hash)
+	if err != nil || !found {
+		return "", false, err
+	}
+
+	return storageKey, true, tx.Commit()
+}
+
+func (d *DBBlob) CreateBlob(ctx context.Context, bucketID, hash, storageKey string, size int64) (string, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if storageKey, err = d.createBlob(ctx, tx, bucketID, hash, storageKey, size); err != nil {
+		return "", err
+	}
+
+	return storageKey, tx.Commit()
+}
+
+func (d *DBBlob) ReleaseBlobs(ctx context.Context, bucketID string, hashes []string) ([]string, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	released, err := d.releaseBlobs(ctx, tx, bucketID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return released, tx.Commit()
+}
+
+func (d *DBBlob) AttachBlob(ctx context.Context, object *interfaces.ObjectBlob) (storageKey string, released []string, err error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+
+	// 先引用新数据块再释放原数据块，同一对象以相同内容覆盖时数据块不会被误删
+	storageKey, err = d.createBlob(ctx, tx, object.BucketID, object.Hash, object.StorageKey, object.Size)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if released, _, err = d.detachObject(ctx, tx, object.BucketID, object.ObjectName); err != nil {
+		return "", nil, err
+	}
+
+	if err = d.insertObject(ctx, tx, object, 0); err != nil {
+		return "", nil, err
+	}
+
+	return storageKey, released, tx.Commit()
+}
+
+func (d *DBBlob) AttachChunks(ctx context.Context, object *interfaces.ObjectBlob, chunks []*interfaces.ObjectChunk) (released []string, err error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if released, _, err = d.detachObject(ctx, tx, object.BucketID, object.ObjectName); err != nil {
+		return nil, err
+	}
+
+	if err = d.insertObject(ctx, tx, object, len(chunks)); err != nil {
+		return nil, err
+	}
+
+	insert := `INSERT INTO t_object_chunk (bucket_id, object_name, seq, hash, chunk_offset, size) VALUES (?, ?, ?, ?, ?, ?)`
+	for _, chunk := range chunks {
+		if _, err = tx.ExecContext(ctx, d.dialect.rebind(insert), object.BucketID, object.ObjectName, chunk.Seq, chunk.Hash, chunk.Offset, chunk.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	return released, tx.Commit()
+}
+
+func (d *DBBlob) DetachBlob(ctx context.Context, bucketID, objectName string) (released []string, found bool, err error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	released, found, err = d.detachObject(ctx, tx, bucketID, objectName)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	return released, true, tx.Commit()
+}
+
+// incrementBlob 已有数据块的引用计数加一并返回其存储对象名
+func (d *DBBlob) incrementBlob(ctx context.Context, tx *sql.Tx, bucketID, hash string) (storageKey string, found bool, err error) {
+	update := `UPDATE t_blob SET ref_count = ref_count + 1 WHERE bucket_id = ? AND hash = ?`
+	result, err := tx.ExecContext(ctx, d.dialect.rebind(update), bucketID, hash)
+	if err != nil {
+		return "", false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return "", false, err
+	}
+
+	query := `SELECT storage_key FROM t_blob WHERE bucket_id = ? AND hash = ?`
+	if err = tx.QueryRowContext(ctx, d.dialect.rebind(query), bucketID, hash).Scan(&storageKey); err != nil {
+		return "", false, err
+	}
+
+	return storageKey, true, nil
+}
+
+// createBlob 引用已有数据块，不存在时以storageKey创建
+func (d *DBBlob) createBlob(ctx context.Context, tx *sql.Tx, bucketID, hash, storageKey string, size int64) (string, error) {
+	existing, found, err := d.incrementBlob(ctx, tx, bucketID, hash)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return existing, nil
+	}
+
+	insert := `INSERT INTO t_blob (bucket_id, hash, storage_key, size, ref_count) VALUES (?, ?, ?, ?, 1)`
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(insert), bucketID, hash, storageKey, size); err != nil {
+		if d.dialect.isDuplicateEntry(err) {
+			return "", fmt.Errorf("%w: %v", interfaces.ErrDuplicateEntry, err)
+		}
+		return "", err
+	}
+
+	return storageKey, nil
+}
+
+func (d *DBBlob) insertObject(ctx context.Context, tx *sql.Tx, object *interfaces.ObjectBlob, chunkCount int) error {
+	insert := `INSERT INTO t_object_blob (bucket_id, object_name, hash, size, content_type, chunk_count) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := tx.ExecContext(ctx, d.dialect.rebind(insert), object.BucketID, object.ObjectName, object.Hash, object.Size, object.ContentType, chunkCount)
+	if err != nil && d.dialect.isDuplicateEntry(err) {
+		return fmt.Errorf("%w: %v", interfaces.ErrDuplicateEntry, err)
+	}
+	return err
+}
+
+// detachObject 删除对象映射及块清单，释放对象引用的数据块
+func (d *DBBlob) detachObject(ctx context.Context, tx *sql.Tx, bucketID, objectName string) (released []string, found bool, err error) {
+	var hash string
+	var chunkCount int
+	query := `SELECT hash, chunk_count FROM t_object_blob WHERE bucket_id = ? AND object_name = ?`
+	err = tx.QueryRowContext(ctx, d.dialect.rebind(query), bucketID, objectName).Scan(&hash, &chunkCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	hashes := []string{hash}
+	if chunkCount > 0 {
+		if hashes, err = d.chunkHashes(ctx, tx, bucketID, objectName); err != nil {
+			return nil, false, err
+		}
+		query = `DELETE FROM t_object_chunk WHERE bucket_id = ? AND object_name = ?`
+		if _, err = tx.ExecContext(ctx, d.dialect.rebind(query), bucketID, objectName); err != nil {
+			return nil, false, err
+		}
+	}
+
+	query = `DELETE FROM t_object_blob WHERE bucket_id = ? AND object_name = ?`
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(query), bucketID, objectName); err != nil {
+		return nil, false, err
+	}
+
+	if released, err = d.releaseBlobs(ctx, tx, bucketID, hashes); err != nil {
+		return nil, false, err
+	}
+
+	return released, true, nil
+}
+
+func (d *DBBlob) chunkHashes(ctx context.Context, tx *sql.Tx, bucketID, objectName string) ([]string, error) {
+	query := `SELECT hash FROM t_object_chunk WHERE bucket_id = ? AND object_name = ? ORDER BY seq`
+	rows, err := tx.QueryContext(ctx, d.dialect.rebind(query), bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err = rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// releaseBlobs 数据块引用计数减一，归零时删除数据块记录并返回其存储对象名
+func (d *DBBlob) releaseBlobs(ctx context.Context, tx *sql.Tx, bucketID string, hashes []string) ([]string, error) {
+	var released []string
+	for _, hash := range hashes {
+		update := `UPDATE t_blob SET ref_count = ref_count - 1 WHERE bucket_id = ? AND hash = ?`
+		if _, err := tx.ExecContext(ctx, d.dialect.rebind(update), bucketID, hash); err != nil {
+			return nil, err
+		}
+
+		var storageKey string
+		var refCount int64
+		query := `SELECT storage_key, ref_count FROM t_blob WHERE bucket_id = ? AND hash = ?`
+		err := tx.QueryRowContext(ctx, d.dialect.rebind(query), bucketID, hash).Scan(&storageKey, &refCount)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return nil, err
+		}
+		if refCount > 0 {
+			continue
+		}
+
+		if _, err = tx.ExecContext(ctx, d.dialect.rebind(`DELETE FROM t_blob WHERE bucket_id = ? AND hash = ?`), bucketID, hash); err != nil {
+			return nil, err
+		}
+		released = append(released, storageKey)
+	}
+
+	return released, nil
+}
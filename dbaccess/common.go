@@ -4,8 +4,13 @@ import "database/sql"
 
 var (
 	dbPool *sql.DB
+	dbType string
 )
 
 func SetDBPool(i *sql.DB) {
 	dbPool = i
 }
+
+func SetDBType(i string) {
+	dbType = i
+}
@@ -0,0 +1,95 @@
+package dbaccess
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// dialect 屏蔽不同数据库在占位符和错误码上的差异
+type dialect interface {
+	// rebind 将查询中的?占位符转换为数据库对应的占位符
+	rebind(query string) string
+	// isDuplicateEntry 判断错误是否为违反唯一约束
+	isDuplicateEntry(err error) bool
+	// forUpdate 返回加在SELECT末尾的行锁子句，不支持行锁的数据库返回空字符串
+	forUpdate() string
+}
+
+func newDialect(dbType string) dialect {
+	switch dbType {
+	case "sqlite":
+		return sqliteDialect{}
+	case "postgres":
+		return postgresDialect{}
+	default:
+		return mysqlDialect{}
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	// 1062: ER_DUP_ENTRY
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+func (mysqlDialect) forUpdate() string {
+	return " FOR UPDATE"
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) isDuplicateEntry(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// forUpdate SQLite的写事务串行执行，无需行锁
+func (sqliteDialect) forUpdate() string {
+	return ""
+}
+
+type postgresDialect struct{}
+
+// rebind 将?占位符转换为$1、$2...形式，查询中不包含字符串字面量形式的?
+func (postgresDialect) rebind(query string) string {
+	var builder strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+func (postgresDialect) isDuplicateEntry(err error) bool {
+	var pgErr *pgconn.PgError
+	// 23505: unique_violation
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (postgresDialect) forUpdate() string {
+	return " FOR UPDATE"
+}
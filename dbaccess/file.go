@@ -4,66 +4,292 @@ import (
 	"FileEngine/interfaces"
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 )
 
 type file struct {
 	ID          string     `json:"id"`
 	Name        string     `json:"name"`
+	TargetName  string     `json:"target_name"`
 	BucketID    string     `json:"bucket_id"`
+	ObjectName  string     `json:"object_name"`
 	Icon        string     `json:"icon"`
 	Size        int64      `json:"size"`
 	ContentType string     `json:"content_type"`
+	Status      string     `json:"status"`
+	SHA256      string     `json:"sha256"`
+	ExpireAt    int64      `json:"expire_at"`
 	CreateTime  *time.Time `json:"create_time"`
 	UpdateTime  *time.Time `json:"update_time"`
 }
 
 type DBFile struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
 }
 
 func NewDBFile() interfaces.DBFile {
 	return &DBFile{
-		db: dbPool,
+		db:      dbPool,
+		dialect: newDialect(dbType),
 	}
 }
 
 func (d *DBFile) CreateFile(ctx context.Context, file *interfaces.FileInfo) error {
 	query := `
 		INSERT INTO t_file 
-		(id, name, content_type, bucket_id, size, icon)
+		(id, name, target_name, content_type, bucket_id, object_name, size, icon, status, sha256, expire_at)
 		VALUES 
-		(?, ?, ?, ?, ?, ?)
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := d.db.ExecContext(ctx, query,
-		file.ID, file.Name, file.ContentType, file.BucketID, file.Size, file.Icon)
+	status := file.Status
+	if status == "" {
+		status = interfaces.FileStatusActive
+	}
+
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query),
+		file.ID, file.Name, file.TargetName, file.ContentType, file.BucketID, file.ObjectName, file.Size, file.Icon, status, file.SHA256, file.ExpireAt)
+	if err != nil && d.dialect.isDuplicateEntry(err) {
+		return fmt.Errorf("%w: %v", interfaces.ErrDuplicateEntry, err)
+	}
 
 	return err
 }
 
+func (d *DBFile) ActivateFile(ctx context.Context, fileID, name, replacedID string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = d.activateFile(ctx, tx, fileID, name, replacedID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// activateFile 在事务中删除被覆盖的同名记录，并将待确认的记录改为name并置为可用
+func (d *DBFile) activateFile(ctx context.Context, tx *sql.Tx, fileID, name, replacedID string) error {
+	if replacedID != "" {
+		if err := d.deleteReplaced(ctx, tx, replacedID, name); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE t_file SET name = ?, target_name = '', status = ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, d.dialect.rebind(query), name, interfaces.FileStatusActive, fileID)
+	if err != nil {
+		if d.dialect.isDuplicateEntry(err) {
+			return fmt.Errorf("%w: %v", interfaces.ErrDuplicateEntry, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (d *DBFile) ReplaceFile(ctx context.Context, fileID string, replaced *interfaces.FileInfo) (bool, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err = d.replaceFile(ctx, tx, fileID, replaced); err != nil {
+		return false, err
+	}
+
+	referenced, err := d.hasObjectReference(ctx, tx, replaced.BucketID, replaced.ObjectName, "")
+	if err != nil {
+		return false, err
+	}
+
+	return referenced, tx.Commit()
+}
+
+// replaceFile 在事务中以待确认记录fileID的对象和元数据替换可用文件replaced的内容，并删除待确认记录
+func (d *DBFile) replaceFile(ctx context.Context, tx *sql.Tx, fileID string, replaced *interfaces.FileInfo) error {
+	query := `SELECT bucket_id, object_name, size, content_type, icon, sha256 FROM t_file WHERE id = ?` + d.dialect.forUpdate()
+	var file file
+	err := tx.QueryRowContext(ctx, d.dialect.rebind(query), fileID).Scan(
+		&file.BucketID, &file.ObjectName, &file.Size, &file.ContentType, &file.Icon, &file.SHA256)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("file %s not found", fileID)
+		}
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(`DELETE FROM t_file WHERE id = ?`), fileID); err != nil {
+		return err
+	}
+
+	// 以读取时的名称和对象名为条件，被并发删除、改名或替换时不更新
+	query = `
+		UPDATE t_file 
+		SET bucket_id = ?, object_name = ?, size = ?, content_type = ?, icon = ?, sha256 = ? 
+		WHERE id = ? AND name = ? AND object_name = ? AND status = ?
+	`
+	result, err := tx.ExecContext(ctx, d.dialect.rebind(query),
+		file.BucketID, file.ObjectName, file.Size, file.ContentType, file.Icon, file.SHA256,
+		replaced.ID, replaced.Name, replaced.ObjectName, interfaces.FileStatusActive)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: file %s with name %s has been replaced", interfaces.ErrDuplicateEntry, replaced.ID, replaced.Name)
+	}
+
+	return nil
+}
+
+func (d *DBFile) ActivateFiles(ctx context.Context, files []*interfaces.FileInfo) ([]string, []*interfaces.StorageObject, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, len(files))
+	var replacedObjects []*interfaces.StorageObject
+	for i, file := range files {
+		ids[i] = file.ID
+		if file.TargetName == "" {
+			if err = d.activatePending(ctx, tx, file.ID); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		replaced, err := d.getFileByNameForUpdate(ctx, tx, file.TargetName)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case replaced == nil:
+			err = d.activateFile(ctx, tx, file.ID, file.TargetName, "")
+		case replaced.Status == interfaces.FileStatusActive:
+			err = d.replaceFile(ctx, tx, file.ID, replaced)
+			ids[i] = replaced.ID
+		default:
+			err = d.activateFile(ctx, tx, file.ID, file.TargetName, replaced.ID)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if replaced != nil {
+			replacedObjects = append(replacedObjects, &interfaces.StorageObject{BucketID: replaced.BucketID, ObjectName: replaced.ObjectName})
+		}
+	}
+
+	// 被替换的对象可能被批次中的其他文件或秒传引用，全部替换完成后再检查
+	var released []*interfaces.StorageObject
+	for _, object := range replacedObjects {
+		referenced, err := d.hasObjectReference(ctx, tx, object.BucketID, object.ObjectName, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		if !referenced {
+			released = append(released, object)
+		}
+	}
+
+	return ids, released, tx.Commit()
+}
+
+// activatePending 将以最终名称创建的待确认记录置为可用
+func (d *DBFile) activatePending(ctx context.Context, tx *sql.Tx, fileID string) error {
+	query := `UPDATE t_file SET status = ? WHERE id = ? AND status = ?`
+	result, err := tx.ExecContext(ctx, d.dialect.rebind(query), interfaces.FileStatusActive, fileID, interfaces.FileStatusPending)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("pending file %s not found", fileID)
+	}
+
+	return nil
+}
+
+// getFileByNameForUpdate 在事务中加行锁读取同名记录，不存在时返回nil
+func (d *DBFile) getFileByNameForUpdate(ctx context.Context, tx *sql.Tx, name string) (*interfaces.FileInfo, error) {
+	query := `SELECT id, name, bucket_id, object_name, status FROM t_file WHERE name = ?` + d.dialect.forUpdate()
+
+	var file interfaces.FileInfo
+	err := tx.QueryRowContext(ctx, d.dialect.rebind(query), name).Scan(&file.ID, &file.Name, &file.BucketID, &file.ObjectName, &file.Status)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// deleteReplaced 删除被覆盖的同名记录，记录已被并发删除或改名时返回ErrDuplicateEntry
+func (d *DBFile) deleteReplaced(ctx context.Context, tx *sql.Tx, replacedID, name string) error {
+	query := `DELETE FROM t_file WHERE id = ? AND name = ?`
+	result, err := tx.ExecContext(ctx, d.dialect.rebind(query), replacedID, name)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: file %s with name %s has been replaced", interfaces.ErrDuplicateEntry, replacedID, name)
+	}
+
+	return nil
+}
+
 func (d *DBFile) GetFileByID(ctx context.Context, fileID string) (*interfaces.FileInfo, error) {
 	query := `
 		SELECT 
 			id, 
 			name, 
+			target_name, 
 			content_type, 
 			bucket_id, 
+			object_name, 
 			size, 
 			icon, 
+			status, 
+			sha256, 
+			expire_at, 
 			create_time, 
 			update_time
 		FROM t_file WHERE id = ?
 	`
 
 	var file file
-	err := d.db.QueryRowContext(ctx, query, fileID).Scan(
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), fileID).Scan(
 		&file.ID,
 		&file.Name,
+		&file.TargetName,
 		&file.ContentType,
 		&file.BucketID,
+		&file.ObjectName,
 		&file.Size,
 		&file.Icon,
+		&file.Status,
+		&file.SHA256,
+		&file.ExpireAt,
 		&file.CreateTime,
 		&file.UpdateTime)
 
@@ -82,23 +308,81 @@ func (d *DBFile) GetFileByName(ctx context.Context, name string) (*interfaces.Fi
 		SELECT 
 			id, 
 			name, 
+			target_name, 
 			content_type, 
 			bucket_id, 
+			object_name, 
 			size, 
 			icon, 
+			status, 
+			sha256, 
+			expire_at, 
 			create_time, 
 			update_time
 		FROM t_file WHERE name = ?
 	`
 
 	var file file
-	err := d.db.QueryRowContext(ctx, query, name).Scan(
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), name).Scan(
+		&file.ID,
+		&file.Name,
+		&file.TargetName,
+		&file.ContentType,
+		&file.BucketID,
+		&file.ObjectName,
+		&file.Size,
+		&file.Icon,
+		&file.Status,
+		&file.SHA256,
+		&file.ExpireAt,
+		&file.CreateTime,
+		&file.UpdateTime)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return convertToFileInfo(&file), nil
+}
+
+func (d *DBFile) GetFileByContent(ctx context.Context, bucketID, sha256 string, size int64) (*interfaces.FileInfo, error) {
+	query := `
+		SELECT 
+			id, 
+			name, 
+			target_name, 
+			content_type, 
+			bucket_id, 
+			object_name, 
+			size, 
+			icon, 
+			status, 
+			sha256, 
+			expire_at, 
+			create_time, 
+			update_time
+		FROM t_file 
+		WHERE sha256 = ? AND size = ? AND bucket_id = ? AND status = ?
+		ORDER BY create_time
+		LIMIT 1
+	`
+
+	var file file
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), sha256, size, bucketID, interfaces.FileStatusActive).Scan(
 		&file.ID,
 		&file.Name,
+		&file.TargetName,
 		&file.ContentType,
 		&file.BucketID,
+		&file.ObjectName,
 		&file.Size,
 		&file.Icon,
+		&file.Status,
+		&file.SHA256,
+		&file.ExpireAt,
 		&file.CreateTime,
 		&file.UpdateTime)
 
@@ -112,17 +396,123 @@ func (d *DBFile) GetFileByName(ctx context.Context, name string) (*interfaces.Fi
 	return convertToFileInfo(&file), nil
 }
 
+func (d *DBFile) DeleteFileReference(ctx context.Context, fileID, bucketID, objectName string) (bool, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(`DELETE FROM t_file WHERE id = ?`), fileID); err != nil {
+		return false, err
+	}
+
+	referenced, err := d.hasObjectReference(ctx, tx, bucketID, objectName, fileID)
+	if err != nil {
+		return false, err
+	}
+
+	return referenced, tx.Commit()
+}
+
+func (d *DBFile) HasObjectReference(ctx context.Context, bucketID, objectName, excludeID string) (bool, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	referenced, err := d.hasObjectReference(ctx, tx, bucketID, objectName, excludeID)
+	if err != nil {
+		return false, err
+	}
+
+	return referenced, tx.Commit()
+}
+
+// hasObjectReference 加行锁查询除excludeID外引用存储对象的记录，
+// 与并发的删除和秒传创建记录互斥，保证对象只在没有任何记录引用时被删除
+func (d *DBFile) hasObjectReference(ctx context.Context, tx *sql.Tx, bucketID, objectName, excludeID string) (bool, error) {
+	query := `SELECT id FROM t_file WHERE bucket_id = ? AND object_name = ? AND id <> ? LIMIT 1` + d.dialect.forUpdate()
+
+	var id string
+	err := tx.QueryRowContext(ctx, d.dialect.rebind(query), bucketID, objectName, excludeID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d *DBFile) ListObjects(ctx context.Context, after *interfaces.StorageObject, limit int) ([]*interfaces.StorageObject, error) {
+	query := `SELECT DISTINCT bucket_id, object_name FROM t_file`
+	var args []interface{}
+	if after != nil {
+		query += ` WHERE bucket_id > ? OR (bucket_id = ? AND object_name > ?)`
+		args = append(args, after.BucketID, after.BucketID, after.ObjectName)
+	}
+	query += ` ORDER BY bucket_id, object_name LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.db.QueryContext(ctx, d.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []*interfaces.StorageObject
+	for rows.Next() {
+		var object interfaces.StorageObject
+		if err = rows.Scan(&object.BucketID, &object.ObjectName); err != nil {
+			return nil, err
+		}
+		objects = append(objects, &object)
+	}
+
+	return objects, rows.Err()
+}
+
+func (d *DBFile) UpdateObjectName(ctx context.Context, bucketID, oldName, newName string) (int64, error) {
+	query := `UPDATE t_file SET object_name = ? WHERE bucket_id = ? AND object_name = ?`
+	result, err := d.db.ExecContext(ctx, d.dialect.rebind(query), newName, bucketID, oldName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (d *DBFile) UpdateFileStatus(ctx context.Context, fileID, status string) error {
+	query := `UPDATE t_file SET status = ? WHERE id = ?`
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query), status, fileID)
+	return err
+}
+
+func (d *DBFile) UpdateFileSHA256(ctx context.Context, fileID, sha256 string) error {
+	query := `UPDATE t_file SET sha256 = ? WHERE id = ?`
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query), sha256, fileID)
+	return err
+}
+
+func (d *DBFile) UpdateFileContentType(ctx context.Context, fileID, contentType string) error {
+	query := `UPDATE t_file SET content_type = ? WHERE id = ?`
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query), contentType, fileID)
+	return err
+}
+
 func (d *DBFile) DeleteFile(ctx context.Context, fileID string) error {
 	query := `DELETE FROM t_file WHERE id = ?`
-	_, err := d.db.ExecContext(ctx, query, fileID)
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query), fileID)
 	return err
 }
 
 func (d *DBFile) GetFileList(ctx context.Context, bucketID string, page, pageSize int) ([]*interfaces.FileInfo, int64, error) {
 	// 获取总数
-	countQuery := `SELECT COUNT(*) FROM t_file WHERE bucket_id = ?`
+	countQuery := `SELECT COUNT(*) FROM t_file WHERE bucket_id = ? AND status = ?`
 	var total int64
-	err := d.db.QueryRowContext(ctx, countQuery, bucketID).Scan(&total)
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(countQuery), bucketID, interfaces.FileStatusActive).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -133,19 +523,24 @@ func (d *DBFile) GetFileList(ctx context.Context, bucketID string, page, pageSiz
 		SELECT 
 			id, 
 			name, 
+			target_name, 
 			content_type, 
 			bucket_id, 
+			object_name, 
 			size, 
 			icon, 
+			status, 
+			sha256, 
+			expire_at, 
 			create_time, 
 			update_time
 		FROM t_file 
-		WHERE bucket_id = ?
+		WHERE bucket_id = ? AND status = ?
 		ORDER BY create_time DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := d.db.QueryContext(ctx, query, bucketID, pageSize, offset)
+	rows, err := d.db.QueryContext(ctx, d.dialect.rebind(query), bucketID, interfaces.FileStatusActive, pageSize, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -157,10 +552,15 @@ func (d *DBFile) GetFileList(ctx context.Context, bucketID string, page, pageSiz
 		err := rows.Scan(
 			&file.ID,
 			&file.Name,
+			&file.TargetName,
 			&file.ContentType,
 			&file.BucketID,
+			&file.ObjectName,
 			&file.Size,
 			&file.Icon,
+			&file.Status,
+			&file.SHA256,
+			&file.ExpireAt,
 			&file.CreateTime,
 			&file.UpdateTime)
 		if err != nil {
@@ -176,10 +576,15 @@ func convertToFileInfo(file *file) *interfaces.FileInfo {
 	return &interfaces.FileInfo{
 		ID:          file.ID,
 		Name:        file.Name,
+		TargetName:  file.TargetName,
 		BucketID:    file.BucketID,
+		ObjectName:  file.ObjectName,
 		Icon:        file.Icon,
 		Size:        file.Size,
 		ContentType: file.ContentType,
+		Status:      file.Status,
+		SHA256:      file.SHA256,
+		ExpireAt:    file.ExpireAt,
 		CreateTime:  file.CreateTime,
 		UpdateTime:  file.UpdateTime,
 	}
@@ -0,0 +1,111 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type DBIdempotencyKey struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewDBIdempotencyKey() interfaces.DBIdempotencyKey {
+	return &DBIdempotencyKey{
+		db:      dbPool,
+		dialect: newDialect(dbType),
+	}
+}
+
+func (d *DBIdempotencyKey) CreateIdempotencyKey(ctx context.Context, record *interfaces.IdempotencyKey) error {
+	query := `
+		INSERT INTO t_idempotency_key
+		(idempotency_key, token, fingerprint, status, expire_at)
+		VALUES
+		(?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query),
+		record.Key, record.Token, record.Fingerprint, record.Status, record.ExpireAt)
+	if err != nil && d.dialect.isDuplicateEntry(err) {
+		return fmt.Errorf("%w: %v", interfaces.ErrDuplicateEntry, err)
+	}
+
+	return err
+}
+
+func (d *DBIdempotencyKey) GetIdempotencyKey(ctx context.Context, key string) (*interfaces.IdempotencyKey, error) {
+	query := `
+		SELECT
+			idempotency_key,
+			token,
+			fingerprint,
+			body_digest,
+			status,
+			response_code,
+			response_header,
+			response_body,
+			expire_at,
+			create_time,
+			update_time
+		FROM t_idempotency_key WHERE idempotency_key = ?
+	`
+
+	var record interfaces.IdempotencyKey
+	var header sql.NullString
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), key).Scan(
+		&record.Key,
+		&record.Token,
+		&record.Fingerprint,
+		&record.BodyDigest,
+		&record.Status,
+		&record.ResponseCode,
+		&header,
+		&record.ResponseBody,
+		&record.ExpireAt,
+		&record.CreateTime,
+		&record.UpdateTime)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	record.ResponseHeader = header.String
+
+	return &record, nil
+}
+
+func (d *DBIdempotencyKey) CompleteIdempotencyKey(ctx context.Context, record *interfaces.IdempotencyKey) (int64, error) {
+	query := `
+		UPDATE t_idempotency_key
+		SET status = ?, body_digest = ?, response_code = ?, response_header = ?, response_body = ?, expire_at = ?
+		WHERE idempotency_key = ? AND token = ?
+	`
+
+	result, err := d.db.ExecContext(ctx, d.dialect.rebind(query),
+		interfaces.IdempotencyStatusCompleted, record.BodyDigest, record.ResponseCode, record.ResponseHeader, record.ResponseBody, record.ExpireAt,
+		record.Key, record.Token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (d *DBIdempotencyKey) DeleteIdempotencyKey(ctx context.Context, key, token string) error {
+	query := `DELETE FROM t_idempotency_key WHERE idempotency_key = ? AND token = ?`
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query), key, token)
+	return err
+}
+
+func (d *DBIdempotencyKey) DeleteExpiredIdempotencyKeys(ctx context.Context, expireAt int64) (int64, error) {
+	query := `DELETE FROM t_idempotency_key WHERE expire_at < ?`
+	result, err := d.db.ExecContext(ctx, d.dialect.rebind(query), expireAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
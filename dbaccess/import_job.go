@@ -0,0 +1,82 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"database/sql"
+)
+
+type DBImportJob struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewDBImportJob() interfaces.DBImportJob {
+	return &DBImportJob{
+		db:      dbPool,
+		dialect: newDialect(dbType),
+	}
+}
+
+func (d *DBImportJob) CreateImportJob(ctx context.Context, job *interfaces.ImportJob) error {
+	query := `
+		INSERT INTO t_import_job
+		(id, bucket_id, source_url, filename, conflict, sha256, status, file_id, error_message, expire_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query),
+		job.ID, job.BucketID, job.SourceURL, job.Filename, job.Conflict, job.SHA256, job.Status, job.FileID, job.ErrorMessage, job.ExpireAt)
+
+	return err
+}
+
+func (d *DBImportJob) GetImportJob(ctx context.Context, jobID string) (*interfaces.ImportJob, error) {
+	query := `
+		SELECT
+			id,
+			bucket_id,
+			source_url,
+			filename,
+			conflict,
+			sha256,
+			status,
+			file_id,
+			error_message,
+			expire_at,
+			create_time,
+			update_time
+		FROM t_import_job WHERE id = ?
+	`
+
+	var job interfaces.ImportJob
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), jobID).Scan(
+		&job.ID,
+		&job.BucketID,
+		&job.SourceURL,
+		&job.Filename,
+		&job.Conflict,
+		&job.SHA256,
+		&job.Status,
+		&job.FileID,
+		&job.ErrorMessage,
+		&job.ExpireAt,
+		&job.CreateTime,
+		&job.UpdateTime)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (d *DBImportJob) UpdateImportJob(ctx context.Context, jobID, status, fileID, errorMessage string) error {
+	query := `UPDATE t_import_job SET status = ?, file_id = ?, error_message = ? WHERE id = ?`
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query), status, fileID, errorMessage, jobID)
+	return err
+}
@@ -0,0 +1,183 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryDBBlob 基于内存的去重存储映射，用于嵌入式运行和单元测试
+type MemoryDBBlob struct {
+	mu      sync.Mutex
+	blobs   map[string]*memoryBlob               // bucketID/hash -> blob
+	objects map[string]*interfaces.ObjectBlob    // bucketID/objectName -> object
+	chunks  map[string][]*interfaces.ObjectChunk // bucketID/objectName -> chunks
+}
+
+type memoryBlob struct {
+	storageKey string
+	refCount   int64
+}
+
+func NewMemoryDBBlob() interfaces.DBBlob {
+	return &MemoryDBBlob{
+		blobs:   make(map[string]*memoryBlob),
+		objects: make(map[string]*interfaces.ObjectBlob),
+		chunks:  make(map[string][]*interfaces.ObjectChunk),
+	}
+}
+
+func (d *MemoryDBBlob) GetObjectBlob(ctx context.Context, bucketID, objectName string) (*interfaces.ObjectBlob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	object, ok := d.objects[bucketID+"/"+objectName]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *object
+	if object.ChunkCount == 0 {
+		copied.StorageKey = d.blobs[bucketID+"/"+object.Hash].storageKey
+	}
+	return &copied, nil
+}
+
+func (d *MemoryDBBlob) GetObjectChunks(ctx context.Context, bucketID, objectName string) ([]*interfaces.ObjectChunk, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var chunks []*interfaces.ObjectChunk
+	for _, chunk := range d.chunks[bucketID+"/"+objectName] {
+		copied := *chunk
+		copied.StorageKey = d.blobs[bucketID+"/"+chunk.Hash].storageKey
+		chunks = append(chunks, &copied)
+	}
+	return chunks, nil
+}
+
+func (d *MemoryDBBlob) AcquireBlob(ctx context.Context, bucketID, hash string) (storageKey string, found bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	blob, ok := d.blobs[bucketID+"/"+hash]
+	if !ok {
+		return "", false, nil
+	}
+	blob.refCount++
+	return blob.storageKey, true, nil
+}
+
+func (d *MemoryDBBlob) CreateBlob(ctx context.Context, bucketID, hash, storageKey string, size int64) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.createBlob(bucketID, hash, storageKey), nil
+}
+
+func (d *MemoryDBBlob) ReleaseBlobs(ctx context.Context, bucketID string, hashes []string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.releaseBlobs(bucketID, hashes), nil
+}
+
+func (d *MemoryDBBlob) AttachBlob(ctx context.Context, object *interfaces.ObjectBlob) (storageKey string, released []string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// 先引用新数据块再释放原数据块，同一对象以相同内容覆盖时数据块不会被误删
+	storageKey = d.createBlob(object.BucketID, object.Hash, object.StorageKey)
+	released, _ = d.detachObject(object.BucketID, object.ObjectName)
+	d.insertObject(object, 0)
+
+	return storageKey, released, nil
+}
+
+func (d *MemoryDBBlob) AttachChunks(ctx context.Context, object *interfaces.ObjectBlob, chunks []*interfaces.ObjectChunk) (released []string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	released, _ = d.detachObject(object.BucketID, object.ObjectName)
+	d.insertObject(object, len(chunks))
+
+	copied := make([]*interfaces.ObjectChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		c := *chunk
+		c.StorageKey = ""
+		copied = append(copied, &c)
+	}
+	d.chunks[object.BucketID+"/"+object.ObjectName] = copied
+
+	return released, nil
+}
+
+func (d *MemoryDBBlob) DetachBlob(ctx context.Context, bucketID, objectName string) (released []string, found bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	released, found = d.detachObject(bucketID, objectName)
+	return released, found, nil
+}
+
+// createBlob 引用已有数据块，不存在时以storageKey创建，调用方需持有锁
+func (d *MemoryDBBlob) createBlob(bucketID, hash, storageKey string) string {
+	blob, ok := d.blobs[bucketID+"/"+hash]
+	if !ok {
+		blob = &memoryBlob{storageKey: storageKey}
+		d.blobs[bucketID+"/"+hash] = blob
+	}
+	blob.refCount++
+	return blob.storageKey
+}
+
+// insertObject 写入对象映射，调用方需持有锁
+func (d *MemoryDBBlob) insertObject(object *interfaces.ObjectBlob, chunkCount int) {
+	now := time.Now().Truncate(time.Second)
+	copied := *object
+	copied.StorageKey = ""
+	copied.ChunkCount = chunkCount
+	copied.CreateTime = &now
+	d.objects[object.BucketID+"/"+object.ObjectName] = &copied
+}
+
+// detachObject 删除对象映射及块清单，释放对象引用的数据块，调用方需持有锁
+func (d *MemoryDBBlob) detachObject(bucketID, objectName string) ([]string, bool) {
+	key := bucketID + "/" + objectName
+	object, ok := d.objects[key]
+	if !ok {
+		return nil, false
+	}
+	delete(d.objects, key)
+
+	hashes := []string{object.Hash}
+	if object.ChunkCount > 0 {
+		hashes = hashes[:0]
+		for _, chunk := range d.chunks[key] {
+			hashes = append(hashes, chunk.Hash)
+		}
+		delete(d.chunks, key)
+	}
+
+	return d.releaseBlobs(bucketID, hashes), true
+}
+
+// releaseBlobs 数据块引用计数减一，归零时删除数据块并返回其存储对象名，调用方需持有锁
+func (d *MemoryDBBlob) releaseBlobs(bucketID string, hashes []string) []string {
+	var released []string
+	for _, hash := range hashes {
+		blob, ok := d.blobs[bucketID+"/"+hash]
+		if !ok {
+			continue
+		}
+		blob.refCount--
+		if blob.refCount > 0 {
+			continue
+		}
+
+		delete(d.blobs, bucketID+"/"+hash)
+		released = append(released, blob.storageKey)
+	}
+	return released
+}
@@ -0,0 +1,458 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryDBFile 基于内存的文件记录存储，用于嵌入式运行和单元测试，与t_file表保持相同的name唯一约束
+type MemoryDBFile struct {
+	mu    sync.RWMutex
+	seq   int64
+	files map[string]*memoryFile // id -> file
+	names map[string]string      // name -> id
+}
+
+type memoryFile struct {
+	seq  int64
+	info interfaces.FileInfo
+}
+
+func NewMemoryDBFile() interfaces.DBFile {
+	return &MemoryDBFile{
+		files: make(map[string]*memoryFile),
+		names: make(map[string]string),
+	}
+}
+
+func (d *MemoryDBFile) CreateFile(ctx context.Context, file *interfaces.FileInfo) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.insertFile(file)
+}
+
+func (d *MemoryDBFile) ActivateFile(ctx context.Context, fileID, name, replacedID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.activateFile(fileID, name, replacedID)
+}
+
+// activateFile 删除被覆盖的同名记录，并将待确认的记录改为name并置为可用，调用方需持有锁
+func (d *MemoryDBFile) activateFile(fileID, name, replacedID string) error {
+	if replacedID != "" {
+		if err := d.checkReplaced(replacedID, name); err != nil {
+			return err
+		}
+	}
+	if id, ok := d.names[name]; ok && id != fileID && id != replacedID {
+		return fmt.Errorf("%w '%s' for key 'idx_name'", interfaces.ErrDuplicateEntry, name)
+	}
+
+	file, ok := d.files[fileID]
+	if !ok {
+		return nil
+	}
+	if replacedID != "" {
+		d.deleteFile(replacedID)
+	}
+	now := time.Now().Truncate(time.Second)
+	delete(d.names, file.info.Name)
+	d.names[name] = fileID
+	file.info.Name = name
+	file.info.TargetName = ""
+	file.info.Status = interfaces.FileStatusActive
+	file.info.UpdateTime = &now
+
+	return nil
+}
+
+func (d *MemoryDBFile) ReplaceFile(ctx context.Context, fileID string, replaced *interfaces.FileInfo) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.replaceFile(fileID, replaced); err != nil {
+		return false, err
+	}
+
+	return d.hasObjectReference(replaced.BucketID, replaced.ObjectName, ""), nil
+}
+
+// replaceFile 以待确认记录fileID的对象和元数据替换可用文件replaced的内容，并删除待确认记录，调用方需持有锁
+func (d *MemoryDBFile) replaceFile(fileID string, replaced *interfaces.FileInfo) error {
+	file, ok := d.files[fileID]
+	if !ok {
+		return fmt.Errorf("file %s not found", fileID)
+	}
+	target, ok := d.files[replaced.ID]
+	if !ok || target.info.Name != replaced.Name || target.info.ObjectName != replaced.ObjectName || target.info.Status != interfaces.FileStatusActive {
+		return fmt.Errorf("%w: file %s with name %s has been replaced", interfaces.ErrDuplicateEntry, replaced.ID, replaced.Name)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	target.info.BucketID = file.info.BucketID
+	target.info.ObjectName = file.info.ObjectName
+	target.info.Size = file.info.Size
+	target.info.ContentType = file.info.ContentType
+	target.info.Icon = file.info.Icon
+	target.info.SHA256 = file.info.SHA256
+	target.info.UpdateTime = &now
+	d.deleteFile(fileID)
+
+	return nil
+}
+
+func (d *MemoryDBFile) ActivateFiles(ctx context.Context, files []*interfaces.FileInfo) ([]string, []*interfaces.StorageObject, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// 任一记录失败时恢复到执行前的状态，与数据库事务回滚一致
+	snapshot := d.snapshot()
+	ids, released, err := d.activateFiles(files)
+	if err != nil {
+		d.files, d.names = snapshot.files, snapshot.names
+		return nil, nil, err
+	}
+
+	return ids, released, nil
+}
+
+// activateFiles 按ActivateFiles的约定依次置为可用，调用方需持有锁
+func (d *MemoryDBFile) activateFiles(files []*interfaces.FileInfo) ([]string, []*interfaces.StorageObject, error) {
+	ids := make([]string, len(files))
+	var replacedObjects []*interfaces.StorageObject
+	for i, file := range files {
+		ids[i] = file.ID
+		if file.TargetName == "" {
+			pending, ok := d.files[file.ID]
+			if !ok || pending.info.Status != interfaces.FileStatusPending {
+				return nil, nil, fmt.Errorf("pending file %s not found", file.ID)
+			}
+			now := time.Now().Truncate(time.Second)
+			pending.info.Status = interfaces.FileStatusActive
+			pending.info.UpdateTime = &now
+			continue
+		}
+
+		var replaced *interfaces.FileInfo
+		if id, ok := d.names[file.TargetName]; ok {
+			replaced = copyFileInfo(&d.files[id].info)
+		}
+		var err error
+		switch {
+		case replaced == nil:
+			err = d.activateFile(file.ID, file.TargetName, "")
+		case replaced.Status == interfaces.FileStatusActive:
+			err = d.replaceFile(file.ID, replaced)
+			ids[i] = replaced.ID
+		default:
+			err = d.activateFile(file.ID, file.TargetName, replaced.ID)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if replaced != nil {
+			replacedObjects = append(replacedObjects, &interfaces.StorageObject{BucketID: replaced.BucketID, ObjectName: replaced.ObjectName})
+		}
+	}
+
+	var released []*interfaces.StorageObject
+	for _, object := range replacedObjects {
+		if !d.hasObjectReference(object.BucketID, object.ObjectName, "") {
+			released = append(released, object)
+		}
+	}
+
+	return ids, released, nil
+}
+
+// snapshot 复制全部记录，调用方需持有锁
+func (d *MemoryDBFile) snapshot() *MemoryDBFile {
+	copied := &MemoryDBFile{
+		files: make(map[string]*memoryFile, len(d.files)),
+		names: make(map[string]string, len(d.names)),
+	}
+	for id, file := range d.files {
+		f := *file
+		copied.files[id] = &f
+	}
+	for name, id := range d.names {
+		copied.names[name] = id
+	}
+
+	return copied
+}
+
+// checkReplaced 检查被覆盖的记录仍以name存在，调用方需持有锁
+func (d *MemoryDBFile) checkReplaced(replacedID, name string) error {
+	if file, ok := d.files[replacedID]; !ok || file.info.Name != name {
+		return fmt.Errorf("%w: file %s with name %s has been replaced", interfaces.ErrDuplicateEntry, replacedID, name)
+	}
+	return nil
+}
+
+// insertFile 写入文件记录，调用方需持有锁
+func (d *MemoryDBFile) insertFile(file *interfaces.FileInfo) error {
+	if _, ok := d.files[file.ID]; ok {
+		return fmt.Errorf("%w '%s' for key 'PRIMARY'", interfaces.ErrDuplicateEntry, file.ID)
+	}
+	if _, ok := d.names[file.Name]; ok {
+		return fmt.Errorf("%w '%s' for key 'idx_name'", interfaces.ErrDuplicateEntry, file.Name)
+	}
+
+	// 与数据库默认值保持一致，时间精确到秒
+	now := time.Now().Truncate(time.Second)
+	file.CreateTime = &now
+	file.UpdateTime = &now
+	if file.Status == "" {
+		file.Status = interfaces.FileStatusActive
+	}
+
+	d.seq++
+	d.files[file.ID] = &memoryFile{
+		seq:  d.seq,
+		info: *copyFileInfo(file),
+	}
+	d.names[file.Name] = file.ID
+
+	return nil
+}
+
+func (d *MemoryDBFile) GetFileByID(ctx context.Context, fileID string) (*interfaces.FileInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	file, ok := d.files[fileID]
+	if !ok {
+		return nil, nil
+	}
+
+	return copyFileInfo(&file.info), nil
+}
+
+func (d *MemoryDBFile) GetFileByName(ctx context.Context, name string) (*interfaces.FileInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	fileID, ok := d.names[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return copyFileInfo(&d.files[fileID].info), nil
+}
+
+func (d *MemoryDBFile) GetFileByContent(ctx context.Context, bucketID, sha256 string, size int64) (*interfaces.FileInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	// 与数据库查询一致，返回最早创建的文件
+	var matched *memoryFile
+	for _, file := range d.files {
+		info := &file.info
+		if info.SHA256 != sha256 || info.Size != size || info.BucketID != bucketID || info.Status != interfaces.FileStatusActive {
+			continue
+		}
+		if matched == nil || file.seq < matched.seq {
+			matched = file
+		}
+	}
+	if matched == nil {
+		return nil, nil
+	}
+
+	return copyFileInfo(&matched.info), nil
+}
+
+func (d *MemoryDBFile) DeleteFileReference(ctx context.Context, fileID, bucketID, objectName string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deleteFile(fileID)
+
+	return d.hasObjectReference(bucketID, objectName, fileID), nil
+}
+
+func (d *MemoryDBFile) HasObjectReference(ctx context.Context, bucketID, objectName, excludeID string) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.hasObjectReference(bucketID, objectName, excludeID), nil
+}
+
+func (d *MemoryDBFile) hasObjectReference(bucketID, objectName, excludeID string) bool {
+	for id, file := range d.files {
+		if id != excludeID && file.info.BucketID == bucketID && file.info.ObjectName == objectName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *MemoryDBFile) ListObjects(ctx context.Context, after *interfaces.StorageObject, limit int) ([]*interfaces.StorageObject, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	less := func(a, b *interfaces.StorageObject) bool {
+		if a.BucketID != b.BucketID {
+			return a.BucketID < b.BucketID
+		}
+		return a.ObjectName < b.ObjectName
+	}
+
+	seen := make(map[interfaces.StorageObject]bool)
+	var objects []*interfaces.StorageObject
+	for _, file := range d.files {
+		object := interfaces.StorageObject{BucketID: file.info.BucketID, ObjectName: file.info.ObjectName}
+		if seen[object] || (after != nil && !less(after, &object)) {
+			continue
+		}
+		seen[object] = true
+		objects = append(objects, &object)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return less(objects[i], objects[j])
+	})
+	if len(objects) > limit {
+		objects = objects[:limit]
+	}
+
+	return objects, nil
+}
+
+func (d *MemoryDBFile) UpdateObjectName(ctx context.Context, bucketID, oldName, newName string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var count int64
+	now := time.Now().Truncate(time.Second)
+	for _, file := range d.files {
+		if file.info.BucketID == bucketID && file.info.ObjectName == oldName {
+			file.info.ObjectName = newName
+			file.info.UpdateTime = &now
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (d *MemoryDBFile) UpdateFileStatus(ctx context.Context, fileID, status string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file, ok := d.files[fileID]
+	if !ok {
+		return nil
+	}
+	now := time.Now().Truncate(time.Second)
+	file.info.Status = status
+	file.info.UpdateTime = &now
+
+	return nil
+}
+
+func (d *MemoryDBFile) UpdateFileSHA256(ctx context.Context, fileID, sha256 string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file, ok := d.files[fileID]
+	if !ok {
+		return nil
+	}
+	now := time.Now().Truncate(time.Second)
+	file.info.SHA256 = sha256
+	file.info.UpdateTime = &now
+
+	return nil
+}
+
+func (d *MemoryDBFile) UpdateFileContentType(ctx context.Context, fileID, contentType string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file, ok := d.files[fileID]
+	if !ok {
+		return nil
+	}
+	now := time.Now().Truncate(time.Second)
+	file.info.ContentType = contentType
+	file.info.UpdateTime = &now
+
+	return nil
+}
+
+func (d *MemoryDBFile) DeleteFile(ctx context.Context, fileID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deleteFile(fileID)
+	return nil
+}
+
+// deleteFile 删除文件记录，调用方需持有锁
+func (d *MemoryDBFile) deleteFile(fileID string) {
+	file, ok := d.files[fileID]
+	if !ok {
+		return
+	}
+	delete(d.names, file.info.Name)
+	delete(d.files, fileID)
+}
+
+func (d *MemoryDBFile) GetFileList(ctx context.Context, bucketID string, page, pageSize int) ([]*interfaces.FileInfo, int64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var matched []*memoryFile
+	for _, file := range d.files {
+		if file.info.BucketID == bucketID && file.info.Status == interfaces.FileStatusActive {
+			matched = append(matched, file)
+		}
+	}
+
+	// 按创建时间倒序，同一秒内按写入顺序倒序
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].info.CreateTime.Equal(*matched[j].info.CreateTime) {
+			return matched[i].info.CreateTime.After(*matched[j].info.CreateTime)
+		}
+		return matched[i].seq > matched[j].seq
+	})
+
+	total := int64(len(matched))
+	offset := (page - 1) * pageSize
+	if offset >= len(matched) {
+		return nil, total, nil
+	}
+	end := offset + pageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	files := make([]*interfaces.FileInfo, 0, end-offset)
+	for _, file := range matched[offset:end] {
+		files = append(files, copyFileInfo(&file.info))
+	}
+
+	return files, total, nil
+}
+
+func copyFileInfo(file *interfaces.FileInfo) *interfaces.FileInfo {
+	copied := *file
+	if file.CreateTime != nil {
+		createTime := *file.CreateTime
+		copied.CreateTime = &createTime
+	}
+	if file.UpdateTime != nil {
+		updateTime := *file.UpdateTime
+		copied.UpdateTime = &updateTime
+	}
+	return &copied
+}
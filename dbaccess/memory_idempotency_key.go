@@ -0,0 +1,96 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryDBIdempotencyKey 基于内存的幂等键存储，用于嵌入式运行和单元测试
+type MemoryDBIdempotencyKey struct {
+	mu      sync.Mutex
+	records map[string]*interfaces.IdempotencyKey
+}
+
+func NewMemoryDBIdempotencyKey() interfaces.DBIdempotencyKey {
+	return &MemoryDBIdempotencyKey{
+		records: make(map[string]*interfaces.IdempotencyKey),
+	}
+}
+
+func (d *MemoryDBIdempotencyKey) CreateIdempotencyKey(ctx context.Context, record *interfaces.IdempotencyKey) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.records[record.Key]; ok {
+		return fmt.Errorf("%w '%s' for key 'PRIMARY'", interfaces.ErrDuplicateEntry, record.Key)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	copied := *record
+	copied.CreateTime = &now
+	copied.UpdateTime = &now
+	d.records[record.Key] = &copied
+
+	return nil
+}
+
+func (d *MemoryDBIdempotencyKey) GetIdempotencyKey(ctx context.Context, key string) (*interfaces.IdempotencyKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	record, ok := d.records[key]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *record
+	return &copied, nil
+}
+
+func (d *MemoryDBIdempotencyKey) CompleteIdempotencyKey(ctx context.Context, record *interfaces.IdempotencyKey) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	existing, ok := d.records[record.Key]
+	if !ok || existing.Token != record.Token {
+		return 0, nil
+	}
+
+	now := time.Now().Truncate(time.Second)
+	existing.Status = interfaces.IdempotencyStatusCompleted
+	existing.BodyDigest = record.BodyDigest
+	existing.ResponseCode = record.ResponseCode
+	existing.ResponseHeader = record.ResponseHeader
+	existing.ResponseBody = append([]byte(nil), record.ResponseBody...)
+	existing.ExpireAt = record.ExpireAt
+	existing.UpdateTime = &now
+
+	return 1, nil
+}
+
+func (d *MemoryDBIdempotencyKey) DeleteIdempotencyKey(ctx context.Context, key, token string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if record, ok := d.records[key]; ok && record.Token == token {
+		delete(d.records, key)
+	}
+	return nil
+}
+
+func (d *MemoryDBIdempotencyKey) DeleteExpiredIdempotencyKeys(ctx context.Context, expireAt int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var deleted int64
+	for key, record := range d.records {
+		if record.ExpireAt < expireAt {
+			delete(d.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
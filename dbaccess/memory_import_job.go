@@ -0,0 +1,70 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryDBImportJob 基于内存的导入任务存储，用于嵌入式运行和单元测试
+type MemoryDBImportJob struct {
+	mu   sync.Mutex
+	jobs map[string]*interfaces.ImportJob
+}
+
+func NewMemoryDBImportJob() interfaces.DBImportJob {
+	return &MemoryDBImportJob{
+		jobs: make(map[string]*interfaces.ImportJob),
+	}
+}
+
+func (d *MemoryDBImportJob) CreateImportJob(ctx context.Context, job *interfaces.ImportJob) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.jobs[job.ID]; ok {
+		return fmt.Errorf("%w '%s' for key 'PRIMARY'", interfaces.ErrDuplicateEntry, job.ID)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	job.CreateTime = &now
+	job.UpdateTime = &now
+
+	copied := *job
+	d.jobs[job.ID] = &copied
+
+	return nil
+}
+
+func (d *MemoryDBImportJob) GetImportJob(ctx context.Context, jobID string) (*interfaces.ImportJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	job, ok := d.jobs[jobID]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *job
+	return &copied, nil
+}
+
+func (d *MemoryDBImportJob) UpdateImportJob(ctx context.Context, jobID, status, fileID, errorMessage string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	job, ok := d.jobs[jobID]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now().Truncate(time.Second)
+	job.Status = status
+	job.FileID = fileID
+	job.ErrorMessage = errorMessage
+	job.UpdateTime = &now
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryDBMultipartUpload 基于内存的分片上传会话存储，用于嵌入式运行和单元测试
+type MemoryDBMultipartUpload struct {
+	mu      sync.Mutex
+	uploads map[string]*interfaces.MultipartUpload
+}
+
+func NewMemoryDBMultipartUpload() interfaces.DBMultipartUpload {
+	return &MemoryDBMultipartUpload{
+		uploads: make(map[string]*interfaces.MultipartUpload),
+	}
+}
+
+func (d *MemoryDBMultipartUpload) CreateMultipartUpload(ctx context.Context, upload *interfaces.MultipartUpload) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.uploads[upload.ID]; ok {
+		return fmt.Errorf("%w '%s' for key 'PRIMARY'", interfaces.ErrDuplicateEntry, upload.ID)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	upload.CreateTime = &now
+	upload.UpdateTime = &now
+
+	copied := *upload
+	d.uploads[upload.ID] = &copied
+
+	return nil
+}
+
+func (d *MemoryDBMultipartUpload) GetMultipartUpload(ctx context.Context, uploadID string) (*interfaces.MultipartUpload, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	upload, ok := d.uploads[uploadID]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *upload
+	return &copied, nil
+}
+
+func (d *MemoryDBMultipartUpload) CompleteMultipartUpload(ctx context.Context, uploadID, fileID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	upload, ok := d.uploads[uploadID]
+	if !ok || upload.FileID != "" {
+		return interfaces.ErrUploadCompleted
+	}
+
+	now := time.Now().Truncate(time.Second)
+	upload.FileID = fileID
+	upload.UpdateTime = &now
+
+	return nil
+}
+
+func (d *MemoryDBMultipartUpload) DeleteMultipartUpload(ctx context.Context, uploadID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.uploads, uploadID)
+
+	return nil
+}
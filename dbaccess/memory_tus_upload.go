@@ -0,0 +1,139 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryDBTusUpload 基于内存的tus上传记录存储，用于嵌入式运行和单元测试
+type MemoryDBTusUpload struct {
+	mu      sync.Mutex
+	uploads map[string]*interfaces.TusUpload
+	parts   map[string][]*interfaces.TusPart
+}
+
+func NewMemoryDBTusUpload() interfaces.DBTusUpload {
+	return &MemoryDBTusUpload{
+		uploads: make(map[string]*interfaces.TusUpload),
+		parts:   make(map[string][]*interfaces.TusPart),
+	}
+}
+
+func (d *MemoryDBTusUpload) CreateTusUpload(ctx context.Context, upload *interfaces.TusUpload) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.uploads[upload.ID]; ok {
+		return fmt.Errorf("%w '%s' for key 'PRIMARY'", interfaces.ErrDuplicateEntry, upload.ID)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	upload.CreateTime = &now
+	upload.UpdateTime = &now
+
+	copied := *upload
+	d.uploads[upload.ID] = &copied
+
+	return nil
+}
+
+func (d *MemoryDBTusUpload) GetTusUpload(ctx context.Context, uploadID string) (*interfaces.TusUpload, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	upload, ok := d.uploads[uploadID]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *upload
+	return &copied, nil
+}
+
+func (d *MemoryDBTusUpload) AppendTusPart(ctx context.Context, part *interfaces.TusPart, expireAt int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	upload, ok := d.uploads[part.UploadID]
+	if !ok || upload.Offset != part.Offset || upload.FileID != "" {
+		return interfaces.ErrOffsetConflict
+	}
+
+	now := time.Now().Truncate(time.Second)
+	upload.Offset += part.Size
+	upload.ExpireAt = expireAt
+	upload.UpdateTime = &now
+
+	copied := *part
+	d.parts[part.UploadID] = append(d.parts[part.UploadID], &copied)
+
+	return nil
+}
+
+func (d *MemoryDBTusUpload) GetTusParts(ctx context.Context, uploadID string) ([]*interfaces.TusPart, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	parts := make([]*interfaces.TusPart, 0, len(d.parts[uploadID]))
+	for _, part := range d.parts[uploadID] {
+		copied := *part
+		parts = append(parts, &copied)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Offset < parts[j].Offset
+	})
+
+	return parts, nil
+}
+
+func (d *MemoryDBTusUpload) CompleteTusUpload(ctx context.Context, uploadID, fileID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	upload, ok := d.uploads[uploadID]
+	if !ok || upload.FileID != "" {
+		return interfaces.ErrOffsetConflict
+	}
+
+	now := time.Now().Truncate(time.Second)
+	upload.FileID = fileID
+	upload.UpdateTime = &now
+	delete(d.parts, uploadID)
+
+	return nil
+}
+
+func (d *MemoryDBTusUpload) DeleteTusUpload(ctx context.Context, uploadID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.uploads, uploadID)
+	delete(d.parts, uploadID)
+
+	return nil
+}
+
+func (d *MemoryDBTusUpload) GetExpiredTusUploads(ctx context.Context, expireAt int64, limit int) ([]*interfaces.TusUpload, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var uploads []*interfaces.TusUpload
+	for _, upload := range d.uploads {
+		if upload.ExpireAt < expireAt {
+			copied := *upload
+			uploads = append(uploads, &copied)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].ExpireAt < uploads[j].ExpireAt
+	})
+	if len(uploads) > limit {
+		uploads = uploads[:limit]
+	}
+
+	return uploads, nil
+}
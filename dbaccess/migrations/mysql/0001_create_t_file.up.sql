@@ -1,7 +1,3 @@
-CREATE DATABASE IF NOT EXISTS `file_engine` DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
-
-USE `file_engine`;
-
 CREATE TABLE IF NOT EXISTS `t_file` (
     `id` VARCHAR(40) NOT NULL,
     `name` VARCHAR(255) NOT NULL COMMENT '文件名',
@@ -14,4 +10,3 @@ CREATE TABLE IF NOT EXISTS `t_file` (
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_name` (`name`)
 ) ENGINE=InnoDB COMMENT='文件表';
-
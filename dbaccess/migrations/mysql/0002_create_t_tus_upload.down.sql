@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `t_tus_part`;
+DROP TABLE IF EXISTS `t_tus_upload`;
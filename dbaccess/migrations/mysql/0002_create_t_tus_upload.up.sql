@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `t_tus_upload` (
+    `id` VARCHAR(40) NOT NULL,
+    `bucket_id` VARCHAR(40) NOT NULL COMMENT '桶ID',
+    `filename` VARCHAR(255) NOT NULL COMMENT '文件名',
+    `content_type` VARCHAR(255) NOT NULL COMMENT '文件类型',
+    `size` BIGINT(20) NOT NULL COMMENT '文件总大小(Upload-Length)',
+    `upload_offset` BIGINT(20) NOT NULL DEFAULT 0 COMMENT '已接收字节数(Upload-Offset)',
+    `metadata` VARCHAR(2048) NOT NULL DEFAULT '' COMMENT '原始Upload-Metadata',
+    `file_id` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '上传完成后生成的文件ID',
+    `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB COMMENT='tus断点续传上传表';
+
+CREATE TABLE IF NOT EXISTS `t_tus_part` (
+    `upload_id` VARCHAR(40) NOT NULL COMMENT '上传ID',
+    `upload_offset` BIGINT(20) NOT NULL COMMENT '分片起始偏移量',
+    `size` BIGINT(20) NOT NULL COMMENT '分片大小',
+    `object_name` VARCHAR(255) NOT NULL COMMENT '分片对象名',
+    `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`upload_id`, `upload_offset`)
+) ENGINE=InnoDB COMMENT='tus断点续传分片表';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `t_multipart_upload` (
+    `id` VARCHAR(40) NOT NULL,
+    `bucket_id` VARCHAR(40) NOT NULL COMMENT '桶ID',
+    `filename` VARCHAR(255) NOT NULL COMMENT '文件名',
+    `content_type` VARCHAR(255) NOT NULL COMMENT '文件类型',
+    `size` BIGINT(20) NOT NULL COMMENT '文件总大小',
+    `part_size` BIGINT(20) NOT NULL COMMENT '分片大小，最后一个分片可以更小',
+    `storage_upload_id` VARCHAR(255) NOT NULL COMMENT '存储侧的分片上传ID',
+    `file_id` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '上传完成后生成的文件ID',
+    `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB COMMENT='分片上传会话表';
@@ -0,0 +1,3 @@
+ALTER TABLE `t_file`
+    DROP INDEX `idx_bucket_status`,
+    DROP COLUMN `status`;
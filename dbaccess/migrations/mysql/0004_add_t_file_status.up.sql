@@ -0,0 +1,3 @@
+ALTER TABLE `t_file`
+    ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'active' COMMENT '文件状态(pending/active/failed)' AFTER `icon`,
+    ADD INDEX `idx_bucket_status` (`bucket_id`, `status`);
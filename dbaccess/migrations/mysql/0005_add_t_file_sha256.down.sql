@@ -0,0 +1,2 @@
+ALTER TABLE `t_file`
+    DROP COLUMN `sha256`;
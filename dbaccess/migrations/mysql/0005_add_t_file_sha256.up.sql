@@ -0,0 +1,2 @@
+ALTER TABLE `t_file`
+    ADD COLUMN `sha256` CHAR(64) NOT NULL DEFAULT '' COMMENT '文件内容的SHA-256(十六进制)，为空表示未计算' AFTER `status`;
@@ -0,0 +1,7 @@
+ALTER TABLE `t_multipart_upload`
+    DROP COLUMN `object_name`;
+
+ALTER TABLE `t_file`
+    DROP INDEX `idx_sha256_size`,
+    DROP INDEX `idx_object_name`,
+    DROP COLUMN `object_name`;
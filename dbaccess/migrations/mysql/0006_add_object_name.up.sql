@@ -0,0 +1,12 @@
+ALTER TABLE `t_file`
+    ADD COLUMN `object_name` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '存储中的对象名，秒传的文件与源文件共用同一对象' AFTER `bucket_id`,
+    ADD INDEX `idx_object_name` (`bucket_id`, `object_name`),
+    ADD INDEX `idx_sha256_size` (`sha256`, `size`);
+
+-- 历史文件的对象名即文件名，保持update_time不变
+UPDATE `t_file` SET `object_name` = `name`, `update_time` = `update_time` WHERE `object_name` = '';
+
+ALTER TABLE `t_multipart_upload`
+    ADD COLUMN `object_name` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '存储中的对象名' AFTER `filename`;
+
+UPDATE `t_multipart_upload` SET `object_name` = `filename`, `update_time` = `update_time` WHERE `object_name` = '';
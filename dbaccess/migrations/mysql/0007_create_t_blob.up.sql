@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `t_blob` (
+    `bucket_id` VARCHAR(40) NOT NULL COMMENT '桶ID',
+    `hash` CHAR(64) NOT NULL COMMENT '内容的SHA-256(十六进制)',
+    `storage_key` VARCHAR(255) NOT NULL COMMENT '数据块在底层存储中的对象名',
+    `size` BIGINT(20) NOT NULL COMMENT '数据块大小',
+    `ref_count` BIGINT(20) NOT NULL COMMENT '引用该数据块的对象数，归零时删除',
+    `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`bucket_id`, `hash`)
+) ENGINE=InnoDB COMMENT='去重存储数据块表';
+
+CREATE TABLE IF NOT EXISTS `t_object_blob` (
+    `bucket_id` VARCHAR(40) NOT NULL COMMENT '桶ID',
+    `object_name` VARCHAR(255) NOT NULL COMMENT '逻辑对象名',
+    `hash` CHAR(64) NOT NULL COMMENT '对象内容的SHA-256，指向t_blob',
+    `size` BIGINT(20) NOT NULL COMMENT '对象大小',
+    `content_type` VARCHAR(255) NOT NULL COMMENT '对象类型',
+    `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`bucket_id`, `object_name`),
+    KEY `idx_bucket_hash` (`bucket_id`, `hash`)
+) ENGINE=InnoDB COMMENT='去重存储对象映射表';
@@ -0,0 +1,12 @@
+ALTER TABLE `t_object_blob`
+    ADD COLUMN `chunk_count` INT NOT NULL DEFAULT 0 COMMENT '分块存储的块数，为0表示整个对象指向一个数据块' AFTER `content_type`;
+
+CREATE TABLE IF NOT EXISTS `t_object_chunk` (
+    `bucket_id` VARCHAR(40) NOT NULL COMMENT '桶ID',
+    `object_name` VARCHAR(255) NOT NULL COMMENT '逻辑对象名',
+    `seq` INT NOT NULL COMMENT '块序号，从0开始',
+    `hash` CHAR(64) NOT NULL COMMENT '块内容的SHA-256，指向t_blob',
+    `chunk_offset` BIGINT(20) NOT NULL COMMENT '块在对象中的起始偏移量',
+    `size` BIGINT(20) NOT NULL COMMENT '块大小',
+    PRIMARY KEY (`bucket_id`, `object_name`, `seq`)
+) ENGINE=InnoDB COMMENT='分块存储对象的块清单表';
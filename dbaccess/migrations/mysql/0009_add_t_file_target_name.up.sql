@@ -0,0 +1,2 @@
+ALTER TABLE `t_file`
+    ADD COLUMN `target_name` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '覆盖上传确认后替换的文件名，为空表示无需替换' AFTER `name`;
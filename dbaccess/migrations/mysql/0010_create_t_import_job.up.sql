@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `t_import_job` (
+    `id` VARCHAR(40) NOT NULL,
+    `bucket_id` VARCHAR(40) NOT NULL COMMENT '桶ID',
+    `source_url` VARCHAR(2048) NOT NULL COMMENT '导入的源URL',
+    `filename` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '指定的文件名，为空时从响应中获取',
+    `conflict` VARCHAR(16) NOT NULL DEFAULT '' COMMENT '同名文件的处理方式',
+    `sha256` CHAR(64) NOT NULL DEFAULT '' COMMENT '期望的内容SHA-256，为空不校验',
+    `status` VARCHAR(16) NOT NULL COMMENT '任务状态：pending、running、succeeded、failed',
+    `file_id` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '导入成功后生成的文件ID',
+    `error_message` VARCHAR(1024) NOT NULL DEFAULT '' COMMENT '导入失败的原因',
+    `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB COMMENT='URL导入任务表';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `t_idempotency_key`;
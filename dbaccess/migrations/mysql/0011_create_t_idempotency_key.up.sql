@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `t_idempotency_key` (
+    `idempotency_key` VARCHAR(255) NOT NULL COMMENT '客户端提供的Idempotency-Key',
+    `token` VARCHAR(40) NOT NULL COMMENT '处理请求的实例持有的令牌，用于完成或释放时校验',
+    `fingerprint` CHAR(64) NOT NULL COMMENT '请求指纹，相同幂等键的请求需一致',
+    `status` VARCHAR(16) NOT NULL COMMENT '状态：processing、completed',
+    `response_code` INT NOT NULL DEFAULT 0 COMMENT '保存的响应码',
+    `response_header` TEXT COMMENT '保存的响应头(JSON)',
+    `response_body` MEDIUMBLOB COMMENT '保存的响应体',
+    `expire_at` BIGINT(20) NOT NULL COMMENT '过期时间(Unix秒)，处理中的记录过期表示处理中断',
+    `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`idempotency_key`),
+    KEY `idx_expire_at` (`expire_at`)
+) ENGINE=InnoDB COMMENT='幂等键表';
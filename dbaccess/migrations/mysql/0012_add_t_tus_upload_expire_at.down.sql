@@ -0,0 +1,3 @@
+ALTER TABLE `t_tus_upload`
+    DROP KEY `idx_expire_at`,
+    DROP COLUMN `expire_at`;
@@ -0,0 +1,6 @@
+ALTER TABLE `t_tus_upload`
+    ADD COLUMN `expire_at` BIGINT(20) NOT NULL DEFAULT 0 COMMENT '过期时间(Unix秒)，过期未完成的上传及其分片被清理' AFTER `file_id`,
+    ADD KEY `idx_expire_at` (`expire_at`);
+
+-- 已有的上传从迁移时起保留24小时
+UPDATE `t_tus_upload` SET `expire_at` = UNIX_TIMESTAMP() + 86400;
@@ -0,0 +1,5 @@
+ALTER TABLE `t_tus_upload`
+    ADD COLUMN `conflict` VARCHAR(16) NOT NULL DEFAULT 'reject' COMMENT '同名文件的处理方式(reject、overwrite、rename)' AFTER `metadata`;
+
+ALTER TABLE `t_multipart_upload`
+    ADD COLUMN `conflict` VARCHAR(16) NOT NULL DEFAULT 'reject' COMMENT '同名文件的处理方式(reject、overwrite、rename)' AFTER `storage_upload_id`;
@@ -0,0 +1,2 @@
+ALTER TABLE `t_idempotency_key`
+    DROP COLUMN `body_digest`;
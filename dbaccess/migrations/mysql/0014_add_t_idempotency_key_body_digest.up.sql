@@ -0,0 +1,2 @@
+ALTER TABLE `t_idempotency_key`
+    ADD COLUMN `body_digest` CHAR(64) NOT NULL DEFAULT '' COMMENT '未计入指纹的请求体的SHA-256，重放时需一致' AFTER `fingerprint`;
@@ -0,0 +1,5 @@
+ALTER TABLE `t_file`
+    ADD COLUMN `expire_at` BIGINT(20) NOT NULL DEFAULT 0 COMMENT '待上传状态的过期时间(Unix秒)，过期仍未确认的记录被回收' AFTER `sha256`;
+
+-- 已有的待上传记录从迁移时起保留24小时
+UPDATE `t_file` SET `expire_at` = UNIX_TIMESTAMP() + 86400 WHERE `status` = 'pending';
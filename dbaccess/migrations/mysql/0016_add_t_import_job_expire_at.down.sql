@@ -0,0 +1,2 @@
+ALTER TABLE `t_import_job`
+    DROP COLUMN `expire_at`;
@@ -0,0 +1,5 @@
+ALTER TABLE `t_import_job`
+    ADD COLUMN `expire_at` BIGINT(20) NOT NULL DEFAULT 0 COMMENT '任务的截止时间(Unix秒)，超过仍未结束视为执行中断' AFTER `error_message`;
+
+-- 未结束的任务从迁移时起按默认超时时间(30分钟)和1分钟余量计算截止时间
+UPDATE `t_import_job` SET `expire_at` = UNIX_TIMESTAMP() + 1860 WHERE `status` IN ('pending', 'running');
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_file;
+DROP FUNCTION IF EXISTS fn_set_update_time();
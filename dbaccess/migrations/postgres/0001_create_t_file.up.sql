@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS t_file (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    bucket_id VARCHAR(40) NOT NULL,
+    size BIGINT NOT NULL,
+    icon VARCHAR(255) NOT NULL,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE t_file IS '文件表';
+COMMENT ON COLUMN t_file.name IS '文件名';
+COMMENT ON COLUMN t_file.content_type IS '文件类型(application/octet-stream)';
+COMMENT ON COLUMN t_file.bucket_id IS '桶ID';
+COMMENT ON COLUMN t_file.size IS '文件大小';
+COMMENT ON COLUMN t_file.icon IS '文件图标';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_name ON t_file (name);
+
+CREATE OR REPLACE FUNCTION fn_set_update_time() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.update_time = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_t_file_update_time ON t_file;
+CREATE TRIGGER trg_t_file_update_time BEFORE UPDATE ON t_file
+FOR EACH ROW EXECUTE FUNCTION fn_set_update_time();
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS t_tus_upload (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    bucket_id VARCHAR(40) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    metadata VARCHAR(2048) NOT NULL DEFAULT '',
+    file_id VARCHAR(40) NOT NULL DEFAULT '',
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE t_tus_upload IS 'tus断点续传上传表';
+COMMENT ON COLUMN t_tus_upload.size IS '文件总大小(Upload-Length)';
+COMMENT ON COLUMN t_tus_upload.upload_offset IS '已接收字节数(Upload-Offset)';
+COMMENT ON COLUMN t_tus_upload.metadata IS '原始Upload-Metadata';
+COMMENT ON COLUMN t_tus_upload.file_id IS '上传完成后生成的文件ID';
+
+DROP TRIGGER IF EXISTS trg_t_tus_upload_update_time ON t_tus_upload;
+CREATE TRIGGER trg_t_tus_upload_update_time BEFORE UPDATE ON t_tus_upload
+FOR EACH ROW EXECUTE FUNCTION fn_set_update_time();
+
+CREATE TABLE IF NOT EXISTS t_tus_part (
+    upload_id VARCHAR(40) NOT NULL,
+    upload_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    object_name VARCHAR(255) NOT NULL,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (upload_id, upload_offset)
+);
+
+COMMENT ON TABLE t_tus_part IS 'tus断点续传分片表';
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS t_multipart_upload (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    bucket_id VARCHAR(40) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    part_size BIGINT NOT NULL,
+    storage_upload_id VARCHAR(255) NOT NULL,
+    file_id VARCHAR(40) NOT NULL DEFAULT '',
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE t_multipart_upload IS '分片上传会话表';
+COMMENT ON COLUMN t_multipart_upload.part_size IS '分片大小，最后一个分片可以更小';
+COMMENT ON COLUMN t_multipart_upload.storage_upload_id IS '存储侧的分片上传ID';
+COMMENT ON COLUMN t_multipart_upload.file_id IS '上传完成后生成的文件ID';
+
+DROP TRIGGER IF EXISTS trg_t_multipart_upload_update_time ON t_multipart_upload;
+CREATE TRIGGER trg_t_multipart_upload_update_time BEFORE UPDATE ON t_multipart_upload
+FOR EACH ROW EXECUTE FUNCTION fn_set_update_time();
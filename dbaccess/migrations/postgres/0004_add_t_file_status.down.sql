@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bucket_status;
+ALTER TABLE t_file DROP COLUMN IF EXISTS status;
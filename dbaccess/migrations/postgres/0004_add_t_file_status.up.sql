@@ -0,0 +1,5 @@
+ALTER TABLE t_file ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+
+COMMENT ON COLUMN t_file.status IS '文件状态(pending/active/failed)';
+
+CREATE INDEX IF NOT EXISTS idx_bucket_status ON t_file (bucket_id, status);
@@ -0,0 +1 @@
+ALTER TABLE t_file DROP COLUMN IF EXISTS sha256;
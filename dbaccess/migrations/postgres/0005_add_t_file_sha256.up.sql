@@ -0,0 +1,3 @@
+ALTER TABLE t_file ADD COLUMN IF NOT EXISTS sha256 CHAR(64) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN t_file.sha256 IS '文件内容的SHA-256(十六进制)，为空表示未计算';
@@ -0,0 +1,19 @@
+ALTER TABLE t_file ADD COLUMN IF NOT EXISTS object_name VARCHAR(255) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN t_file.object_name IS '存储中的对象名，秒传的文件与源文件共用同一对象';
+
+-- 历史文件的对象名即文件名，保持update_time不变
+ALTER TABLE t_file DISABLE TRIGGER trg_t_file_update_time;
+UPDATE t_file SET object_name = name WHERE object_name = '';
+ALTER TABLE t_file ENABLE TRIGGER trg_t_file_update_time;
+
+CREATE INDEX IF NOT EXISTS idx_object_name ON t_file (bucket_id, object_name);
+CREATE INDEX IF NOT EXISTS idx_sha256_size ON t_file (sha256, size);
+
+ALTER TABLE t_multipart_upload ADD COLUMN IF NOT EXISTS object_name VARCHAR(255) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN t_multipart_upload.object_name IS '存储中的对象名';
+
+ALTER TABLE t_multipart_upload DISABLE TRIGGER trg_t_multipart_upload_update_time;
+UPDATE t_multipart_upload SET object_name = filename WHERE object_name = '';
+ALTER TABLE t_multipart_upload ENABLE TRIGGER trg_t_multipart_upload_update_time;
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS t_blob (
+    bucket_id VARCHAR(40) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    ref_count BIGINT NOT NULL,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (bucket_id, hash)
+);
+
+COMMENT ON TABLE t_blob IS '去重存储数据块表';
+COMMENT ON COLUMN t_blob.hash IS '内容的SHA-256(十六进制)';
+COMMENT ON COLUMN t_blob.storage_key IS '数据块在底层存储中的对象名';
+COMMENT ON COLUMN t_blob.ref_count IS '引用该数据块的对象数，归零时删除';
+
+DROP TRIGGER IF EXISTS trg_t_blob_update_time ON t_blob;
+CREATE TRIGGER trg_t_blob_update_time BEFORE UPDATE ON t_blob
+FOR EACH ROW EXECUTE FUNCTION fn_set_update_time();
+
+CREATE TABLE IF NOT EXISTS t_object_blob (
+    bucket_id VARCHAR(40) NOT NULL,
+    object_name VARCHAR(255) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (bucket_id, object_name)
+);
+
+COMMENT ON TABLE t_object_blob IS '去重存储对象映射表';
+COMMENT ON COLUMN t_object_blob.object_name IS '逻辑对象名';
+COMMENT ON COLUMN t_object_blob.hash IS '对象内容的SHA-256，指向t_blob';
+
+CREATE INDEX IF NOT EXISTS idx_bucket_hash ON t_object_blob (bucket_id, hash);
+
+DROP TRIGGER IF EXISTS trg_t_object_blob_update_time ON t_object_blob;
+CREATE TRIGGER trg_t_object_blob_update_time BEFORE UPDATE ON t_object_blob
+FOR EACH ROW EXECUTE FUNCTION fn_set_update_time();
@@ -0,0 +1,18 @@
+ALTER TABLE t_object_blob ADD COLUMN IF NOT EXISTS chunk_count INT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN t_object_blob.chunk_count IS '分块存储的块数，为0表示整个对象指向一个数据块';
+
+CREATE TABLE IF NOT EXISTS t_object_chunk (
+    bucket_id VARCHAR(40) NOT NULL,
+    object_name VARCHAR(255) NOT NULL,
+    seq INT NOT NULL,
+    hash CHAR(64) NOT NULL,
+    chunk_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    PRIMARY KEY (bucket_id, object_name, seq)
+);
+
+COMMENT ON TABLE t_object_chunk IS '分块存储对象的块清单表';
+COMMENT ON COLUMN t_object_chunk.seq IS '块序号，从0开始';
+COMMENT ON COLUMN t_object_chunk.hash IS '块内容的SHA-256，指向t_blob';
+COMMENT ON COLUMN t_object_chunk.chunk_offset IS '块在对象中的起始偏移量';
@@ -0,0 +1,3 @@
+ALTER TABLE t_file ADD COLUMN IF NOT EXISTS target_name VARCHAR(255) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN t_file.target_name IS '覆盖上传确认后替换的文件名，为空表示无需替换';
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS t_import_job (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    bucket_id VARCHAR(40) NOT NULL,
+    source_url VARCHAR(2048) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    conflict VARCHAR(16) NOT NULL DEFAULT '',
+    sha256 CHAR(64) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    file_id VARCHAR(40) NOT NULL DEFAULT '',
+    error_message VARCHAR(1024) NOT NULL DEFAULT '',
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE t_import_job IS 'URL导入任务表';
+COMMENT ON COLUMN t_import_job.filename IS '指定的文件名，为空时从响应中获取';
+COMMENT ON COLUMN t_import_job.sha256 IS '期望的内容SHA-256，为空不校验';
+COMMENT ON COLUMN t_import_job.status IS '任务状态：pending、running、succeeded、failed';
+COMMENT ON COLUMN t_import_job.file_id IS '导入成功后生成的文件ID';
+
+DROP TRIGGER IF EXISTS trg_t_import_job_update_time ON t_import_job;
+CREATE TRIGGER trg_t_import_job_update_time BEFORE UPDATE ON t_import_job
+FOR EACH ROW EXECUTE FUNCTION fn_set_update_time();
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS t_idempotency_key (
+    idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY,
+    token VARCHAR(40) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    response_code INT NOT NULL DEFAULT 0,
+    response_header TEXT,
+    response_body BYTEA,
+    expire_at BIGINT NOT NULL,
+    create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_t_idempotency_key_expire_at ON t_idempotency_key (expire_at);
+
+COMMENT ON TABLE t_idempotency_key IS '幂等键表';
+COMMENT ON COLUMN t_idempotency_key.token IS '处理请求的实例持有的令牌，用于完成或释放时校验';
+COMMENT ON COLUMN t_idempotency_key.fingerprint IS '请求指纹，相同幂等键的请求需一致';
+COMMENT ON COLUMN t_idempotency_key.status IS '状态：processing、completed';
+COMMENT ON COLUMN t_idempotency_key.expire_at IS '过期时间(Unix秒)，处理中的记录过期表示处理中断';
+
+DROP TRIGGER IF EXISTS trg_t_idempotency_key_update_time ON t_idempotency_key;
+CREATE TRIGGER trg_t_idempotency_key_update_time BEFORE UPDATE ON t_idempotency_key
+FOR EACH ROW EXECUTE FUNCTION fn_set_update_time();
@@ -0,0 +1,7 @@
+ALTER TABLE t_tus_upload ADD COLUMN IF NOT EXISTS expire_at BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_t_tus_upload_expire_at ON t_tus_upload (expire_at);
+
+COMMENT ON COLUMN t_tus_upload.expire_at IS '过期时间(Unix秒)，过期未完成的上传及其分片被清理';
+
+-- 已有的上传从迁移时起保留24小时
+UPDATE t_tus_upload SET expire_at = CAST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) AS BIGINT) + 86400;
@@ -0,0 +1,5 @@
+ALTER TABLE t_tus_upload ADD COLUMN IF NOT EXISTS conflict VARCHAR(16) NOT NULL DEFAULT 'reject';
+ALTER TABLE t_multipart_upload ADD COLUMN IF NOT EXISTS conflict VARCHAR(16) NOT NULL DEFAULT 'reject';
+
+COMMENT ON COLUMN t_tus_upload.conflict IS '同名文件的处理方式(reject、overwrite、rename)';
+COMMENT ON COLUMN t_multipart_upload.conflict IS '同名文件的处理方式(reject、overwrite、rename)';
@@ -0,0 +1,3 @@
+ALTER TABLE t_idempotency_key ADD COLUMN IF NOT EXISTS body_digest CHAR(64) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN t_idempotency_key.body_digest IS '未计入指纹的请求体的SHA-256，重放时需一致';
@@ -0,0 +1,6 @@
+ALTER TABLE t_file ADD COLUMN IF NOT EXISTS expire_at BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN t_file.expire_at IS '待上传状态的过期时间(Unix秒)，过期仍未确认的记录被回收';
+
+-- 已有的待上传记录从迁移时起保留24小时
+UPDATE t_file SET expire_at = CAST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) AS BIGINT) + 86400 WHERE status = 'pending';
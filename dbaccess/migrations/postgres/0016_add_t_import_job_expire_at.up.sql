@@ -0,0 +1,6 @@
+ALTER TABLE t_import_job ADD COLUMN IF NOT EXISTS expire_at BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN t_import_job.expire_at IS '任务的截止时间(Unix秒)，超过仍未结束视为执行中断';
+
+-- 未结束的任务从迁移时起按默认超时时间(30分钟)和1分钟余量计算截止时间
+UPDATE t_import_job SET expire_at = CAST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) AS BIGINT) + 1860 WHERE status IN ('pending', 'running');
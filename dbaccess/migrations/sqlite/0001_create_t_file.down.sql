@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_t_file_update_time;
+DROP TABLE IF EXISTS t_file;
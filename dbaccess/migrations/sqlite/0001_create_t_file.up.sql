@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS t_file (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    bucket_id VARCHAR(40) NOT NULL,
+    size BIGINT NOT NULL,
+    icon VARCHAR(255) NOT NULL,
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_name ON t_file (name);
+
+CREATE TRIGGER IF NOT EXISTS trg_t_file_update_time AFTER UPDATE ON t_file
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_file SET update_time = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS t_tus_part;
+DROP TRIGGER IF EXISTS trg_t_tus_upload_update_time;
+DROP TABLE IF EXISTS t_tus_upload;
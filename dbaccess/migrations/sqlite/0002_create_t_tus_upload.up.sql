@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS t_tus_upload (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    bucket_id VARCHAR(40) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    metadata VARCHAR(2048) NOT NULL DEFAULT '',
+    file_id VARCHAR(40) NOT NULL DEFAULT '',
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER IF NOT EXISTS trg_t_tus_upload_update_time AFTER UPDATE ON t_tus_upload
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_tus_upload SET update_time = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
+
+CREATE TABLE IF NOT EXISTS t_tus_part (
+    upload_id VARCHAR(40) NOT NULL,
+    upload_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    object_name VARCHAR(255) NOT NULL,
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (upload_id, upload_offset)
+);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_t_multipart_upload_update_time;
+DROP TABLE IF EXISTS t_multipart_upload;
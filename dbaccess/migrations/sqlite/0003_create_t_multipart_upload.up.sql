@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS t_multipart_upload (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    bucket_id VARCHAR(40) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    part_size BIGINT NOT NULL,
+    storage_upload_id VARCHAR(255) NOT NULL,
+    file_id VARCHAR(40) NOT NULL DEFAULT '',
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER IF NOT EXISTS trg_t_multipart_upload_update_time AFTER UPDATE ON t_multipart_upload
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_multipart_upload SET update_time = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
@@ -0,0 +1,3 @@
+ALTER TABLE t_file ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS idx_bucket_status ON t_file (bucket_id, status);
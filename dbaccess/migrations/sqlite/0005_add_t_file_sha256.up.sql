@@ -0,0 +1 @@
+ALTER TABLE t_file ADD COLUMN sha256 CHAR(64) NOT NULL DEFAULT '';
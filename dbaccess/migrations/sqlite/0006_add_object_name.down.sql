@@ -0,0 +1,5 @@
+ALTER TABLE t_multipart_upload DROP COLUMN object_name;
+
+DROP INDEX IF EXISTS idx_sha256_size;
+DROP INDEX IF EXISTS idx_object_name;
+ALTER TABLE t_file DROP COLUMN object_name;
@@ -0,0 +1,23 @@
+ALTER TABLE t_file ADD COLUMN object_name VARCHAR(255) NOT NULL DEFAULT '';
+
+-- 历史文件的对象名即文件名，回填期间移除触发器以保持update_time不变
+DROP TRIGGER IF EXISTS trg_t_file_update_time;
+UPDATE t_file SET object_name = name WHERE object_name = '';
+CREATE TRIGGER IF NOT EXISTS trg_t_file_update_time AFTER UPDATE ON t_file
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_file SET update_time = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
+
+CREATE INDEX IF NOT EXISTS idx_object_name ON t_file (bucket_id, object_name);
+CREATE INDEX IF NOT EXISTS idx_sha256_size ON t_file (sha256, size);
+
+ALTER TABLE t_multipart_upload ADD COLUMN object_name VARCHAR(255) NOT NULL DEFAULT '';
+
+DROP TRIGGER IF EXISTS trg_t_multipart_upload_update_time;
+UPDATE t_multipart_upload SET object_name = filename WHERE object_name = '';
+CREATE TRIGGER IF NOT EXISTS trg_t_multipart_upload_update_time AFTER UPDATE ON t_multipart_upload
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_multipart_upload SET update_time = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_object_blob;
+DROP TABLE IF EXISTS t_blob;
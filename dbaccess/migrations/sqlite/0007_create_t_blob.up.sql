@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS t_blob (
+    bucket_id VARCHAR(40) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    ref_count BIGINT NOT NULL,
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (bucket_id, hash)
+);
+
+CREATE TRIGGER IF NOT EXISTS trg_t_blob_update_time AFTER UPDATE ON t_blob
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_blob SET update_time = CURRENT_TIMESTAMP WHERE bucket_id = OLD.bucket_id AND hash = OLD.hash;
+END;
+
+CREATE TABLE IF NOT EXISTS t_object_blob (
+    bucket_id VARCHAR(40) NOT NULL,
+    object_name VARCHAR(255) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (bucket_id, object_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bucket_hash ON t_object_blob (bucket_id, hash);
+
+CREATE TRIGGER IF NOT EXISTS trg_t_object_blob_update_time AFTER UPDATE ON t_object_blob
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_object_blob SET update_time = CURRENT_TIMESTAMP WHERE bucket_id = OLD.bucket_id AND object_name = OLD.object_name;
+END;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_object_chunk;
+ALTER TABLE t_object_blob DROP COLUMN chunk_count;
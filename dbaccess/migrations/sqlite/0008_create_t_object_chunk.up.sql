@@ -0,0 +1,11 @@
+ALTER TABLE t_object_blob ADD COLUMN chunk_count INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS t_object_chunk (
+    bucket_id VARCHAR(40) NOT NULL,
+    object_name VARCHAR(255) NOT NULL,
+    seq INT NOT NULL,
+    hash CHAR(64) NOT NULL,
+    chunk_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    PRIMARY KEY (bucket_id, object_name, seq)
+);
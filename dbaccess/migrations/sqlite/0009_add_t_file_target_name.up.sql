@@ -0,0 +1 @@
+ALTER TABLE t_file ADD COLUMN target_name VARCHAR(255) NOT NULL DEFAULT '';
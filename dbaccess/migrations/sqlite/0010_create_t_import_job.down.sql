@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_t_import_job_update_time;
+DROP TABLE IF EXISTS t_import_job;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS t_import_job (
+    id VARCHAR(40) NOT NULL PRIMARY KEY,
+    bucket_id VARCHAR(40) NOT NULL,
+    source_url VARCHAR(2048) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    conflict VARCHAR(16) NOT NULL DEFAULT '',
+    sha256 CHAR(64) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    file_id VARCHAR(40) NOT NULL DEFAULT '',
+    error_message VARCHAR(1024) NOT NULL DEFAULT '',
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER IF NOT EXISTS trg_t_import_job_update_time AFTER UPDATE ON t_import_job
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_import_job SET update_time = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
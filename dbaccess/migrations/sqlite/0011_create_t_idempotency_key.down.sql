@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_t_idempotency_key_update_time;
+DROP TABLE IF EXISTS t_idempotency_key;
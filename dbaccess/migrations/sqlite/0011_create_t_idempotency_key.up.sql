@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS t_idempotency_key (
+    idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY,
+    token VARCHAR(40) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    response_code INT NOT NULL DEFAULT 0,
+    response_header TEXT,
+    response_body BLOB,
+    expire_at BIGINT NOT NULL,
+    create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_t_idempotency_key_expire_at ON t_idempotency_key (expire_at);
+
+CREATE TRIGGER IF NOT EXISTS trg_t_idempotency_key_update_time AFTER UPDATE ON t_idempotency_key
+FOR EACH ROW WHEN NEW.update_time = OLD.update_time
+BEGIN
+    UPDATE t_idempotency_key SET update_time = CURRENT_TIMESTAMP WHERE idempotency_key = OLD.idempotency_key;
+END;
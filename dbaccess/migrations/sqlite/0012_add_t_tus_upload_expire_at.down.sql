@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_t_tus_upload_expire_at;
+ALTER TABLE t_tus_upload DROP COLUMN expire_at;
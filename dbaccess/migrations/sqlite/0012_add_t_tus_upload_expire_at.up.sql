@@ -0,0 +1,5 @@
+ALTER TABLE t_tus_upload ADD COLUMN expire_at BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_t_tus_upload_expire_at ON t_tus_upload (expire_at);
+
+-- 已有的上传从迁移时起保留24小时
+UPDATE t_tus_upload SET expire_at = CAST(strftime('%s', 'now') AS INTEGER) + 86400;
@@ -0,0 +1,2 @@
+ALTER TABLE t_multipart_upload DROP COLUMN conflict;
+ALTER TABLE t_tus_upload DROP COLUMN conflict;
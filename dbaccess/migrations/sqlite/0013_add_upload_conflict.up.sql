@@ -0,0 +1,2 @@
+ALTER TABLE t_tus_upload ADD COLUMN conflict VARCHAR(16) NOT NULL DEFAULT 'reject';
+ALTER TABLE t_multipart_upload ADD COLUMN conflict VARCHAR(16) NOT NULL DEFAULT 'reject';
@@ -0,0 +1 @@
+ALTER TABLE t_idempotency_key DROP COLUMN body_digest;
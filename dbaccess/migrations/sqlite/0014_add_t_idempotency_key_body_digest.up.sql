@@ -0,0 +1 @@
+ALTER TABLE t_idempotency_key ADD COLUMN body_digest CHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE t_file ADD COLUMN expire_at BIGINT NOT NULL DEFAULT 0;
+
+-- 已有的待上传记录从迁移时起保留24小时
+UPDATE t_file SET expire_at = CAST(strftime('%s', 'now') AS INTEGER) + 86400 WHERE status = 'pending';
@@ -0,0 +1,4 @@
+ALTER TABLE t_import_job ADD COLUMN expire_at BIGINT NOT NULL DEFAULT 0;
+
+-- 未结束的任务从迁移时起按默认超时时间(30分钟)和1分钟余量计算截止时间
+UPDATE t_import_job SET expire_at = CAST(strftime('%s', 'now') AS INTEGER) + 1860 WHERE status IN ('pending', 'running');
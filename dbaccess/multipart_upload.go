@@ -0,0 +1,100 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"database/sql"
+)
+
+type DBMultipartUpload struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewDBMultipartUpload() interfaces.DBMultipartUpload {
+	return &DBMultipartUpload{
+		db:      dbPool,
+		dialect: newDialect(dbType),
+	}
+}
+
+func (d *DBMultipartUpload) CreateMultipartUpload(ctx context.Context, upload *interfaces.MultipartUpload) error {
+	query := `
+		INSERT INTO t_multipart_upload
+		(id, bucket_id, filename, object_name, content_type, size, part_size, storage_upload_id, conflict, file_id)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query),
+		upload.ID, upload.BucketID, upload.Filename, upload.ObjectName, upload.ContentType, upload.Size, upload.PartSize, upload.StorageUploadID, upload.Conflict, upload.FileID)
+
+	return err
+}
+
+func (d *DBMultipartUpload) GetMultipartUpload(ctx context.Context, uploadID string) (*interfaces.MultipartUpload, error) {
+	query := `
+		SELECT
+			id,
+			bucket_id,
+			filename,
+			object_name,
+			content_type,
+			size,
+			part_size,
+			storage_upload_id,
+			conflict,
+			file_id,
+			create_time,
+			update_time
+		FROM t_multipart_upload WHERE id = ?
+	`
+
+	var upload interfaces.MultipartUpload
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), uploadID).Scan(
+		&upload.ID,
+		&upload.BucketID,
+		&upload.Filename,
+		&upload.ObjectName,
+		&upload.ContentType,
+		&upload.Size,
+		&upload.PartSize,
+		&upload.StorageUploadID,
+		&upload.Conflict,
+		&upload.FileID,
+		&upload.CreateTime,
+		&upload.UpdateTime)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+func (d *DBMultipartUpload) CompleteMultipartUpload(ctx context.Context, uploadID, fileID string) error {
+	query := `UPDATE t_multipart_upload SET file_id = ? WHERE id = ? AND file_id = ''`
+	result, err := d.db.ExecContext(ctx, d.dialect.rebind(query), fileID, uploadID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return interfaces.ErrUploadCompleted
+	}
+
+	return nil
+}
+
+func (d *DBMultipartUpload) DeleteMultipartUpload(ctx context.Context, uploadID string) error {
+	query := `DELETE FROM t_multipart_upload WHERE id = ?`
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query), uploadID)
+	return err
+}
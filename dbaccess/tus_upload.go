@@ -0,0 +1,206 @@
+package dbaccess
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"database/sql"
+)
+
+type DBTusUpload struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewDBTusUpload() interfaces.DBTusUpload {
+	return &DBTusUpload{
+		db:      dbPool,
+		dialect: newDialect(dbType),
+	}
+}
+
+func (d *DBTusUpload) CreateTusUpload(ctx context.Context, upload *interfaces.TusUpload) error {
+	query := `
+		INSERT INTO t_tus_upload
+		(id, bucket_id, filename, content_type, size, upload_offset, metadata, conflict, file_id, expire_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.ExecContext(ctx, d.dialect.rebind(query),
+		upload.ID, upload.BucketID, upload.Filename, upload.ContentType, upload.Size, upload.Offset, upload.Metadata, upload.Conflict, upload.FileID, upload.ExpireAt)
+
+	return err
+}
+
+func (d *DBTusUpload) GetTusUpload(ctx context.Context, uploadID string) (*interfaces.TusUpload, error) {
+	query := `
+		SELECT
+			id,
+			bucket_id,
+			filename,
+			content_type,
+			size,
+			upload_offset,
+			metadata,
+			conflict,
+			file_id,
+			expire_at,
+			create_time,
+			update_time
+		FROM t_tus_upload WHERE id = ?
+	`
+
+	var upload interfaces.TusUpload
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(query), uploadID).Scan(
+		&upload.ID,
+		&upload.BucketID,
+		&upload.Filename,
+		&upload.ContentType,
+		&upload.Size,
+		&upload.Offset,
+		&upload.Metadata,
+		&upload.Conflict,
+		&upload.FileID,
+		&upload.ExpireAt,
+		&upload.CreateTime,
+		&upload.UpdateTime)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+func (d *DBTusUpload) AppendTusPart(ctx context.Context, part *interfaces.TusPart, expireAt int64) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 以当前偏移量作为条件更新，保证并发追加时只有一个请求成功
+	update := `UPDATE t_tus_upload SET upload_offset = upload_offset + ?, expire_at = ? WHERE id = ? AND upload_offset = ? AND file_id = ''`
+	result, err := tx.ExecContext(ctx, d.dialect.rebind(update), part.Size, expireAt, part.UploadID, part.Offset)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return interfaces.ErrOffsetConflict
+	}
+
+	insert := `INSERT INTO t_tus_part (upload_id, upload_offset, size, object_name) VALUES (?, ?, ?, ?)`
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(insert), part.UploadID, part.Offset, part.Size, part.ObjectName); err != nil {
+		if d.dialect.isDuplicateEntry(err) {
+			return interfaces.ErrOffsetConflict
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (d *DBTusUpload) GetTusParts(ctx context.Context, uploadID string) ([]*interfaces.TusPart, error) {
+	query := `
+		SELECT upload_id, upload_offset, size, object_name
+		FROM t_tus_part
+		WHERE upload_id = ?
+		ORDER BY upload_offset ASC
+	`
+
+	rows, err := d.db.QueryContext(ctx, d.dialect.rebind(query), uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []*interfaces.TusPart
+	for rows.Next() {
+		var part interfaces.TusPart
+		if err = rows.Scan(&part.UploadID, &part.Offset, &part.Size, &part.ObjectName); err != nil {
+			return nil, err
+		}
+		parts = append(parts, &part)
+	}
+
+	return parts, rows.Err()
+}
+
+func (d *DBTusUpload) CompleteTusUpload(ctx context.Context, uploadID, fileID string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	update := `UPDATE t_tus_upload SET file_id = ? WHERE id = ? AND file_id = ''`
+	result, err := tx.ExecContext(ctx, d.dialect.rebind(update), fileID, uploadID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return interfaces.ErrOffsetConflict
+	}
+
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(`DELETE FROM t_tus_part WHERE upload_id = ?`), uploadID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (d *DBTusUpload) DeleteTusUpload(ctx context.Context, uploadID string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(`DELETE FROM t_tus_part WHERE upload_id = ?`), uploadID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, d.dialect.rebind(`DELETE FROM t_tus_upload WHERE id = ?`), uploadID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (d *DBTusUpload) GetExpiredTusUploads(ctx context.Context, expireAt int64, limit int) ([]*interfaces.TusUpload, error) {
+	query := `
+		SELECT id, bucket_id, filename, content_type, size, upload_offset, metadata, file_id, expire_at
+		FROM t_tus_upload
+		WHERE expire_at < ?
+		ORDER BY expire_at ASC
+		LIMIT ?
+	`
+
+	rows, err := d.db.QueryContext(ctx, d.dialect.rebind(query), expireAt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*interfaces.TusUpload
+	for rows.Next() {
+		var upload interfaces.TusUpload
+		if err = rows.Scan(&upload.ID, &upload.BucketID, &upload.Filename, &upload.ContentType, &upload.Size,
+			&upload.Offset, &upload.Metadata, &upload.FileID, &upload.ExpireAt); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, &upload)
+	}
+
+	return uploads, rows.Err()
+}
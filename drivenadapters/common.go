@@ -2,19 +2,72 @@ package drivenadapters
 
 import (
 	"FileEngine/common"
-
-	"github.com/minio/minio-go/v7"
+	"FileEngine/interfaces"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
 )
 
+// MaxPartNumber 分片号上限，与S3保持一致
+const MaxPartNumber = 10000
+
 var (
-	config      *common.Config
-	minioClient *minio.Client
+	config *common.Config
 )
 
 func SetConfig(c *common.Config) {
 	config = c
 }
 
-func SetMinioClient(client *minio.Client) {
-	minioClient = client
+// sectionReader 将reader定位到offset并限制只读取length个字节，reader不支持Seek时跳过offset之前的内容
+func sectionReader(reader io.ReadCloser, offset, length int64) (io.ReadCloser, error) {
+	var err error
+	if seeker, ok := reader.(io.Seeker); ok {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, reader, offset)
+	}
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+	}
+
+	return &limitedReadCloser{Reader: io.LimitReader(reader, length), Closer: reader}, nil
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// partQuery 分片上传URL中标识分片的查询参数，与S3保持一致
+func partQuery(uploadID string, partNumber int) url.Values {
+	query := url.Values{}
+	query.Set("uploadId", uploadID)
+	query.Set("partNumber", strconv.Itoa(partNumber))
+	return query
+}
+
+// signPostPolicy 为由FileEngine自身接收表单上传的存储生成POST策略，表单字段与S3保持一致
+func signPostPolicy(signer *common.URLSigner, publicURL, bucketID, objectName string, conditions *interfaces.UploadConditions, expiration time.Duration) *interfaces.PresignedPost {
+	encoded, signature := signer.SignPolicy(&common.PostPolicy{
+		Expiration:  time.Now().Add(expiration).Unix(),
+		Bucket:      bucketID,
+		Key:         objectName,
+		ContentType: conditions.ContentType,
+		MinSize:     conditions.MinSize,
+		MaxSize:     conditions.MaxSize,
+	})
+
+	return &interfaces.PresignedPost{
+		URL: common.BuildBucketURL(publicURL, bucketID),
+		FormData: map[string]string{
+			"key":          objectName,
+			"Content-Type": conditions.ContentType,
+			"policy":       encoded,
+			"signature":    signature,
+		},
+	}
 }
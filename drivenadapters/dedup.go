@@ -0,0 +1,243 @@
+package drivenadapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 去重模式
+const (
+	DedupNone   = ""       // 不去重
+	DedupObject = "object" // 按整个对象的内容去重
+	DedupChunk  = "chunk"  // 按FastCDC切分的块去重，适合反复上传的大文件的不同版本
+)
+
+// 数据块在底层存储中的对象名前缀
+const blobPrefix = "blobs/"
+
+// DedupAdapter 内容寻址的去重存储装饰器。对象内容按SHA-256只存储一份数据块，逻辑对象名经映射表指向数据块并维护引用计数，
+// 删除对象时引用计数减一，归零后回收数据块。
+// 数据块写入时内容哈希尚未知，因此每个数据块使用唯一的存储对象名，由映射表以哈希为键查找，回收与新建同一内容的数据块互不影响。
+// 分块模式下对象按内容切分为块，每个块作为数据块存储，对象保存有序的块清单，读取时按清单拼接。
+// 映射表中不存在的对象(启用去重前写入、客户端直传或分片合并的对象)按原对象名访问底层存储
+type DedupAdapter struct {
+	interfaces.StorageAdapter
+	db        interfaces.DBBlob
+	mode      string
+	publicURL string
+	signer    *common.URLSigner
+}
+
+func NewDedupAdapter(storage interfaces.StorageAdapter, db interfaces.DBBlob, mode string) (interfaces.StorageAdapter, error) {
+	if mode != DedupObject && mode != DedupChunk {
+		return nil, fmt.Errorf("unknown dedup mode %q", mode)
+	}
+
+	return &DedupAdapter{
+		StorageAdapter: storage,
+		db:             db,
+		mode:           mode,
+		publicURL:      config.Server.PublicURL,
+		signer:         common.NewURLSigner(config.Server.SignKey),
+	}, nil
+}
+
+func (d *DedupAdapter) Upload(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error {
+	if d.mode == DedupChunk {
+		return d.uploadChunks(ctx, bucketID, objectName, reader, size, contentType)
+	}
+	return d.uploadBlob(ctx, bucketID, objectName, reader, size, contentType)
+}
+
+// uploadBlob 将整个对象作为一个数据块存储
+func (d *DedupAdapter) uploadBlob(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error {
+	// 先写入新的数据块并计算哈希，内容已存在时再删除
+	storageKey := blobPrefix + uuid.New().String()
+	hash := sha256.New()
+	counter := &countingWriter{}
+	err := d.StorageAdapter.Upload(ctx, bucketID, storageKey, io.TeeReader(reader, io.MultiWriter(hash, counter)), size, contentType)
+	if err != nil {
+		return err
+	}
+
+	object := &interfaces.ObjectBlob{
+		BucketID:    bucketID,
+		ObjectName:  objectName,
+		Hash:        hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  storageKey,
+		Size:        counter.n,
+		ContentType: contentType,
+	}
+	attachedKey, released, err := d.db.AttachBlob(ctx, object)
+	if errors.Is(err, interfaces.ErrDuplicateEntry) {
+		// 并发写入相同内容或同名对象，重试一次即可引用已创建的数据块
+		attachedKey, released, err = d.db.AttachBlob(ctx, object)
+	}
+	if err != nil {
+		d.deleteBlob(ctx, bucketID, storageKey)
+		return fmt.Errorf("failed to attach blob: %w", err)
+	}
+
+	if attachedKey != storageKey {
+		d.deleteBlob(ctx, bucketID, storageKey)
+	}
+	d.deleteBlobs(ctx, bucketID, released)
+
+	return nil
+}
+
+// Download 分块存储的对象返回可Seek的读取器，按需打开所需的块
+func (d *DedupAdapter) Download(ctx context.Context, bucketID, objectName string) (io.ReadCloser, error) {
+	storageKey, object, err := d.resolve(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	if object != nil && object.ChunkCount > 0 {
+		chunks, err := d.db.GetObjectChunks(ctx, bucketID, objectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get object chunks: %w", err)
+		}
+		return newChunkReader(ctx, d.StorageAdapter, bucketID, chunks, object.Size), nil
+	}
+
+	return d.StorageAdapter.Download(ctx, bucketID, storageKey)
+}
+
+func (d *DedupAdapter) DownloadRange(ctx context.Context, bucketID, objectName string, offset, length int64) (io.ReadCloser, error) {
+	storageKey, object, err := d.resolve(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	if object != nil && object.ChunkCount > 0 {
+		chunks, err := d.db.GetObjectChunks(ctx, bucketID, objectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get object chunks: %w", err)
+		}
+		return sectionReader(newChunkReader(ctx, d.StorageAdapter, bucketID, chunks, object.Size), offset, length)
+	}
+
+	return d.StorageAdapter.DownloadRange(ctx, bucketID, storageKey, offset, length)
+}
+
+func (d *DedupAdapter) Delete(ctx context.Context, bucketID, objectName string) error {
+	released, found, err := d.db.DetachBlob(ctx, bucketID, objectName)
+	if err != nil {
+		return fmt.Errorf("failed to detach blob: %w", err)
+	}
+	if !found {
+		return d.StorageAdapter.Delete(ctx, bucketID, objectName)
+	}
+
+	d.deleteBlobs(ctx, bucketID, released)
+	return nil
+}
+
+func (d *DedupAdapter) FileExists(ctx context.Context, bucketID, objectName string) (bool, error) {
+	storageKey, object, err := d.resolve(ctx, bucketID, objectName)
+	if err != nil {
+		return false, err
+	}
+	if object != nil && object.ChunkCount > 0 {
+		return true, nil
+	}
+
+	return d.StorageAdapter.FileExists(ctx, bucketID, storageKey)
+}
+
+func (d *DedupAdapter) GetFileInfo(ctx context.Context, bucketID, objectName string) (*interfaces.StorageFileInfo, error) {
+	storageKey, object, err := d.resolve(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	if object != nil && object.ChunkCount > 0 {
+		return &interfaces.StorageFileInfo{
+			Size:         object.Size,
+			ContentType:  object.ContentType,
+			LastModified: object.CreateTime.Format("2006-01-02 15:04:05"),
+			ETag:         object.Hash,
+		}, nil
+	}
+
+	info, err := d.StorageAdapter.GetFileInfo(ctx, bucketID, storageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 数据块的类型和修改时间来自第一个写入者，以对象自身的为准
+	if object != nil {
+		info.ContentType = object.ContentType
+		if object.CreateTime != nil {
+			info.LastModified = object.CreateTime.Format("2006-01-02 15:04:05")
+		}
+	}
+	return info, nil
+}
+
+// SignsURLs 分块存储的对象由FileEngine自身签发下载URL，其余URL由底层存储签发
+func (d *DedupAdapter) SignsURLs() bool {
+	if d.mode == DedupChunk {
+		return true
+	}
+	signer, ok := d.StorageAdapter.(interfaces.URLSigningStorage)
+	return ok && signer.SignsURLs()
+}
+
+// GeneratePresignedDownloadURL 分块存储的对象无法由底层存储直接提供，由FileEngine自身签发URL并拼接块
+func (d *DedupAdapter) GeneratePresignedDownloadURL(ctx context.Context, bucketID, objectName string, expiration time.Duration) (string, error) {
+	storageKey, object, err := d.resolve(ctx, bucketID, objectName)
+	if err != nil {
+		return "", err
+	}
+	if object != nil && object.ChunkCount > 0 {
+		query := d.signer.Sign(http.MethodGet, bucketID, objectName, time.Now().Add(expiration), nil)
+		return common.BuildObjectURL(d.publicURL, bucketID, objectName, query), nil
+	}
+
+	return d.StorageAdapter.GeneratePresignedDownloadURL(ctx, bucketID, storageKey, expiration)
+}
+
+// resolve 返回对象在底层存储中的对象名，不存在映射时即为原对象名
+func (d *DedupAdapter) resolve(ctx context.Context, bucketID, objectName string) (string, *interfaces.ObjectBlob, error) {
+	object, err := d.db.GetObjectBlob(ctx, bucketID, objectName)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get object blob: %w", err)
+	}
+	if object == nil {
+		return objectName, nil, nil
+	}
+
+	return object.StorageKey, object, nil
+}
+
+// deleteBlob 删除不再被引用的数据块，失败时仅记录日志，不影响对象本身的操作结果
+func (d *DedupAdapter) deleteBlob(ctx context.Context, bucketID, storageKey string) {
+	if err := d.StorageAdapter.Delete(context.WithoutCancel(ctx), bucketID, storageKey); err != nil {
+		log.Printf("[WARN] failed to delete blob %s/%s: %v", bucketID, storageKey, err)
+	}
+}
+
+func (d *DedupAdapter) deleteBlobs(ctx context.Context, bucketID string, storageKeys []string) {
+	for _, storageKey := range storageKeys {
+		d.deleteBlob(ctx, bucketID, storageKey)
+	}
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
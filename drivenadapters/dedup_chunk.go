@@ -0,0 +1,235 @@
+package drivenadapters
+
+import (
+	"FileEngine/interfaces"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// uploadChunks 将对象按内容切分为块，已存在的块只增加引用，仅上传新的块
+func (d *DedupAdapter) uploadChunks(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error {
+	hash := sha256.New()
+	chunker := newChunker(io.TeeReader(reader, hash))
+
+	var chunks []*interfaces.ObjectChunk
+	var offset int64
+	for {
+		data, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			d.releaseChunks(ctx, bucketID, chunks)
+			return err
+		}
+
+		chunk, err := d.storeChunk(ctx, bucketID, data)
+		if err != nil {
+			d.releaseChunks(ctx, bucketID, chunks)
+			return err
+		}
+		chunk.Seq = len(chunks)
+		chunk.Offset = offset
+		offset += chunk.Size
+		chunks = append(chunks, chunk)
+	}
+
+	if size >= 0 && offset != size {
+		d.releaseChunks(ctx, bucketID, chunks)
+		return fmt.Errorf("object size mismatch: expected %d bytes, got %d", size, offset)
+	}
+	// 空对象没有块，按整个对象存储
+	if len(chunks) == 0 {
+		return d.uploadBlob(ctx, bucketID, objectName, bytes.NewReader(nil), 0, contentType)
+	}
+
+	object := &interfaces.ObjectBlob{
+		BucketID:    bucketID,
+		ObjectName:  objectName,
+		Hash:        hex.EncodeToString(hash.Sum(nil)),
+		Size:        offset,
+		ContentType: contentType,
+	}
+	released, err := d.db.AttachChunks(ctx, object, chunks)
+	if err != nil {
+		d.releaseChunks(ctx, bucketID, chunks)
+		return fmt.Errorf("failed to attach chunks: %w", err)
+	}
+
+	d.deleteBlobs(ctx, bucketID, released)
+	return nil
+}
+
+// storeChunk 引用内容相同的数据块，不存在时上传为新的数据块
+func (d *DedupAdapter) storeChunk(ctx context.Context, bucketID string, data []byte) (*interfaces.ObjectChunk, error) {
+	sum := sha256.Sum256(data)
+	chunk := &interfaces.ObjectChunk{
+		Hash: hex.EncodeToString(sum[:]),
+		Size: int64(len(data)),
+	}
+
+	storageKey, found, err := d.db.AcquireBlob(ctx, bucketID, chunk.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire blob: %w", err)
+	}
+	if found {
+		chunk.StorageKey = storageKey
+		return chunk, nil
+	}
+
+	// 数据块写入存储后才创建记录，其他上传不会引用到尚未写完的数据块
+	candidate := blobPrefix + uuid.New().String()
+	err = d.StorageAdapter.Upload(ctx, bucketID, candidate, bytes.NewReader(data), chunk.Size, "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	storageKey, err = d.db.CreateBlob(ctx, bucketID, chunk.Hash, candidate, chunk.Size)
+	if errors.Is(err, interfaces.ErrDuplicateEntry) {
+		storageKey, err = d.db.CreateBlob(ctx, bucketID, chunk.Hash, candidate, chunk.Size)
+	}
+	if err != nil {
+		d.deleteBlob(ctx, bucketID, candidate)
+		return nil, fmt.Errorf("failed to create blob: %w", err)
+	}
+	if storageKey != candidate {
+		d.deleteBlob(ctx, bucketID, candidate)
+	}
+
+	chunk.StorageKey = storageKey
+	return chunk, nil
+}
+
+// releaseChunks 上传失败时释放已取得的块引用
+func (d *DedupAdapter) releaseChunks(ctx context.Context, bucketID string, chunks []*interfaces.ObjectChunk) {
+	if len(chunks) == 0 {
+		return
+	}
+
+	hashes := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		hashes = append(hashes, chunk.Hash)
+	}
+
+	released, err := d.db.ReleaseBlobs(context.WithoutCancel(ctx), bucketID, hashes)
+	if err != nil {
+		log.Printf("[WARN] failed to release %d chunks in bucket %s: %v", len(hashes), bucketID, err)
+		return
+	}
+	d.deleteBlobs(ctx, bucketID, released)
+}
+
+// chunkReader 按块清单顺序读取分块存储的对象，支持Seek以实现范围读取
+type chunkReader struct {
+	ctx      context.Context
+	storage  interfaces.StorageAdapter
+	bucketID string
+	chunks   []*interfaces.ObjectChunk
+	size     int64
+	offset   int64
+	index    int           // 当前打开的块
+	current  io.ReadCloser // 当前块的读取器，位于offset处
+}
+
+func newChunkReader(ctx context.Context, storage interfaces.StorageAdapter, bucketID string, chunks []*interfaces.ObjectChunk, size int64) *chunkReader {
+	return &chunkReader{
+		ctx:      ctx,
+		storage:  storage,
+		bucketID: bucketID,
+		chunks:   chunks,
+		size:     size,
+	}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.current == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	chunk := r.chunks[r.index]
+	remaining := chunk.Offset + chunk.Size - r.offset
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := r.current.Read(p)
+	r.offset += int64(n)
+	if r.offset == chunk.Offset+chunk.Size {
+		r.closeCurrent()
+		return n, nil
+	}
+	if err == io.EOF {
+		return n, fmt.Errorf("chunk %d of %d bytes ended at offset %d: %w", chunk.Seq, chunk.Size, r.offset-chunk.Offset, io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+func (r *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if offset != r.offset {
+		r.closeCurrent()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+func (r *chunkReader) Close() error {
+	r.closeCurrent()
+	return nil
+}
+
+// open 打开offset所在的块并定位到offset
+func (r *chunkReader) open() error {
+	r.index = sort.Search(len(r.chunks), func(i int) bool {
+		return r.chunks[i].Offset+r.chunks[i].Size > r.offset
+	})
+	chunk := r.chunks[r.index]
+
+	// 从块的中间开始读取时只获取块的剩余部分
+	var reader io.ReadCloser
+	var err error
+	if skip := r.offset - chunk.Offset; skip > 0 {
+		reader, err = r.storage.DownloadRange(r.ctx, r.bucketID, chunk.StorageKey, skip, chunk.Size-skip)
+	} else {
+		reader, err = r.storage.Download(r.ctx, r.bucketID, chunk.StorageKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download chunk %d: %w", chunk.Seq, err)
+	}
+
+	r.current = reader
+	return nil
+}
+
+func (r *chunkReader) closeCurrent() {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+}
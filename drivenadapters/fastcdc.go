@@ -0,0 +1,122 @@
+package drivenadapters
+
+import (
+	"io"
+	"math/bits"
+)
+
+// FastCDC分块参数，块大小在最小值和最大值之间，按内容决定切分点并趋向平均值
+const (
+	chunkMinSize = 256 * 1024
+	chunkAvgSize = 1024 * 1024
+	chunkMaxSize = 4 * 1024 * 1024
+)
+
+var (
+	// gearTable 滚动哈希的随机表，由固定种子生成，修改后切分点随之改变，已存储的块不再能被新上传复用
+	gearTable [256]uint64
+	// 归一化分块：未达到平均大小时使用更严格的掩码，超过后使用更宽松的掩码，使块大小集中在平均值附近
+	chunkMaskS uint64
+	chunkMaskL uint64
+)
+
+func init() {
+	// splitmix64
+	seed := uint64(0x46696c65456e67) // "FileEng"
+	for i := range gearTable {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gearTable[i] = z ^ (z >> 31)
+	}
+
+	// 哈希左移累加，高位受最近的64个字节影响，掩码取高位
+	avgBits := bits.Len(uint(chunkAvgSize)) - 1
+	chunkMaskS = ^uint64(0) << (64 - (avgBits + 2))
+	chunkMaskL = ^uint64(0) << (64 - (avgBits - 2))
+}
+
+// chunker 按FastCDC算法将流切分为内容定义的块，内容局部修改时只影响附近的块
+type chunker struct {
+	reader io.Reader
+	buf    []byte
+	start  int
+	end    int
+	eof    bool
+}
+
+func newChunker(reader io.Reader) *chunker {
+	return &chunker{
+		reader: reader,
+		buf:    make([]byte, chunkMaxSize),
+	}
+}
+
+// Next 返回下一个块，数据在下次调用前有效，读取完毕时返回io.EOF
+func (c *chunker) Next() ([]byte, error) {
+	if err := c.fill(); err != nil {
+		return nil, err
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+
+	n := cutPoint(c.buf[c.start:c.end])
+	chunk := c.buf[c.start : c.start+n]
+	c.start += n
+	return chunk, nil
+}
+
+// fill 将未切分的数据移到缓冲区开头并读满缓冲区，保证切分时能看到最大块长度的数据
+func (c *chunker) fill() error {
+	if c.eof || c.end-c.start == len(c.buf) {
+		return nil
+	}
+
+	c.end = copy(c.buf, c.buf[c.start:c.end])
+	c.start = 0
+	for c.end < len(c.buf) {
+		n, err := c.reader.Read(c.buf[c.end:])
+		c.end += n
+		if err == io.EOF {
+			c.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cutPoint 返回data中第一个块的长度
+func cutPoint(data []byte) int {
+	n := len(data)
+	if n <= chunkMinSize {
+		return n
+	}
+	if n > chunkMaxSize {
+		n = chunkMaxSize
+	}
+	normal := chunkAvgSize
+	if n < normal {
+		normal = n
+	}
+
+	var fp uint64
+	i := chunkMinSize
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gearTable[data[i]]
+		if fp&chunkMaskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gearTable[data[i]]
+		if fp&chunkMaskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
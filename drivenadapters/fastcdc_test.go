@@ -0,0 +1,104 @@
+package drivenadapters
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func TestCutPoint(t *testing.T) {
+	random := randomBytes(1, 3*chunkMaxSize)
+	tests := []struct {
+		name     string
+		data     []byte
+		min, max int
+	}{
+		{"empty", nil, 0, 0},
+		{"shorter than min size", random[:chunkMinSize-1], chunkMinSize - 1, chunkMinSize - 1},
+		{"exactly min size", random[:chunkMinSize], chunkMinSize, chunkMinSize},
+		{"shorter than max size", random[:chunkMinSize+chunkAvgSize], chunkMinSize + 1, chunkMinSize + chunkAvgSize},
+		{"longer than max size", random, chunkMinSize + 1, chunkMaxSize},
+		{"zeros longer than max size", make([]byte, 2*chunkMaxSize), chunkMinSize + 1, chunkMaxSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := cutPoint(tt.data)
+			if n < tt.min || n > tt.max {
+				t.Fatalf("cutPoint() = %d, want in [%d, %d]", n, tt.min, tt.max)
+			}
+			if again := cutPoint(tt.data); again != n {
+				t.Fatalf("cutPoint() is not deterministic: %d then %d", n, again)
+			}
+		})
+	}
+}
+
+// chunkAll 返回chunker切分出的全部块
+func chunkAll(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	c := newChunker(bytes.NewReader(data))
+	var chunks [][]byte
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		chunks = append(chunks, append([]byte(nil), chunk...))
+	}
+}
+
+func TestChunker(t *testing.T) {
+	data := randomBytes(2, 16*chunkAvgSize)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"single small chunk", data[:1000]},
+		{"many chunks", data},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkAll(t, tt.data)
+			if got := bytes.Join(chunks, nil); !bytes.Equal(got, tt.data) {
+				t.Fatalf("chunks do not reassemble to the input: got %d bytes, want %d", len(got), len(tt.data))
+			}
+			for i, chunk := range chunks {
+				if len(chunk) > chunkMaxSize || (i < len(chunks)-1 && len(chunk) <= chunkMinSize) {
+					t.Fatalf("chunk %d has size %d, want in (%d, %d]", i, len(chunk), chunkMinSize, chunkMaxSize)
+				}
+			}
+		})
+	}
+}
+
+// 在开头插入数据后，只有附近的块发生变化，其余块仍可复用
+func TestChunkerInsertion(t *testing.T) {
+	data := randomBytes(3, 16*chunkAvgSize)
+	original := chunkAll(t, data)
+	modified := chunkAll(t, append(randomBytes(4, 1000), data...))
+
+	seen := make(map[string]bool, len(original))
+	for _, chunk := range original {
+		seen[string(chunk)] = true
+	}
+	shared := 0
+	for _, chunk := range modified {
+		if seen[string(chunk)] {
+			shared++
+		}
+	}
+	if shared < len(original)-2 {
+		t.Fatalf("only %d of %d chunks are shared after inserting a prefix", shared, len(original))
+	}
+}
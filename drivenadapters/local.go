@@ -0,0 +1,480 @@
+package drivenadapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 对象元数据存放目录，与桶目录同级
+	localMetaDir = ".meta"
+	// 分片上传存放目录，每个分片上传一个子目录
+	localMultipartDir = ".multipart"
+)
+
+type LocalAdapter struct {
+	rootDir   string
+	publicURL string
+	signer    *common.URLSigner
+}
+
+// 对象元数据
+type localObjectMeta struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+}
+
+// 分片上传元数据
+type localMultipartMeta struct {
+	BucketID    string `json:"bucket_id"`
+	ObjectName  string `json:"object_name"`
+	ContentType string `json:"content_type"`
+}
+
+func init() {
+	RegisterStorageDriver("local", NewLocalAdapter)
+}
+
+func NewLocalAdapter(cfg *common.StorageConfig) (interfaces.StorageAdapter, error) {
+	options := &common.LocalConfig{}
+	if err := cfg.DecodeOptions(options); err != nil {
+		return nil, fmt.Errorf("failed to decode local options: %w", err)
+	}
+	if options.RootDir == "" {
+		return nil, fmt.Errorf("local storage requires rootDir")
+	}
+
+	return &LocalAdapter{
+		rootDir:   options.RootDir,
+		publicURL: config.Server.PublicURL,
+		signer:    common.NewURLSigner(config.Server.SignKey),
+	}, nil
+}
+
+func (l *LocalAdapter) Upload(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error {
+	dataPath, metaPath, err := l.resolve(bucketID, objectName)
+	if err != nil {
+		return err
+	}
+
+	return writeLocalObject(ctx, dataPath, metaPath, reader, size, contentType)
+}
+
+func (l *LocalAdapter) Download(ctx context.Context, bucketID, objectName string) (io.ReadCloser, error) {
+	dataPath, _, err := l.resolve(bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(dataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	return f, nil
+}
+
+func (l *LocalAdapter) DownloadRange(ctx context.Context, bucketID, objectName string, offset, length int64) (io.ReadCloser, error) {
+	reader, err := l.Download(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return sectionReader(reader, offset, length)
+}
+
+func (l *LocalAdapter) Delete(ctx context.Context, bucketID, objectName string) error {
+	dataPath, metaPath, err := l.resolve(bucketID, objectName)
+	if err != nil {
+		return err
+	}
+
+	// 与对象存储保持一致，删除不存在的对象不报错
+	if err = os.Remove(dataPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	if err = os.Remove(metaPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete object meta: %w", err)
+	}
+
+	pruneEmptyDirs(filepath.Dir(dataPath), filepath.Join(l.rootDir, bucketID))
+	pruneEmptyDirs(filepath.Dir(metaPath), filepath.Join(l.rootDir, localMetaDir, bucketID))
+
+	return nil
+}
+
+func (l *LocalAdapter) FileExists(ctx context.Context, bucketID, objectName string) (bool, error) {
+	dataPath, _, err := l.resolve(bucketID, objectName)
+	if err != nil {
+		return false, err
+	}
+
+	stat, err := os.Stat(dataPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return !stat.IsDir(), nil
+}
+
+func (l *LocalAdapter) GetFileInfo(ctx context.Context, bucketID, objectName string) (*interfaces.StorageFileInfo, error) {
+	dataPath, metaPath, err := l.resolve(bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("object %s does not exist in bucket %s", objectName, bucketID)
+	}
+
+	meta := &localObjectMeta{
+		ContentType: "application/octet-stream",
+	}
+	if content, err := os.ReadFile(metaPath); err == nil {
+		json.Unmarshal(content, meta)
+	}
+
+	return &interfaces.StorageFileInfo{
+		Size:         stat.Size(),
+		ContentType:  meta.ContentType,
+		LastModified: stat.ModTime().Format("2006-01-02 15:04:05"),
+		ETag:         meta.ETag,
+	}, nil
+}
+
+// SignsURLs 本地存储的预签名URL均由FileEngine自身签发
+func (l *LocalAdapter) SignsURLs() bool {
+	return true
+}
+
+// 生成预签名下载URL，由FileEngine自身校验签名并提供下载
+func (l *LocalAdapter) GeneratePresignedDownloadURL(ctx context.Context, bucketID, objectName string, expiration time.Duration) (string, error) {
+	exists, err := l.FileExists(ctx, bucketID, objectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to check object existence: %w", err)
+	}
+
+	if !exists {
+		return "", fmt.Errorf("object %s does not exist in bucket %s", objectName, bucketID)
+	}
+
+	query := l.signer.Sign(http.MethodGet, bucketID, objectName, time.Now().Add(expiration), nil)
+	return common.BuildObjectURL(l.publicURL, bucketID, objectName, query), nil
+}
+
+// 生成预签名上传URL，由FileEngine自身校验签名并接收上传
+func (l *LocalAdapter) GeneratePresignedUploadURL(ctx context.Context, bucketID, objectName string, expiration time.Duration) (string, error) {
+	if _, _, err := l.resolve(bucketID, objectName); err != nil {
+		return "", err
+	}
+
+	query := l.signer.Sign(http.MethodPut, bucketID, objectName, time.Now().Add(expiration), nil)
+	return common.BuildObjectURL(l.publicURL, bucketID, objectName, query), nil
+}
+
+// resolve 将桶ID和对象名映射为数据文件与元数据文件路径，拒绝越出根目录的对象名
+func (l *LocalAdapter) resolve(bucketID, objectName string) (dataPath, metaPath string, err error) {
+	if bucketID == "" || strings.HasPrefix(bucketID, ".") || strings.ContainsAny(bucketID, `/\`) {
+		return "", "", fmt.Errorf("invalid bucket id %s", bucketID)
+	}
+	if objectName == "" || strings.Contains(objectName, `\`) || path.Clean("/" + objectName)[1:] != objectName {
+		return "", "", fmt.Errorf("invalid object name %s", objectName)
+	}
+
+	dataPath = filepath.Join(l.rootDir, bucketID, filepath.FromSlash(objectName))
+	metaPath = filepath.Join(l.rootDir, localMetaDir, bucketID, filepath.FromSlash(objectName)+".json")
+	return dataPath, metaPath, nil
+}
+
+// 生成预签名POST策略，由FileEngine自身校验策略并接收表单上传
+func (l *LocalAdapter) GeneratePresignedPostPolicy(ctx context.Context, bucketID, objectName string, conditions *interfaces.UploadConditions, expiration time.Duration) (*interfaces.PresignedPost, error) {
+	if _, _, err := l.resolve(bucketID, objectName); err != nil {
+		return nil, err
+	}
+
+	return signPostPolicy(l.signer, l.publicURL, bucketID, objectName, conditions, expiration), nil
+}
+
+// 初始化分片上传
+func (l *LocalAdapter) InitiateMultipartUpload(ctx context.Context, bucketID, objectName, contentType string) (string, error) {
+	if _, _, err := l.resolve(bucketID, objectName); err != nil {
+		return "", err
+	}
+
+	uploadID := uuid.New().String()
+	dir := filepath.Join(l.rootDir, localMultipartDir, uploadID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create multipart directory: %w", err)
+	}
+
+	meta, _ := json.Marshal(&localMultipartMeta{
+		BucketID:    bucketID,
+		ObjectName:  objectName,
+		ContentType: contentType,
+	})
+	if err := os.WriteFile(filepath.Join(dir, "upload.json"), meta, 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to write multipart meta: %w", err)
+	}
+
+	return uploadID, nil
+}
+
+// 上传分片
+func (l *LocalAdapter) UploadPart(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (*interfaces.StoragePart, error) {
+	dir, _, err := l.multipartUpload(bucketID, objectName, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if partNumber < 1 || partNumber > MaxPartNumber {
+		return nil, fmt.Errorf("invalid part number %d", partNumber)
+	}
+
+	partPath := filepath.Join(dir, strconv.Itoa(partNumber))
+	if err = writeLocalObject(ctx, partPath, partPath+".json", reader, size, ""); err != nil {
+		return nil, err
+	}
+
+	return l.partInfo(partPath, partNumber)
+}
+
+// 生成分片的预签名上传URL，由FileEngine自身校验签名并接收上传
+func (l *LocalAdapter) GeneratePresignedPartURL(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, expiration time.Duration) (string, error) {
+	if _, _, err := l.multipartUpload(bucketID, objectName, uploadID); err != nil {
+		return "", err
+	}
+
+	query := l.signer.Sign(http.MethodPut, bucketID, objectName, time.Now().Add(expiration), partQuery(uploadID, partNumber))
+	return common.BuildObjectURL(l.publicURL, bucketID, objectName, query), nil
+}
+
+// 列出已上传的分片
+func (l *LocalAdapter) ListParts(ctx context.Context, bucketID, objectName, uploadID string) ([]*interfaces.StoragePart, error) {
+	dir, _, err := l.multipartUpload(bucketID, objectName, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+
+	var parts []*interfaces.StoragePart
+	for _, entry := range entries {
+		partNumber, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		part, err := l.partInfo(filepath.Join(dir, entry.Name()), partNumber)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+
+	return parts, nil
+}
+
+// 合并分片，按parts的顺序拼接分片数据写入目标对象
+func (l *LocalAdapter) CompleteMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string, parts []*interfaces.StoragePart) error {
+	dir, meta, err := l.multipartUpload(bucketID, objectName, uploadID)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		partPath := filepath.Join(dir, strconv.Itoa(part.PartNumber))
+		info, err := l.partInfo(partPath, part.PartNumber)
+		if err != nil {
+			return fmt.Errorf("part %d does not exist: %w", part.PartNumber, err)
+		}
+		if info.ETag != strings.Trim(part.ETag, "\"") {
+			return fmt.Errorf("etag of part %d does not match", part.PartNumber)
+		}
+
+		f, err := os.Open(partPath)
+		if err != nil {
+			return fmt.Errorf("failed to open part %d: %w", part.PartNumber, err)
+		}
+		defer f.Close()
+
+		readers = append(readers, f)
+		size += info.Size
+	}
+
+	if err = l.Upload(ctx, bucketID, objectName, io.MultiReader(readers...), size, meta.ContentType); err != nil {
+		return err
+	}
+
+	return l.AbortMultipartUpload(ctx, bucketID, objectName, uploadID)
+}
+
+// 取消分片上传
+func (l *LocalAdapter) AbortMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string) error {
+	dir, _, err := l.multipartUpload(bucketID, objectName, uploadID)
+	if err != nil {
+		return err
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// multipartUpload 返回分片上传的目录及元数据，并校验其所属的桶和对象
+func (l *LocalAdapter) multipartUpload(bucketID, objectName, uploadID string) (string, *localMultipartMeta, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", nil, fmt.Errorf("invalid upload id %s", uploadID)
+	}
+
+	dir := filepath.Join(l.rootDir, localMultipartDir, uploadID)
+	content, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if err != nil {
+		return "", nil, fmt.Errorf("multipart upload %s does not exist", uploadID)
+	}
+
+	meta := &localMultipartMeta{}
+	if err = json.Unmarshal(content, meta); err != nil {
+		return "", nil, fmt.Errorf("failed to decode multipart meta: %w", err)
+	}
+	if meta.BucketID != bucketID || meta.ObjectName != objectName {
+		return "", nil, fmt.Errorf("multipart upload %s does not belong to object %s", uploadID, objectName)
+	}
+
+	return dir, meta, nil
+}
+
+func (l *LocalAdapter) partInfo(partPath string, partNumber int) (*interfaces.StoragePart, error) {
+	stat, err := os.Stat(partPath)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &localObjectMeta{}
+	if content, err := os.ReadFile(partPath + ".json"); err == nil {
+		json.Unmarshal(content, meta)
+	}
+
+	return &interfaces.StoragePart{
+		PartNumber:   partNumber,
+		Size:         stat.Size(),
+		ETag:         meta.ETag,
+		LastModified: stat.ModTime().Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// writeLocalObject 写入数据文件及元数据文件。两者都先写入临时文件，数据文件重命名后再重命名元数据文件，
+// 避免读到写了一半的对象，也避免覆盖时新的元数据与旧的数据同时存在
+func writeLocalObject(ctx context.Context, dataPath, metaPath string, reader io.Reader, size int64, contentType string) error {
+	if err := os.MkdirAll(filepath.Dir(dataPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dataPath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), &contextReader{ctx: ctx, reader: reader})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("object size mismatch, expected %d, got %d", size, written)
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	meta, _ := json.Marshal(&localObjectMeta{
+		ContentType: contentType,
+		ETag:        hex.EncodeToString(hash.Sum(nil)),
+	})
+	if err = os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create meta directory: %w", err)
+	}
+	metaTmp, err := os.CreateTemp(filepath.Dir(metaPath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp meta file: %w", err)
+	}
+	defer os.Remove(metaTmp.Name())
+	_, err = metaTmp.Write(meta)
+	if closeErr := metaTmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// 与os.WriteFile创建的文件权限一致
+		err = os.Chmod(metaTmp.Name(), 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write object meta: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), dataPath); err != nil {
+		return fmt.Errorf("failed to move object into place: %w", err)
+	}
+	if err = os.Rename(metaTmp.Name(), metaPath); err != nil {
+		return fmt.Errorf("failed to move object meta into place: %w", err)
+	}
+
+	return nil
+}
+
+// pruneEmptyDirs 自下而上删除dir至stop(不含)之间的空目录，模拟对象存储中不存在空前缀的行为
+func pruneEmptyDirs(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// contextReader 在上下文取消后中断读取
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
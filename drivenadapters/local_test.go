@@ -0,0 +1,75 @@
+package drivenadapters
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteLocalObject(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dataPath := filepath.Join(dir, "data", "a", "object")
+	metaPath := filepath.Join(dir, "meta", "a", "object.json")
+
+	tests := []struct {
+		name        string
+		content     string
+		size        int64
+		contentType string
+		wantErr     bool
+		wantData    string
+		wantMeta    string
+	}{
+		{"create", "hello", 5, "text/plain", false, "hello", `"content_type":"text/plain"`},
+		{"overwrite", "<p>hi</p>", -1, "text/html", false, "<p>hi</p>", `"content_type":"text/html"`},
+		// 写入失败时保留原对象的数据和元数据
+		{"size mismatch", "truncated", 100, "image/png", true, "<p>hi</p>", `"content_type":"text/html"`},
+		{"default content type", "", 0, "", false, "", `"content_type":"application/octet-stream"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writeLocalObject(ctx, dataPath, metaPath, strings.NewReader(tt.content), tt.size, tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeLocalObject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if data, err := os.ReadFile(dataPath); err != nil || string(data) != tt.wantData {
+				t.Fatalf("data = %q, %v, want %q", data, err, tt.wantData)
+			}
+			if meta, err := os.ReadFile(metaPath); err != nil || !strings.Contains(string(meta), tt.wantMeta) {
+				t.Fatalf("meta = %s, %v, want %s", meta, err, tt.wantMeta)
+			}
+			// 临时文件均已清理
+			for _, d := range []string{filepath.Dir(dataPath), filepath.Dir(metaPath)} {
+				entries, err := os.ReadDir(d)
+				if err != nil || len(entries) != 1 {
+					t.Fatalf("%s has %d entries (%v), want 1", d, len(entries), err)
+				}
+			}
+		})
+	}
+}
+
+// 数据文件无法移动到目标位置时，元数据文件保持不变
+func TestWriteLocalObjectRenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	dataPath := filepath.Join(dir, "object")
+	metaPath := filepath.Join(dir, "object.json")
+	// 目标位置是非空目录，重命名失败
+	if err := os.MkdirAll(filepath.Join(dataPath, "child"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldMeta := `{"content_type":"text/plain","etag":"old"}`
+	if err := os.WriteFile(metaPath, []byte(oldMeta), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeLocalObject(context.Background(), dataPath, metaPath, strings.NewReader("<p>hi</p>"), -1, "text/html"); err == nil {
+		t.Fatal("writeLocalObject() error = nil, want rename failure")
+	}
+	if meta, err := os.ReadFile(metaPath); err != nil || string(meta) != oldMeta {
+		t.Fatalf("meta = %s, %v, want unchanged %s", meta, err, oldMeta)
+	}
+}
@@ -0,0 +1,337 @@
+package drivenadapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryAdapter 基于内存的对象存储，用于嵌入式运行和单元测试，预签名URL由FileEngine自身提供
+type MemoryAdapter struct {
+	mu        sync.RWMutex
+	buckets   map[string]map[string]*memoryObject
+	uploads   map[string]*memoryMultipartUpload
+	publicURL string
+	signer    *common.URLSigner
+}
+
+type memoryObject struct {
+	data         []byte
+	contentType  string
+	etag         string
+	lastModified time.Time
+}
+
+// 分片上传
+type memoryMultipartUpload struct {
+	bucketID    string
+	objectName  string
+	contentType string
+	parts       map[int]*memoryObject
+}
+
+func init() {
+	RegisterStorageDriver("memory", NewMemoryAdapter)
+}
+
+func NewMemoryAdapter(cfg *common.StorageConfig) (interfaces.StorageAdapter, error) {
+	return &MemoryAdapter{
+		buckets:   make(map[string]map[string]*memoryObject),
+		uploads:   make(map[string]*memoryMultipartUpload),
+		publicURL: config.Server.PublicURL,
+		signer:    common.NewURLSigner(config.Server.SignKey),
+	}, nil
+}
+
+func (m *MemoryAdapter) Upload(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(&contextReader{ctx: ctx, reader: reader})
+	if err != nil {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return fmt.Errorf("object size mismatch, expected %d, got %d", size, len(data))
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	sum := md5.Sum(data)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[bucketID]
+	if !ok {
+		bucket = make(map[string]*memoryObject)
+		m.buckets[bucketID] = bucket
+	}
+	bucket[objectName] = &memoryObject{
+		data:         data,
+		contentType:  contentType,
+		etag:         hex.EncodeToString(sum[:]),
+		lastModified: time.Now(),
+	}
+
+	return nil
+}
+
+func (m *MemoryAdapter) Download(ctx context.Context, bucketID, objectName string) (io.ReadCloser, error) {
+	object, err := m.get(bucketID, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	// 对象写入后不再修改，可直接共享底层数据
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+func (m *MemoryAdapter) DownloadRange(ctx context.Context, bucketID, objectName string, offset, length int64) (io.ReadCloser, error) {
+	object, err := m.get(bucketID, objectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	if offset < 0 || length < 0 || offset+length > int64(len(object.data)) {
+		return nil, fmt.Errorf("range %d-%d is out of object size %d", offset, offset+length-1, len(object.data))
+	}
+
+	return io.NopCloser(bytes.NewReader(object.data[offset : offset+length])), nil
+}
+
+func (m *MemoryAdapter) Delete(ctx context.Context, bucketID, objectName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if bucket, ok := m.buckets[bucketID]; ok {
+		delete(bucket, objectName)
+	}
+
+	return nil
+}
+
+func (m *MemoryAdapter) FileExists(ctx context.Context, bucketID, objectName string) (bool, error) {
+	_, err := m.get(bucketID, objectName)
+	return err == nil, nil
+}
+
+func (m *MemoryAdapter) GetFileInfo(ctx context.Context, bucketID, objectName string) (*interfaces.StorageFileInfo, error) {
+	object, err := m.get(bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &interfaces.StorageFileInfo{
+		Size:         int64(len(object.data)),
+		ContentType:  object.contentType,
+		LastModified: object.lastModified.Format("2006-01-02 15:04:05"),
+		ETag:         object.etag,
+	}, nil
+}
+
+// SignsURLs 内存存储的预签名URL均由FileEngine自身签发
+func (m *MemoryAdapter) SignsURLs() bool {
+	return true
+}
+
+// 生成预签名下载URL，由FileEngine自身校验签名并提供下载
+func (m *MemoryAdapter) GeneratePresignedDownloadURL(ctx context.Context, bucketID, objectName string, expiration time.Duration) (string, error) {
+	if _, err := m.get(bucketID, objectName); err != nil {
+		return "", err
+	}
+
+	query := m.signer.Sign(http.MethodGet, bucketID, objectName, time.Now().Add(expiration), nil)
+	return common.BuildObjectURL(m.publicURL, bucketID, objectName, query), nil
+}
+
+// 生成预签名上传URL，由FileEngine自身校验签名并接收上传
+func (m *MemoryAdapter) GeneratePresignedUploadURL(ctx context.Context, bucketID, objectName string, expiration time.Duration) (string, error) {
+	query := m.signer.Sign(http.MethodPut, bucketID, objectName, time.Now().Add(expiration), nil)
+	return common.BuildObjectURL(m.publicURL, bucketID, objectName, query), nil
+}
+
+// 生成预签名POST策略，由FileEngine自身校验策略并接收表单上传
+func (m *MemoryAdapter) GeneratePresignedPostPolicy(ctx context.Context, bucketID, objectName string, conditions *interfaces.UploadConditions, expiration time.Duration) (*interfaces.PresignedPost, error) {
+	return signPostPolicy(m.signer, m.publicURL, bucketID, objectName, conditions, expiration), nil
+}
+
+// 初始化分片上传
+func (m *MemoryAdapter) InitiateMultipartUpload(ctx context.Context, bucketID, objectName, contentType string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uploadID := uuid.New().String()
+	m.uploads[uploadID] = &memoryMultipartUpload{
+		bucketID:    bucketID,
+		objectName:  objectName,
+		contentType: contentType,
+		parts:       make(map[int]*memoryObject),
+	}
+
+	return uploadID, nil
+}
+
+// 上传分片
+func (m *MemoryAdapter) UploadPart(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (*interfaces.StoragePart, error) {
+	if partNumber < 1 || partNumber > MaxPartNumber {
+		return nil, fmt.Errorf("invalid part number %d", partNumber)
+	}
+
+	data, err := io.ReadAll(&contextReader{ctx: ctx, reader: reader})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read part: %w", err)
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return nil, fmt.Errorf("part size mismatch, expected %d, got %d", size, len(data))
+	}
+	sum := md5.Sum(data)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, err := m.getUpload(bucketID, objectName, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	part := &memoryObject{
+		data:         data,
+		etag:         hex.EncodeToString(sum[:]),
+		lastModified: time.Now(),
+	}
+	upload.parts[partNumber] = part
+
+	return memoryPartInfo(partNumber, part), nil
+}
+
+// 生成分片的预签名上传URL，由FileEngine自身校验签名并接收上传
+func (m *MemoryAdapter) GeneratePresignedPartURL(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, expiration time.Duration) (string, error) {
+	m.mu.RLock()
+	_, err := m.getUpload(bucketID, objectName, uploadID)
+	m.mu.RUnlock()
+	if err != nil {
+		return "", err
+	}
+
+	query := m.signer.Sign(http.MethodPut, bucketID, objectName, time.Now().Add(expiration), partQuery(uploadID, partNumber))
+	return common.BuildObjectURL(m.publicURL, bucketID, objectName, query), nil
+}
+
+// 列出已上传的分片
+func (m *MemoryAdapter) ListParts(ctx context.Context, bucketID, objectName, uploadID string) ([]*interfaces.StoragePart, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	upload, err := m.getUpload(bucketID, objectName, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]*interfaces.StoragePart, 0, len(upload.parts))
+	for partNumber, part := range upload.parts {
+		parts = append(parts, memoryPartInfo(partNumber, part))
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+
+	return parts, nil
+}
+
+// 合并分片
+func (m *MemoryAdapter) CompleteMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string, parts []*interfaces.StoragePart) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, err := m.getUpload(bucketID, objectName, uploadID)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	for _, part := range parts {
+		uploaded, ok := upload.parts[part.PartNumber]
+		if !ok {
+			return fmt.Errorf("part %d does not exist", part.PartNumber)
+		}
+		if uploaded.etag != strings.Trim(part.ETag, "\"") {
+			return fmt.Errorf("etag of part %d does not match", part.PartNumber)
+		}
+		data = append(data, uploaded.data...)
+	}
+
+	contentType := upload.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	sum := md5.Sum(data)
+
+	bucket, ok := m.buckets[bucketID]
+	if !ok {
+		bucket = make(map[string]*memoryObject)
+		m.buckets[bucketID] = bucket
+	}
+	bucket[objectName] = &memoryObject{
+		data:         data,
+		contentType:  contentType,
+		etag:         hex.EncodeToString(sum[:]),
+		lastModified: time.Now(),
+	}
+	delete(m.uploads, uploadID)
+
+	return nil
+}
+
+// 取消分片上传
+func (m *MemoryAdapter) AbortMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.getUpload(bucketID, objectName, uploadID); err != nil {
+		return err
+	}
+	delete(m.uploads, uploadID)
+
+	return nil
+}
+
+// getUpload 获取分片上传并校验其所属的桶和对象，调用方需持有锁
+func (m *MemoryAdapter) getUpload(bucketID, objectName, uploadID string) (*memoryMultipartUpload, error) {
+	upload, ok := m.uploads[uploadID]
+	if !ok || upload.bucketID != bucketID || upload.objectName != objectName {
+		return nil, fmt.Errorf("multipart upload %s does not exist", uploadID)
+	}
+
+	return upload, nil
+}
+
+func memoryPartInfo(partNumber int, part *memoryObject) *interfaces.StoragePart {
+	return &interfaces.StoragePart{
+		PartNumber:   partNumber,
+		Size:         int64(len(part.data)),
+		ETag:         part.etag,
+		LastModified: part.lastModified.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func (m *MemoryAdapter) get(bucketID, objectName string) (*memoryObject, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	object, ok := m.buckets[bucketID][objectName]
+	if !ok {
+		return nil, fmt.Errorf("object %s does not exist in bucket %s", objectName, bucketID)
+	}
+
+	return object, nil
+}
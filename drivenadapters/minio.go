@@ -1,26 +1,65 @@
 package drivenadapters
 
 import (
+	"FileEngine/common"
 	"FileEngine/interfaces"
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// 长度未知的流式上传默认参数，内存占用约为分片大小乘以并发数
+const (
+	defaultStreamPartSize = 16 * 1024 * 1024
+	defaultStreamThreads  = 4
 )
 
 type MinioAdapter struct {
-	client   *minio.Client
-	bucketID string
+	client         *minio.Client
+	core           *minio.Core
+	bucketID       string
+	streamPartSize uint64
+	streamThreads  uint
 }
 
-func NewMinioAdapter() interfaces.StorageAdapter {
-	return &MinioAdapter{
-		client:   minioClient,
-		bucketID: config.Minio.BucketID,
+func init() {
+	RegisterStorageDriver("minio", NewMinioAdapter)
+}
+
+func NewMinioAdapter(cfg *common.StorageConfig) (interfaces.StorageAdapter, error) {
+	options := &common.MinioConfig{}
+	if err := cfg.DecodeOptions(options); err != nil {
+		return nil, fmt.Errorf("failed to decode minio options: %w", err)
+	}
+
+	client, err := minio.New(options.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(options.AccessKey, options.SecretKey, ""),
+		Secure: options.Secure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize minio client: %w", err)
+	}
+
+	if options.StreamPartSize == 0 {
+		options.StreamPartSize = defaultStreamPartSize
 	}
+	if options.StreamThreads == 0 {
+		options.StreamThreads = defaultStreamThreads
+	}
+
+	return &MinioAdapter{
+		client:         client,
+		core:           &minio.Core{Client: client},
+		bucketID:       cfg.BucketID,
+		streamPartSize: options.StreamPartSize,
+		streamThreads:  options.StreamThreads,
+	}, nil
 }
 
 func (m *MinioAdapter) Upload(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error {
@@ -37,10 +76,16 @@ func (m *MinioAdapter) Upload(ctx context.Context, bucketID, objectName string,
 		}
 	}
 
-	// 上传文件
-	_, err = m.client.PutObject(ctx, bucketID, objectName, reader, size, minio.PutObjectOptions{
+	// 上传文件，长度未知时按固定分片大小并发上传分片
+	opts := minio.PutObjectOptions{
 		ContentType: contentType,
-	})
+	}
+	if size < 0 {
+		opts.PartSize = m.streamPartSize
+		opts.NumThreads = m.streamThreads
+		opts.ConcurrentStreamParts = true
+	}
+	_, err = m.client.PutObject(ctx, bucketID, objectName, reader, size, opts)
 
 	return err
 }
@@ -54,6 +99,20 @@ func (m *MinioAdapter) Download(ctx context.Context, bucketID, objectName string
 	return obj, nil
 }
 
+func (m *MinioAdapter) DownloadRange(ctx context.Context, bucketID, objectName string, offset, length int64) (io.ReadCloser, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+
+	obj, err := m.client.GetObject(ctx, bucketID, objectName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	return obj, nil
+}
+
 func (m *MinioAdapter) Delete(ctx context.Context, bucketID, objectName string) error {
 	return m.client.RemoveObject(ctx, bucketID, objectName, minio.RemoveObjectOptions{})
 }
@@ -137,3 +196,152 @@ func (m *MinioAdapter) GeneratePresignedUploadURL(ctx context.Context, bucketID,
 
 	return presignedURL.String(), nil
 }
+
+// 生成预签名POST策略
+func (m *MinioAdapter) GeneratePresignedPostPolicy(ctx context.Context, bucketID, objectName string, conditions *interfaces.UploadConditions, expiration time.Duration) (*interfaces.PresignedPost, error) {
+	// 检查bucket是否存在，不存在则创建
+	exists, err := m.client.BucketExists(ctx, bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+	}
+
+	if !exists {
+		err = m.client.MakeBucket(ctx, bucketID, minio.MakeBucketOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+	}
+
+	policy := minio.NewPostPolicy()
+	if err = policy.SetBucket(bucketID); err != nil {
+		return nil, err
+	}
+	if err = policy.SetKey(objectName); err != nil {
+		return nil, err
+	}
+	if err = policy.SetExpires(time.Now().UTC().Add(expiration)); err != nil {
+		return nil, err
+	}
+	if err = policy.SetContentType(conditions.ContentType); err != nil {
+		return nil, err
+	}
+	if err = policy.SetContentLengthRange(conditions.MinSize, conditions.MaxSize); err != nil {
+		return nil, err
+	}
+
+	presignedURL, formData, err := m.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate presigned post policy: %w", err)
+	}
+
+	return &interfaces.PresignedPost{
+		URL:      presignedURL.String(),
+		FormData: formData,
+	}, nil
+}
+
+// 初始化分片上传
+func (m *MinioAdapter) InitiateMultipartUpload(ctx context.Context, bucketID, objectName, contentType string) (string, error) {
+	// 确保bucket存在
+	exists, err := m.client.BucketExists(ctx, bucketID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check bucket existence: %w", err)
+	}
+
+	if !exists {
+		err = m.client.MakeBucket(ctx, bucketID, minio.MakeBucketOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to create bucket: %w", err)
+		}
+	}
+
+	uploadID, err := m.core.NewMultipartUpload(ctx, bucketID, objectName, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+
+	return uploadID, nil
+}
+
+// 上传分片
+func (m *MinioAdapter) UploadPart(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (*interfaces.StoragePart, error) {
+	part, err := m.core.PutObjectPart(ctx, bucketID, objectName, uploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload part: %w", err)
+	}
+
+	return &interfaces.StoragePart{
+		PartNumber:   part.PartNumber,
+		Size:         part.Size,
+		ETag:         strings.Trim(part.ETag, "\""),
+		LastModified: part.LastModified.Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// 生成分片的预签名上传URL
+func (m *MinioAdapter) GeneratePresignedPartURL(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, expiration time.Duration) (string, error) {
+	presignedURL, err := m.client.Presign(ctx, http.MethodPut, bucketID, objectName, expiration, partQuery(uploadID, partNumber))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned part URL: %w", err)
+	}
+
+	return presignedURL.String(), nil
+}
+
+// 列出已上传的分片
+func (m *MinioAdapter) ListParts(ctx context.Context, bucketID, objectName, uploadID string) ([]*interfaces.StoragePart, error) {
+	var parts []*interfaces.StoragePart
+
+	marker := 0
+	for {
+		result, err := m.core.ListObjectParts(ctx, bucketID, objectName, uploadID, marker, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list parts: %w", err)
+		}
+
+		for _, part := range result.ObjectParts {
+			parts = append(parts, &interfaces.StoragePart{
+				PartNumber:   part.PartNumber,
+				Size:         part.Size,
+				ETag:         strings.Trim(part.ETag, "\""),
+				LastModified: part.LastModified.Format("2006-01-02 15:04:05"),
+			})
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+
+	return parts, nil
+}
+
+// 合并分片
+func (m *MinioAdapter) CompleteMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string, parts []*interfaces.StoragePart) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		})
+	}
+
+	_, err := m.core.CompleteMultipartUpload(ctx, bucketID, objectName, uploadID, completeParts, minio.PutObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// 取消分片上传
+func (m *MinioAdapter) AbortMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string) error {
+	if err := m.core.AbortMultipartUpload(ctx, bucketID, objectName, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package drivenadapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// StorageFactory 根据存储配置构建存储适配器
+type StorageFactory func(cfg *common.StorageConfig) (interfaces.StorageAdapter, error)
+
+var (
+	storageDriversMu sync.RWMutex
+	storageDrivers   = make(map[string]StorageFactory)
+)
+
+// RegisterStorageDriver 注册存储驱动，第三方驱动可在init中调用。重复注册同名驱动会panic
+func RegisterStorageDriver(name string, factory StorageFactory) {
+	storageDriversMu.Lock()
+	defer storageDriversMu.Unlock()
+
+	if factory == nil {
+		panic("drivenadapters: RegisterStorageDriver factory is nil")
+	}
+	if _, dup := storageDrivers[name]; dup {
+		panic("drivenadapters: RegisterStorageDriver called twice for driver " + name)
+	}
+	storageDrivers[name] = factory
+}
+
+// StorageDrivers 返回已注册的存储驱动名称
+func StorageDrivers() []string {
+	storageDriversMu.RLock()
+	defer storageDriversMu.RUnlock()
+
+	names := make([]string, 0, len(storageDrivers))
+	for name := range storageDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStorageAdapter 根据配置中的驱动名称构建存储适配器
+func NewStorageAdapter(cfg *common.StorageConfig) (interfaces.StorageAdapter, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("storage config is missing")
+	}
+
+	storageDriversMu.RLock()
+	factory, ok := storageDrivers[cfg.Driver]
+	storageDriversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver %q (registered: %v)", cfg.Driver, StorageDrivers())
+	}
+
+	adapter, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage driver %s: %w", cfg.Driver, err)
+	}
+
+	return adapter, nil
+}
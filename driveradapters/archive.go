@@ -0,0 +1,67 @@
+package driveradapters
+
+import (
+	"FileEngine/interfaces"
+	"archive/zip"
+	"compress/flate"
+	"io"
+	"mime"
+	"strings"
+	"time"
+)
+
+// defaultArchiveName 未指定压缩包名称时使用的名称
+const defaultArchiveName = "files.zip"
+
+// archiveDisposition 压缩包的Content-Disposition，非ASCII名称按RFC 2231编码
+func archiveDisposition(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if strings.Trim(name, ".") == "" {
+		name = defaultArchiveName
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".zip") {
+		name += ".zip"
+	}
+	if disposition := mime.FormatMediaType("attachment", map[string]string{"filename": name}); disposition != "" {
+		return disposition
+	}
+	return "attachment; filename=" + defaultArchiveName
+}
+
+// writeArchive 以ZIP格式依次写入各文件，每个文件在写入时才从存储读取。
+// 边读边写时无法预先计算CRC，文件大小写在数据描述符中，部分解压工具不支持Store方式的数据描述符，
+// 因此使用Deflate的最快压缩级别；大于4GiB的文件或超过65535个文件时自动使用ZIP64。
+// 写入失败时不写入中央目录，客户端解压时能发现压缩包不完整
+func writeArchive(w io.Writer, entries []*interfaces.ArchiveEntry, open func(file *interfaces.FileInfo) (io.ReadCloser, error)) error {
+	zw := zip.NewWriter(w)
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestSpeed)
+	})
+	for _, entry := range entries {
+		header := &zip.FileHeader{
+			Name:   entry.Name,
+			Method: zip.Deflate,
+		}
+		if entry.File.UpdateTime != nil {
+			header.Modified = *entry.File.UpdateTime
+		} else {
+			header.Modified = time.Now()
+		}
+		header.SetMode(0644)
+
+		part, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		reader, err := open(entry.File)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,64 @@
+package driveradapters
+
+import (
+	"FileEngine/interfaces"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setCacheHeaders 设置ETag、Last-Modified和Cache-Control
+func setCacheHeaders(c *gin.Context, info *interfaces.CacheInfo) {
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+	if info.LastModified != nil {
+		c.Header("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if info.CacheControl != "" {
+		c.Header("Cache-Control", info.CacheControl)
+	}
+}
+
+// notModified 按If-None-Match和If-Modified-Since(RFC 9110)判断客户端缓存的版本是否仍为最新，
+// 存在If-None-Match时忽略If-Modified-Since
+func notModified(c *gin.Context, info *interfaces.CacheInfo) bool {
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		return etagListMatches(ifNoneMatch, info.ETag)
+	}
+
+	ifModifiedSince := c.GetHeader("If-Modified-Since")
+	if ifModifiedSince == "" || info.LastModified == nil {
+		return false
+	}
+	t, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !info.LastModified.Truncate(time.Second).After(t)
+}
+
+// etagListMatches If-None-Match中的任一实体标签与etag弱比较相同，*匹配任意存在的文件
+func etagListMatches(list, etag string) bool {
+	if textproto.TrimString(list) == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimPrefix(textproto.TrimString(item), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// replyNotModified 返回304，响应头中保留实体标签和缓存控制，不返回响应体
+func replyNotModified(c *gin.Context) {
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+}
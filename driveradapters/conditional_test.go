@@ -0,0 +1,35 @@
+package driveradapters
+
+import "testing"
+
+func TestEtagListMatches(t *testing.T) {
+	const etag = `"abc123"`
+	tests := []struct {
+		name string
+		list string
+		etag string
+		want bool
+	}{
+		{"exact", `"abc123"`, etag, true},
+		{"different", `"def456"`, etag, false},
+		{"in list", `"def456", "abc123"`, etag, true},
+		{"list without spaces", `"def456","abc123"`, etag, true},
+		{"weak in list", `W/"abc123"`, etag, true},
+		{"weak etag", `"abc123"`, `W/"abc123"`, true},
+		{"both weak", `W/"abc123"`, `W/"abc123"`, true},
+		{"wildcard", "*", etag, true},
+		{"wildcard with spaces", " * ", etag, true},
+		{"wildcard without etag", "*", "", true},
+		{"no etag", `"abc123"`, "", false},
+		{"empty list", "", etag, false},
+		{"unquoted", "abc123", etag, false},
+		{"prefix only", `"abc"`, etag, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagListMatches(tt.list, tt.etag); got != tt.want {
+				t.Fatalf("etagListMatches(%q, %q) = %v, want %v", tt.list, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
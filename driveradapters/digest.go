@@ -0,0 +1,77 @@
+package driveradapters
+
+import (
+	"FileEngine/common"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestSHA256 获取客户端期望的文件SHA-256(十六进制)，依次读取Repr-Digest(RFC 9530)、Digest(RFC 3230)请求头和十六进制的value，
+// 均未提供时返回空字符串表示不校验
+func requestSHA256(c *gin.Context, value string) (string, error) {
+	for _, header := range []string{"Repr-Digest", "Digest"} {
+		sum, ok, err := parseDigestHeader(c.GetHeader(header))
+		if err != nil {
+			return "", invalidDigestError(header, err)
+		}
+		if ok {
+			return sum, nil
+		}
+	}
+
+	if value == "" {
+		return "", nil
+	}
+	return parseSHA256Hex(value)
+}
+
+// parseSHA256Hex 校验十六进制的SHA-256并统一为小写
+func parseSHA256Hex(value string) (string, error) {
+	sum, err := hex.DecodeString(value)
+	if err != nil || len(sum) != sha256.Size {
+		return "", invalidDigestError("sha256", fmt.Errorf("%s is not a hex encoded sha-256", value))
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// parseDigestHeader 解析形如sha-256=:base64:或sha-256=base64的摘要列表，忽略其他算法
+func parseDigestHeader(header string) (string, bool, error) {
+	for _, item := range strings.Split(header, ",") {
+		algorithm, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || !strings.EqualFold(algorithm, "sha-256") {
+			continue
+		}
+
+		sum, err := base64.StdEncoding.DecodeString(strings.Trim(value, ":"))
+		if err != nil || len(sum) != sha256.Size {
+			return "", false, fmt.Errorf("%s is not a base64 encoded sha-256", value)
+		}
+		return hex.EncodeToString(sum), true, nil
+	}
+
+	return "", false, nil
+}
+
+func invalidDigestError(field string, err error) error {
+	return common.NewHTTPError(http.StatusBadRequest, "Invalid digest", []map[string]interface{}{
+		{"error": "Invalid digest", "message": fmt.Sprintf("%s: %s", field, err.Error())},
+	})
+}
+
+// setDigestHeaders 在响应中返回文件内容的摘要，未计算摘要的历史文件不返回
+func setDigestHeaders(c *gin.Context, sha256Hex string) {
+	sum, err := hex.DecodeString(sha256Hex)
+	if err != nil || len(sum) != sha256.Size {
+		return
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(sum)
+	c.Header("Repr-Digest", "sha-256=:"+encoded+":")
+	c.Header("Digest", "sha-256="+encoded)
+}
@@ -5,27 +5,42 @@ import (
 	"FileEngine/interfaces"
 	"FileEngine/logics"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// batchFormMemory 批量上传时表单在内存中保存的最大字节数，超出的文件暂存在临时文件中
+	batchFormMemory = 32 << 20
+	// archiveTimeout 打包下载的最长时间，压缩包可能包含大量文件
+	archiveTimeout = 2 * time.Hour
+)
+
 var (
 	fileHandlerOnce sync.Once
 	fileHandler     *FileHandler
 )
 
 type FileHandler struct {
-	logicsFile interfaces.LogicsFile
+	logicsFile        interfaces.LogicsFile
+	logicsIdempotency interfaces.LogicsIdempotency
 }
 
 func NewFileHandler() interfaces.RESTHandler {
 	fileHandlerOnce.Do(func() {
 		fileHandler = &FileHandler{
-			logicsFile: logics.NewLogicsFile(),
+			logicsFile:        logics.NewLogicsFile(),
+			logicsIdempotency: logics.NewLogicsIdempotency(),
 		}
 	})
 	return fileHandler
@@ -33,41 +48,90 @@ func NewFileHandler() interfaces.RESTHandler {
 
 func (handler *FileHandler) RegisterPublic(engine *gin.Engine) {
 	engine.Use(handler.authMiddleware())
-	engine.POST("/api/v1/file-engine/files", handler.uploadFile)
+	// 创建和删除文件的接口支持Idempotency-Key，客户端超时重试时不会重复执行
+	idempotent := idempotencyMiddleware(handler.logicsIdempotency)
+	engine.POST("/api/v1/file-engine/files", idempotent, handler.uploadFile)
+	engine.POST("/api/v1/file-engine/files/batch", idempotent, handler.batchUploadFiles)
 	engine.GET("/api/v1/file-engine/files/:fileID", handler.downloadFile)
+	engine.POST("/api/v1/file-engine/files/archive", handler.downloadArchive)
 
-	engine.POST("/api/v2/file-engine/files", handler.getUploadURL)
+	engine.POST("/api/v2/file-engine/files", idempotent, handler.getUploadURL)
+	engine.POST("/api/v2/file-engine/files/precheck", idempotent, handler.precheckUpload)
+	engine.POST("/api/v2/file-engine/files/:fileID/complete", idempotent, handler.completeUpload)
 	engine.GET("/api/v2/file-engine/files/:fileID", handler.getDownloadURL)
+	engine.GET("/api/v2/file-engine/upload-policy", handler.getUploadPolicy)
 
 	engine.GET("/api/v1/file-engine/files/:fileID/meta", handler.getFileMeta)
-	engine.DELETE("/api/v1/file-engine/files/:fileID", handler.deleteFile)
+	engine.DELETE("/api/v1/file-engine/files/:fileID", idempotent, handler.deleteFile)
 }
 
 func (handler *FileHandler) RegisterPrivate(engine *gin.Engine) {
 }
 
-// 文件上传
+// 文件上传，请求体以流的方式直接写入存储，不在本地暂存
 func (handler *FileHandler) uploadFile(c *gin.Context) {
-	// 获取上传的文件
-	file, err := c.FormFile("file")
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		err = common.NewHTTPError(http.StatusBadRequest, "Request must be multipart/form-data", nil)
+		common.ReplyError(c, err)
+		return
+	}
+
+	// 读取file之前的表单字段，sha256字段为期望的文件摘要，conflict字段为同名文件的处理方式
+	var file *multipart.Part
+	var digest, conflict string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			err = common.NewHTTPError(http.StatusBadRequest, "No file uploaded, please select a file to upload", nil)
+			common.ReplyError(c, err)
+			return
+		}
+		if err != nil {
+			err = common.NewHTTPError(http.StatusBadRequest, "Invalid multipart form", []map[string]interface{}{
+				{"error": "Invalid multipart form", "message": err.Error()},
+			})
+			common.ReplyError(c, err)
+			return
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			file = part
+			break
+		}
+		if part.FormName() == "sha256" {
+			value, _ := io.ReadAll(io.LimitReader(part, maxFormFieldSize))
+			digest = strings.TrimSpace(string(value))
+		}
+		if part.FormName() == "conflict" {
+			value, _ := io.ReadAll(io.LimitReader(part, maxFormFieldSize))
+			conflict = strings.TrimSpace(string(value))
+		}
+	}
+	defer file.Close()
+
+	sha256, err := requestSHA256(c, digest)
 	if err != nil {
-		err = common.NewHTTPError(http.StatusBadRequest, "No file uploaded, please select a file to upload", nil)
 		common.ReplyError(c, err)
 		return
 	}
 
-	// 设置超时上下文
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	// 设置超时上下文，超时时间包含客户端发送文件的时间
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 
 	// 调用业务逻辑上传文件
-	fileInfo, err := handler.logicsFile.Upload(ctx, file)
+	fileInfo, err := handler.logicsFile.UploadFromReader(ctx, file.FileName(), file.Header.Get("Content-Type"), -1, sha256, conflict, file)
 	if err != nil {
 		common.ReplyError(c, err)
 		return
 	}
 
-	data := map[string]interface{}{
+	common.ReplyOK(c, http.StatusOK, uploadedFileData(fileInfo))
+}
+
+// 上传接口返回的文件信息
+func uploadedFileData(fileInfo *interfaces.FileInfo) map[string]interface{} {
+	return map[string]interface{}{
 		"id":           fileInfo.ID,
 		"name":         fileInfo.Name,
 		"content_type": fileInfo.ContentType,
@@ -76,7 +140,99 @@ func (handler *FileHandler) uploadFile(c *gin.Context) {
 		"create_time":  fileInfo.CreateTime.Format("2006-01-02 15:04:05"),
 		"update_time":  fileInfo.UpdateTime.Format("2006-01-02 15:04:05"),
 	}
-	common.ReplyOK(c, http.StatusOK, data)
+}
+
+// 批量上传，表单中的多个file字段并发上传，conflict字段为同名文件的处理方式，atomic为true时任一文件失败则全部回滚。
+// 文件超过batchFormMemory的部分暂存在临时文件中，请求结束后删除
+func (handler *FileHandler) batchUploadFiles(c *gin.Context) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		err = common.NewHTTPError(http.StatusBadRequest, "Request must be multipart/form-data", nil)
+		common.ReplyError(c, err)
+		return
+	}
+	form, err := reader.ReadForm(batchFormMemory)
+	if err != nil {
+		err = common.NewHTTPError(http.StatusBadRequest, "Invalid multipart form", []map[string]interface{}{
+			{"error": "Invalid multipart form", "message": err.Error()},
+		})
+		common.ReplyError(c, err)
+		return
+	}
+	defer form.RemoveAll()
+
+	allOrNothing := false
+	if value := formValue(form, "atomic"); value != "" {
+		if allOrNothing, err = strconv.ParseBool(value); err != nil {
+			err = common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+				{"error": "Invalid request parameters", "message": fmt.Sprintf("atomic must be a boolean, got %s", value)},
+			})
+			common.ReplyError(c, err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	results, err := handler.logicsFile.BatchUpload(ctx, form.File["file"], formValue(form, "conflict"), allOrNothing)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	// 全部成功时返回200，否则返回207，由每个文件的status区分成功、失败和已回滚
+	statusCode := http.StatusOK
+	succeeded, failed := 0, 0
+	items := make([]map[string]interface{}, 0, len(results))
+	for i, result := range results {
+		item := map[string]interface{}{
+			"index": i,
+			"name":  result.Filename,
+		}
+		switch {
+		case result.Err != nil:
+			item["status"] = "failed"
+			item["error"] = toHTTPError(result.Err)
+			failed++
+		case result.RolledBack:
+			item["status"] = "rolled_back"
+		default:
+			item["status"] = "succeeded"
+			item["file"] = uploadedFileData(result.File)
+			succeeded++
+		}
+		if result.Err != nil || result.RolledBack {
+			statusCode = http.StatusMultiStatus
+		}
+		items = append(items, item)
+	}
+
+	common.ReplyOK(c, statusCode, map[string]interface{}{
+		"total":     len(results),
+		"succeeded": succeeded,
+		"failed":    failed,
+		"results":   items,
+	})
+}
+
+// formValue 返回表单字段的第一个值
+func formValue(form *multipart.Form, key string) string {
+	if values := form.Value[key]; len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// toHTTPError 将批量操作中单项的错误转换为与接口错误响应一致的格式
+func toHTTPError(err error) *common.HTTPError {
+	var httpErr *common.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	return common.NewHTTPError(http.StatusInternalServerError, "Internal Server Error", []map[string]interface{}{
+		{"error": "Internal Server Error", "message": err.Error()},
+	})
 }
 
 // 获取文件信息
@@ -98,18 +254,45 @@ func (handler *FileHandler) getFileMeta(c *gin.Context) {
 		return
 	}
 
+	cacheInfo, err := handler.logicsFile.GetMetaCacheInfo(ctx, fileInfo)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+	setCacheHeaders(c, cacheInfo)
+	if notModified(c, cacheInfo) {
+		replyNotModified(c)
+		return
+	}
+
 	data := map[string]interface{}{
 		"id":           fileInfo.ID,
 		"name":         fileInfo.Name,
 		"content_type": fileInfo.ContentType,
 		"size":         fileInfo.Size,
 		"icon":         fileInfo.Icon,
+		"status":       fileInfo.Status,
+		"sha256":       fileInfo.SHA256,
 		"create_time":  fileInfo.CreateTime.Format("2006-01-02 15:04:05"),
 		"update_time":  fileInfo.UpdateTime.Format("2006-01-02 15:04:05"),
 	}
 	common.ReplyOK(c, http.StatusOK, data)
 }
 
+// 获取上传策略，客户端可在上传前按策略校验文件
+func (handler *FileHandler) getUploadPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := handler.logicsFile.GetUploadPolicy(ctx)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	common.ReplyOK(c, http.StatusOK, policy)
+}
+
 // 文件下载
 func (handler *FileHandler) downloadFile(c *gin.Context) {
 	fileID := c.Param("fileID")
@@ -123,17 +306,142 @@ func (handler *FileHandler) downloadFile(c *gin.Context) {
 	defer cancel()
 
 	// 获取文件信息
-	fileDownloadInfo, err := handler.logicsFile.Download(ctx, fileID)
+	file, err := handler.logicsFile.GetDownloadFile(ctx, fileID)
 	if err != nil {
 		common.ReplyError(c, err)
 		return
 	}
-	defer fileDownloadInfo.Close()
 
+	// 条件请求在Range之前处理，客户端缓存的版本仍为最新时返回304
+	cacheInfo, err := handler.logicsFile.GetCacheInfo(ctx, file)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+	setCacheHeaders(c, cacheInfo)
+	if notModified(c, cacheInfo) {
+		replyNotModified(c)
+		return
+	}
+
+	// Range请求只返回指定的字节范围，If-Range与文件当前版本不一致时返回整个文件
+	ranges, err := requestRanges(c, file, cacheInfo)
+	if err != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", file.Size))
+		common.ReplyError(c, common.NewHTTPError(http.StatusRequestedRangeNotSatisfiable, "Range not satisfiable", []map[string]interface{}{
+			{"error": "Range not satisfiable", "message": err.Error()},
+		}))
+		return
+	}
+
+	setDigestHeaders(c, file.SHA256)
+	c.Header("Accept-Ranges", "bytes")
 	extraHeaders := map[string]string{
-		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", fileDownloadInfo.File.Name),
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", file.Name),
+	}
+
+	status, offset, length := http.StatusOK, int64(0), file.Size
+	switch len(ranges) {
+	case 0:
+	case 1:
+		status, offset, length = http.StatusPartialContent, ranges[0].start, ranges[0].length
+		extraHeaders["Content-Range"] = ranges[0].contentRange(file.Size)
+	default:
+		handler.downloadRanges(ctx, c, file, ranges, extraHeaders)
+		return
+	}
+
+	reader, err := handler.logicsFile.DownloadRange(ctx, file, offset, length)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(status, length, file.ContentType, reader, extraHeaders)
+}
+
+// 以multipart/byteranges返回多个范围，先打开第一个范围以便在写入响应前返回存储错误
+func (handler *FileHandler) downloadRanges(ctx context.Context, c *gin.Context, file *interfaces.FileInfo, ranges []httpRange, extraHeaders map[string]string) {
+	first, err := handler.logicsFile.DownloadRange(ctx, file, ranges[0].start, ranges[0].length)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	for key, value := range extraHeaders {
+		c.Header(key, value)
+	}
+	c.Header("Content-Type", "multipart/byteranges; boundary="+boundary)
+	c.Header("Content-Length", strconv.FormatInt(multipartRangesSize(ranges, boundary, file.ContentType, file.Size), 10))
+	c.Status(http.StatusPartialContent)
+
+	err = writeMultipartRanges(c.Writer, boundary, file.ContentType, file.Size, ranges, func(r httpRange) (io.ReadCloser, error) {
+		if first != nil {
+			reader := first
+			first = nil
+			return reader, nil
+		}
+		return handler.logicsFile.DownloadRange(ctx, file, r.start, r.length)
+	})
+	if first != nil {
+		first.Close()
+	}
+	if err != nil {
+		log.Printf("[WARN] failed to write ranges of file %s: %v", file.ID, err)
+	}
+}
+
+// 打包下载，请求体为JSON或表单，file_ids为文件ID列表，name为压缩包名称。
+// 压缩包边读取存储边写入响应，不在内存或磁盘中缓存，先打开第一个文件以便在写入响应前返回存储错误
+func (handler *FileHandler) downloadArchive(c *gin.Context) {
+	var request struct {
+		FileIDs []string `json:"file_ids" form:"file_ids" binding:"required"`
+		Name    string   `json:"name" form:"name"`
+	}
+
+	if err := c.ShouldBind(&request); err != nil {
+		err := common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+			{"error": "Invalid request parameters", "message": err.Error()},
+		})
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+	defer cancel()
+
+	entries, err := handler.logicsFile.GetArchiveEntries(ctx, request.FileIDs)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	first, err := handler.logicsFile.DownloadRange(ctx, entries[0].File, 0, entries[0].File.Size)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", archiveDisposition(request.Name))
+	c.Status(http.StatusOK)
+
+	err = writeArchive(c.Writer, entries, func(file *interfaces.FileInfo) (io.ReadCloser, error) {
+		if first != nil {
+			reader := first
+			first = nil
+			return reader, nil
+		}
+		return handler.logicsFile.DownloadRange(ctx, file, 0, file.Size)
+	})
+	if first != nil {
+		first.Close()
+	}
+	if err != nil {
+		log.Printf("[WARN] failed to write archive of %d files: %v", len(entries), err)
 	}
-	c.DataFromReader(http.StatusOK, fileDownloadInfo.File.Size, fileDownloadInfo.File.ContentType, fileDownloadInfo.Reader, extraHeaders)
 }
 
 // 删除文件
@@ -165,6 +473,8 @@ func (handler *FileHandler) getUploadURL(c *gin.Context) {
 		ContentType string `json:"content_type"`
 		Size        int64  `json:"size" binding:"required"`
 		Expires     int    `json:"expires"`
+		Method      string `json:"method"`   // PUT(默认)返回预签名URL，POST返回表单上传策略
+		Conflict    string `json:"conflict"` // 同名文件的处理方式：reject(默认)、overwrite、rename
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -179,7 +489,18 @@ func (handler *FileHandler) getUploadURL(c *gin.Context) {
 	defer cancel()
 
 	// 生成上传URL
-	uploadURL, err := handler.logicsFile.GenerateUploadURL(ctx, request.Filename, request.ContentType, request.Size)
+	var uploadURL *interfaces.UploadURL
+	var err error
+	switch strings.ToUpper(request.Method) {
+	case "", http.MethodPut:
+		uploadURL, err = handler.logicsFile.GenerateUploadURL(ctx, request.Filename, request.ContentType, request.Size, request.Conflict)
+	case http.MethodPost:
+		uploadURL, err = handler.logicsFile.GenerateUploadPolicy(ctx, request.Filename, request.ContentType, request.Size, request.Conflict)
+	default:
+		err = common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+			{"error": "Invalid request parameters", "message": fmt.Sprintf("unsupported upload method %s", request.Method)},
+		})
+	}
 	if err != nil {
 		common.ReplyError(c, err)
 		return
@@ -187,10 +508,121 @@ func (handler *FileHandler) getUploadURL(c *gin.Context) {
 
 	data := map[string]interface{}{
 		"id":         uploadURL.ID,
+		"name":       uploadURL.Name,
+		"method":     uploadURL.Method,
 		"url":        uploadURL.URL,
 		"expires_at": uploadURL.ExpiresAt.Format("2006-01-02 15:04:05"),
 		"expires_in": uploadURL.ExpiresIn,
 	}
+	if uploadURL.FormData != nil {
+		data["form_data"] = uploadURL.FormData
+	}
+	common.ReplyOK(c, http.StatusOK, data)
+}
+
+// 秒传预检查，exists为false时客户端继续通过v1或v2接口上传
+func (handler *FileHandler) precheckUpload(c *gin.Context) {
+	var request struct {
+		Filename string `json:"filename" binding:"required"`
+		SHA256   string `json:"sha256" binding:"required"`
+		Size     int64  `json:"size" binding:"required"`
+		Conflict string `json:"conflict"` // 同名文件的处理方式：reject(默认)、overwrite、rename
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		err := common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+			{"error": "Invalid request parameters", "message": err.Error()},
+		})
+		common.ReplyError(c, err)
+		return
+	}
+
+	sha256, err := parseSHA256Hex(request.SHA256)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fileInfo, err := handler.logicsFile.PrecheckUpload(ctx, request.Filename, sha256, request.Size, request.Conflict)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+	if fileInfo == nil {
+		common.ReplyOK(c, http.StatusOK, map[string]interface{}{"exists": false})
+		return
+	}
+
+	data := map[string]interface{}{
+		"exists":       true,
+		"id":           fileInfo.ID,
+		"name":         fileInfo.Name,
+		"content_type": fileInfo.ContentType,
+		"size":         fileInfo.Size,
+		"icon":         fileInfo.Icon,
+		"status":       fileInfo.Status,
+		"sha256":       fileInfo.SHA256,
+		"create_time":  fileInfo.CreateTime.Format("2006-01-02 15:04:05"),
+		"update_time":  fileInfo.UpdateTime.Format("2006-01-02 15:04:05"),
+	}
+	common.ReplyOK(c, http.StatusOK, data)
+}
+
+// 确认预签名上传完成
+func (handler *FileHandler) completeUpload(c *gin.Context) {
+	fileID := c.Param("fileID")
+	if fileID == "" {
+		err := common.NewHTTPError(http.StatusBadRequest, "File ID is required", nil)
+		common.ReplyError(c, err)
+		return
+	}
+
+	var request struct {
+		ETag   string `json:"etag"`
+		SHA256 string `json:"sha256"`
+	}
+
+	// 请求体可选，携带etag、sha256时与存储中的对象比对
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			err := common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+				{"error": "Invalid request parameters", "message": err.Error()},
+			})
+			common.ReplyError(c, err)
+			return
+		}
+	}
+
+	sha256, err := requestSHA256(c, request.SHA256)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	// 需读取整个对象计算摘要
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	fileInfo, err := handler.logicsFile.CompleteUpload(ctx, fileID, request.ETag, sha256)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"id":           fileInfo.ID,
+		"name":         fileInfo.Name,
+		"content_type": fileInfo.ContentType,
+		"size":         fileInfo.Size,
+		"icon":         fileInfo.Icon,
+		"status":       fileInfo.Status,
+		"sha256":       fileInfo.SHA256,
+		"create_time":  fileInfo.CreateTime.Format("2006-01-02 15:04:05"),
+		"update_time":  fileInfo.UpdateTime.Format("2006-01-02 15:04:05"),
+	}
 	common.ReplyOK(c, http.StatusOK, data)
 }
 
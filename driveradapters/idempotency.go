@@ -0,0 +1,289 @@
+package driveradapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxIdempotencyKeyLength Idempotency-Key的最大长度，与t_idempotency_key.idempotency_key一致
+	maxIdempotencyKeyLength = 255
+	// maxFingerprintBody 按内容计算指纹的请求体的最大字节数，更大的请求体在处理时计算摘要
+	maxFingerprintBody = 1 << 20
+	// maxUnreadBody 请求处理完成后仍未读取的请求体在计算摘要时最多读取的字节数，超出时不保存响应
+	maxUnreadBody = 1 << 20
+	// maxIdempotentResponse 保存的响应体的最大字节数，超出时不保存响应
+	maxIdempotentResponse = 1 << 20
+)
+
+// idempotencyMiddleware 处理Idempotency-Key请求头。首次请求正常处理并保存响应，
+// 有效期内相同幂等键的请求直接返回保存的响应并携带Idempotent-Replayed响应头；
+// 请求体未计入指纹时在处理过程中计算其摘要并随响应保存，重放前读取本次请求体比较，不一致时返回422；
+// 服务端错误、请求体未完整读取等情况不保存响应，客户端可使用相同幂等键重试
+func idempotencyMiddleware(logicsIdempotency interfaces.LogicsIdempotency) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if err := checkIdempotencyKey(key); err != nil {
+			common.ReplyError(c, common.NewHTTPError(http.StatusBadRequest, "Invalid idempotency key", []map[string]interface{}{
+				{"error": "Invalid idempotency key", "message": err.Error()},
+			}))
+			c.Abort()
+			return
+		}
+
+		fingerprint, streamed, err := requestFingerprint(c)
+		if err != nil {
+			common.ReplyError(c, common.NewHTTPError(http.StatusBadRequest, "Failed to read request body", []map[string]interface{}{
+				{"error": "Failed to read request body", "message": err.Error()},
+			}))
+			c.Abort()
+			return
+		}
+
+		token, replay, err := logicsIdempotency.Acquire(c.Request.Context(), key, fingerprint)
+		if err != nil {
+			common.ReplyError(c, err)
+			c.Abort()
+			return
+		}
+		if replay != nil {
+			if replay.BodyDigest != "" {
+				digest, err := bodyDigest(c.GetHeader("Content-Type"), c.Request.Body)
+				if err != nil {
+					common.ReplyError(c, common.NewHTTPError(http.StatusBadRequest, "Failed to read request body", []map[string]interface{}{
+						{"error": "Failed to read request body", "message": err.Error()},
+					}))
+					c.Abort()
+					return
+				}
+				if digest != replay.BodyDigest {
+					common.ReplyError(c, common.NewHTTPError(http.StatusUnprocessableEntity, "Idempotency key reused with a different request", []map[string]interface{}{
+						{"error": "Idempotency key reused with a different request", "message": "idempotency key " + key + " was used by a request with a different body"},
+					}))
+					c.Abort()
+					return
+				}
+			}
+			for name, values := range replay.Header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Writer.WriteHeader(replay.StatusCode)
+			c.Writer.Write(replay.Body)
+			c.Abort()
+			return
+		}
+
+		body := &idempotencyBody{ReadCloser: c.Request.Body}
+		if streamed {
+			body.hash = newBodyHash(c.GetHeader("Content-Type"))
+		}
+		c.Request.Body = body
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// 请求在处理过程中panic时释放幂等键，否则相同幂等键的请求需等待lockTimeout
+		completed := false
+		defer func() {
+			if !completed {
+				releaseIdempotencyKey(logicsIdempotency, c, key, token)
+			}
+		}()
+
+		c.Next()
+
+		completed = true
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests ||
+			body.err != nil || writer.overflow || c.Request.Context().Err() != nil {
+			releaseIdempotencyKey(logicsIdempotency, c, key, token)
+			return
+		}
+
+		header := writer.Header().Clone()
+		header.Del("Date")
+		response := &interfaces.IdempotentResponse{
+			StatusCode: status,
+			Header:     header,
+			Body:       writer.body.Bytes(),
+		}
+		if streamed {
+			// 请求可能在读完请求体前就已返回，摘要需覆盖完整的请求体
+			if !body.drain() {
+				releaseIdempotencyKey(logicsIdempotency, c, key, token)
+				return
+			}
+			response.BodyDigest = body.hash.Sum()
+		}
+		if err := logicsIdempotency.Complete(context.WithoutCancel(c.Request.Context()), key, token, response); err != nil {
+			log.Printf("[WARN] failed to save response for idempotency key %s: %v", key, err)
+			releaseIdempotencyKey(logicsIdempotency, c, key, token)
+		}
+	}
+}
+
+func releaseIdempotencyKey(logicsIdempotency interfaces.LogicsIdempotency, c *gin.Context, key, token string) {
+	if err := logicsIdempotency.Release(context.WithoutCancel(c.Request.Context()), key, token); err != nil {
+		log.Printf("[WARN] failed to release idempotency key %s: %v", key, err)
+	}
+}
+
+// checkIdempotencyKey 幂等键为1~255个可见ASCII字符，建议使用UUID
+func checkIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("Idempotency-Key exceeds %d bytes", maxIdempotencyKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return fmt.Errorf("Idempotency-Key must contain only visible ASCII characters")
+		}
+	}
+	return nil
+}
+
+// requestFingerprint 计算请求指纹，包含方法、路径、查询参数、内容类型、摘要请求头和请求体。
+// multipart请求和长度未知或较大的请求体以流的方式处理，指纹中以请求体长度代替内容，streamed为true，
+// 由调用方在处理过程中计算请求体的摘要
+func requestFingerprint(c *gin.Context) (fingerprint string, streamed bool, err error) {
+	h := sha256.New()
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, mediaType)
+	fmt.Fprintf(h, "%s\n%s\n", c.GetHeader("Repr-Digest"), c.GetHeader("Digest"))
+
+	length := c.Request.ContentLength
+	if strings.HasPrefix(mediaType, "multipart/") || length < 0 || length > maxFingerprintBody {
+		fmt.Fprintf(h, "length:%d", length)
+		return hex.EncodeToString(h.Sum(nil)), true, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxFingerprintBody))
+	if err != nil {
+		return "", false, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil)), false, nil
+}
+
+// bodyDigest 读取完整的请求体并计算摘要，重放的请求同样需要上传完整的请求体
+func bodyDigest(contentType string, body io.Reader) (string, error) {
+	h := newBodyHash(contentType)
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	return h.Sum(), nil
+}
+
+// bodyHash 计算请求体的SHA-256。multipart请求的边界每次请求可能不同，计算时去除请求体中的边界，
+// 仅边界不同的请求体摘要相同
+type bodyHash struct {
+	hash     hash.Hash
+	boundary []byte
+	pending  []byte
+}
+
+func newBodyHash(contentType string) *bodyHash {
+	b := &bodyHash{hash: sha256.New()}
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		b.boundary = []byte("--" + params["boundary"])
+	}
+	return b
+}
+
+func (b *bodyHash) Write(p []byte) (int, error) {
+	if len(b.boundary) == 0 {
+		return b.hash.Write(p)
+	}
+	b.pending = append(b.pending, p...)
+	for {
+		if i := bytes.Index(b.pending, b.boundary); i >= 0 {
+			b.hash.Write(b.pending[:i])
+			b.pending = b.pending[i+len(b.boundary):]
+			continue
+		}
+		// 保留可能是边界前缀的末尾数据，与后续数据一起匹配
+		if keep := len(b.boundary) - 1; len(b.pending) > keep {
+			b.hash.Write(b.pending[:len(b.pending)-keep])
+			b.pending = append(b.pending[:0], b.pending[len(b.pending)-keep:]...)
+		}
+		return len(p), nil
+	}
+}
+
+// Sum 返回十六进制编码的摘要
+func (b *bodyHash) Sum() string {
+	b.hash.Write(b.pending)
+	b.pending = nil
+	return hex.EncodeToString(b.hash.Sum(nil))
+}
+
+// idempotencyBody 记录读取请求体时的错误，请求体被截断时不保存响应；hash不为nil时计算读取的请求体的摘要
+type idempotencyBody struct {
+	io.ReadCloser
+	hash *bodyHash
+	err  error
+}
+
+func (b *idempotencyBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.hash != nil {
+		b.hash.Write(p[:n])
+	}
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// drain 读取处理请求时未读取的请求体，最多读取maxUnreadBody字节，读取失败或剩余数据更多时返回false
+func (b *idempotencyBody) drain() bool {
+	n, err := io.Copy(io.Discard, io.LimitReader(b, maxUnreadBody+1))
+	return err == nil && b.err == nil && n <= maxUnreadBody
+}
+
+// idempotencyWriter 在写入响应的同时保存响应体
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+	w.save(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.save([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idempotencyWriter) save(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > maxIdempotentResponse {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
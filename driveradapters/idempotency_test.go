@@ -0,0 +1,162 @@
+package driveradapters
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func fingerprintContext(method, target, contentType string, body io.Reader, length int64) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(method, target, body)
+	c.Request.ContentLength = length
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	return c
+}
+
+func multipartUpload(t *testing.T, content string) (string, []byte) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	fw, err := w.CreateFormFile("file", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(content))
+	w.Close()
+	return w.FormDataContentType(), buf.Bytes()
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	type request struct {
+		method, target, contentType, body string
+		length                            int64 // 为0时使用body的长度
+	}
+	base := request{http.MethodPost, "/api/v1/file-engine/files?a=1", "application/json", `{"name":"a.txt"}`, 0}
+	tests := []struct {
+		name         string
+		a, b         request
+		same         bool
+		wantStreamed bool
+	}{
+		{"identical", base, base, true, false},
+		{"content type parameters ignored", base, request{base.method, base.target, "application/json; charset=utf-8", base.body, 0}, true, false},
+		{"different body", base, request{base.method, base.target, base.contentType, `{"name":"b.txt"}`, 0}, false, false},
+		{"different method", base, request{http.MethodPut, base.target, base.contentType, base.body, 0}, false, false},
+		{"different path", base, request{base.method, "/api/v1/file-engine/files/x?a=1", base.contentType, base.body, 0}, false, false},
+		{"different query", base, request{base.method, "/api/v1/file-engine/files?a=2", base.contentType, base.body, 0}, false, false},
+		{"different content type", base, request{base.method, base.target, "text/plain", base.body, 0}, false, false},
+		{"unknown length uses length only", request{base.method, base.target, "application/octet-stream", "aaaa", -1},
+			request{base.method, base.target, "application/octet-stream", "bbbb", -1}, true, true},
+		{"large body uses length only", request{base.method, base.target, "application/octet-stream", "aaaa", maxFingerprintBody + 1},
+			request{base.method, base.target, "application/octet-stream", "bbbb", maxFingerprintBody + 1}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprint := func(r request) (string, bool) {
+				length := r.length
+				if length == 0 {
+					length = int64(len(r.body))
+				}
+				c := fingerprintContext(r.method, r.target, r.contentType, strings.NewReader(r.body), length)
+				fp, streamed, err := requestFingerprint(c)
+				if err != nil {
+					t.Fatalf("requestFingerprint() error = %v", err)
+				}
+				if !streamed {
+					// 计算指纹后请求体仍可被处理函数读取
+					if body, _ := io.ReadAll(c.Request.Body); string(body) != r.body {
+						t.Fatalf("request body after fingerprint = %q, want %q", body, r.body)
+					}
+				}
+				return fp, streamed
+			}
+			a, streamed := fingerprint(tt.a)
+			b, _ := fingerprint(tt.b)
+			if (a == b) != tt.same {
+				t.Fatalf("fingerprints equal = %v, want %v", a == b, tt.same)
+			}
+			if streamed != tt.wantStreamed {
+				t.Fatalf("streamed = %v, want %v", streamed, tt.wantStreamed)
+			}
+		})
+	}
+}
+
+// multipart请求的边界不同而内容长度相同，指纹相同，由请求体摘要区分内容
+func TestRequestFingerprintMultipart(t *testing.T) {
+	ct1, body1 := multipartUpload(t, "hello a")
+	ct2, body2 := multipartUpload(t, "hello a")
+	ct3, body3 := multipartUpload(t, "hello b")
+	fingerprint := func(ct string, body []byte) string {
+		fp, streamed, err := requestFingerprint(fingerprintContext(http.MethodPost, "/files", ct, bytes.NewReader(body), int64(len(body))))
+		if err != nil || !streamed {
+			t.Fatalf("requestFingerprint() streamed = %v, error = %v", streamed, err)
+		}
+		return fp
+	}
+	if fingerprint(ct1, body1) != fingerprint(ct2, body2) || fingerprint(ct1, body1) != fingerprint(ct3, body3) {
+		t.Fatal("multipart fingerprints differ for bodies of the same length")
+	}
+
+	digest := func(ct string, body []byte) string {
+		d, err := bodyDigest(ct, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	if digest(ct1, body1) != digest(ct2, body2) {
+		t.Fatal("body digests differ for the same content with different boundaries")
+	}
+	if digest(ct1, body1) == digest(ct3, body3) {
+		t.Fatal("body digests equal for different content")
+	}
+}
+
+func TestBodyHash(t *testing.T) {
+	const boundary = "xyz"
+	contentType := "multipart/form-data; boundary=" + boundary
+	tests := []struct {
+		name        string
+		contentType string
+		a, b        string
+		same        bool
+	}{
+		{"plain identical", "application/octet-stream", "abc", "abc", true},
+		{"plain different", "application/octet-stream", "abc", "abd", false},
+		{"plain keeps boundary-like text", "application/octet-stream", "a--xyzb", "ab", false},
+		{"multipart boundary removed", contentType, "--xyz\r\ndata\r\n--xyz--", "\r\ndata\r\n--", true},
+		{"multipart different data", contentType, "--xyz\r\ndata\r\n--xyz--", "--xyz\r\ndatb\r\n--xyz--", false},
+		{"multipart partial boundary kept", contentType, "--xy", "", false},
+		{"invalid content type", "multipart/form-data; boundary", "--xyz", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := bodyDigest(tt.contentType, strings.NewReader(tt.a))
+			b, _ := bodyDigest(tt.contentType, strings.NewReader(tt.b))
+			if (a == b) != tt.same {
+				t.Fatalf("digests equal = %v, want %v", a == b, tt.same)
+			}
+		})
+	}
+
+	// 边界跨越多次写入时同样去除
+	whole := newBodyHash(contentType)
+	whole.Write([]byte("--xyz\r\ndata\r\n--xyz--"))
+	split := newBodyHash(contentType)
+	for _, b := range []byte("--xyz\r\ndata\r\n--xyz--") {
+		split.Write([]byte{b})
+	}
+	if whole.Sum() != split.Sum() {
+		t.Fatal("digest depends on how the body is split across writes")
+	}
+}
@@ -0,0 +1,117 @@
+package driveradapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"FileEngine/logics"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	importPath    = "/api/v2/file-engine/files:import"
+	importJobPath = "/api/v2/file-engine/imports"
+)
+
+var (
+	importHandlerOnce sync.Once
+	importHandler     *ImportHandler
+)
+
+// ImportHandler 从URL导入文件，导入在后台执行，客户端轮询任务状态
+type ImportHandler struct {
+	logicsImport      interfaces.LogicsImport
+	logicsIdempotency interfaces.LogicsIdempotency
+}
+
+func NewImportHandler() interfaces.RESTHandler {
+	importHandlerOnce.Do(func() {
+		importHandler = &ImportHandler{
+			logicsImport:      logics.NewLogicsImport(),
+			logicsIdempotency: logics.NewLogicsIdempotency(),
+		}
+	})
+	return importHandler
+}
+
+func (handler *ImportHandler) RegisterPublic(engine *gin.Engine) {
+	engine.POST(importPath, idempotencyMiddleware(handler.logicsIdempotency), handler.createImport)
+	engine.GET(importJobPath+"/:jobID", handler.getImport)
+}
+
+func (handler *ImportHandler) RegisterPrivate(engine *gin.Engine) {
+}
+
+// 创建URL导入任务，返回202及任务状态，Location为任务的查询地址
+func (handler *ImportHandler) createImport(c *gin.Context) {
+	// gin(v1.10)将路径中的":import"解析为参数，files后为其他内容的路径同样会匹配到这里
+	if c.Param("import") != ":import" {
+		common.ReplyError(c, common.NotFound)
+		return
+	}
+
+	var request struct {
+		URL      string `json:"url" binding:"required"`
+		Filename string `json:"filename"` // 为空时从响应的Content-Disposition或URL路径获取
+		SHA256   string `json:"sha256"`
+		Conflict string `json:"conflict"` // 同名文件的处理方式：reject(默认)、overwrite、rename
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		err := common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+			{"error": "Invalid request parameters", "message": err.Error()},
+		})
+		common.ReplyError(c, err)
+		return
+	}
+
+	sha256, err := requestSHA256(c, request.SHA256)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, err := handler.logicsImport.CreateImport(ctx, request.URL, request.Filename, sha256, request.Conflict)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	c.Header("Location", importJobPath+"/"+job.ID)
+	common.ReplyOK(c, http.StatusAccepted, importJobData(job))
+}
+
+// 查询URL导入任务
+func (handler *ImportHandler) getImport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, err := handler.logicsImport.GetImport(ctx, c.Param("jobID"))
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	common.ReplyOK(c, http.StatusOK, importJobData(job))
+}
+
+func importJobData(job *interfaces.ImportJob) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          job.ID,
+		"url":         job.SourceURL,
+		"filename":    job.Filename,
+		"conflict":    job.Conflict,
+		"status":      job.Status,
+		"file_id":     job.FileID,
+		"error":       job.ErrorMessage,
+		"create_time": job.CreateTime.Format("2006-01-02 15:04:05"),
+		"update_time": job.UpdateTime.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,58 @@
+package driveradapters
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubLogicsImport struct {
+	created []string
+}
+
+func (l *stubLogicsImport) CreateImport(ctx context.Context, sourceURL, filename, sha256, conflict string) (*interfaces.ImportJob, error) {
+	l.created = append(l.created, sourceURL)
+	now := time.Now()
+	return &interfaces.ImportJob{ID: "job", SourceURL: sourceURL, Status: interfaces.ImportStatusPending, CreateTime: &now, UpdateTime: &now}, nil
+}
+
+func (l *stubLogicsImport) GetImport(ctx context.Context, jobID string) (*interfaces.ImportJob, error) {
+	return nil, nil
+}
+
+func TestImportRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/api/v2/file-engine/files:import", http.StatusAccepted},
+		{"/api/v2/file-engine/files:export", http.StatusNotFound},
+		{"/api/v2/file-engine/files", http.StatusNotFound},
+		{"/api/v2/file-engine/filesimport", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			logicsImport := &stubLogicsImport{}
+			engine := gin.New()
+			(&ImportHandler{logicsImport: logicsImport}).RegisterPublic(engine)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{"url":"https://example.com/a.txt"}`))
+			req.Header.Set("Content-Type", "application/json")
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("POST %s = %d %s, want %d", tt.path, w.Code, w.Body, tt.wantStatus)
+			}
+			if created := len(logicsImport.created) == 1; created != (tt.wantStatus == http.StatusAccepted) {
+				t.Fatalf("import created = %v for %s", created, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package driveradapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"FileEngine/logics"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	multipartHandlerOnce sync.Once
+	multipartHandler     *MultipartHandler
+)
+
+// MultipartHandler 分片上传会话，分片通过预签名URL直传存储，可并行上传
+type MultipartHandler struct {
+	logicsMultipart interfaces.LogicsMultipart
+}
+
+func NewMultipartHandler() interfaces.RESTHandler {
+	multipartHandlerOnce.Do(func() {
+		multipartHandler = &MultipartHandler{
+			logicsMultipart: logics.NewLogicsMultipart(),
+		}
+	})
+	return multipartHandler
+}
+
+func (handler *MultipartHandler) RegisterPublic(engine *gin.Engine) {
+	engine.POST("/api/v2/file-engine/multipart-uploads", handler.initiateUpload)
+	engine.GET("/api/v2/file-engine/multipart-uploads/:uploadID", handler.listParts)
+	engine.POST("/api/v2/file-engine/multipart-uploads/:uploadID/part-urls", handler.getPartURLs)
+	engine.POST("/api/v2/file-engine/multipart-uploads/:uploadID/complete", handler.completeUpload)
+	engine.DELETE("/api/v2/file-engine/multipart-uploads/:uploadID", handler.abortUpload)
+}
+
+func (handler *MultipartHandler) RegisterPrivate(engine *gin.Engine) {
+}
+
+// 初始化分片上传
+func (handler *MultipartHandler) initiateUpload(c *gin.Context) {
+	var request struct {
+		Filename    string `json:"filename" binding:"required"`
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size" binding:"required"`
+		PartSize    int64  `json:"part_size"`
+		Conflict    string `json:"conflict"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		err := common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+			{"error": "Invalid request parameters", "message": err.Error()},
+		})
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	upload, err := handler.logicsMultipart.InitiateUpload(ctx, request.Filename, request.ContentType, request.Size, request.PartSize, request.Conflict)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	common.ReplyOK(c, http.StatusOK, multipartUploadData(upload))
+}
+
+// 获取分片的预签名上传URL
+func (handler *MultipartHandler) getPartURLs(c *gin.Context) {
+	var request struct {
+		PartNumbers []int `json:"part_numbers"`
+	}
+
+	// 请求体为空时生成全部分片的URL
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			err := common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+				{"error": "Invalid request parameters", "message": err.Error()},
+			})
+			common.ReplyError(c, err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	partURLs, err := handler.logicsMultipart.GeneratePartURLs(ctx, c.Param("uploadID"), request.PartNumbers)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	parts := make([]map[string]interface{}, 0, len(partURLs))
+	for _, partURL := range partURLs {
+		parts = append(parts, map[string]interface{}{
+			"part_number": partURL.PartNumber,
+			"size":        partURL.Size,
+			"url":         partURL.URL,
+			"expires_at":  partURL.ExpiresAt.Format("2006-01-02 15:04:05"),
+			"expires_in":  partURL.ExpiresIn,
+		})
+	}
+	common.ReplyOK(c, http.StatusOK, map[string]interface{}{"parts": parts})
+}
+
+// 获取分片上传会话及已上传的分片
+func (handler *MultipartHandler) listParts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	upload, uploadedParts, err := handler.logicsMultipart.ListParts(ctx, c.Param("uploadID"))
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	parts := make([]map[string]interface{}, 0, len(uploadedParts))
+	for _, part := range uploadedParts {
+		parts = append(parts, map[string]interface{}{
+			"part_number":   part.PartNumber,
+			"size":          part.Size,
+			"etag":          part.ETag,
+			"last_modified": part.LastModified,
+		})
+	}
+
+	data := multipartUploadData(upload)
+	data["parts"] = parts
+	common.ReplyOK(c, http.StatusOK, data)
+}
+
+// 完成分片上传
+func (handler *MultipartHandler) completeUpload(c *gin.Context) {
+	var request struct {
+		Parts []struct {
+			PartNumber int    `json:"part_number" binding:"required"`
+			ETag       string `json:"etag"`
+		} `json:"parts"`
+		SHA256 string `json:"sha256"`
+	}
+
+	// 请求体为空时使用存储中已上传的全部分片
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			err := common.NewHTTPError(http.StatusBadRequest, "Invalid request parameters", []map[string]interface{}{
+				{"error": "Invalid request parameters", "message": err.Error()},
+			})
+			common.ReplyError(c, err)
+			return
+		}
+	}
+
+	sha256, err := requestSHA256(c, request.SHA256)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	parts := make([]*interfaces.StoragePart, 0, len(request.Parts))
+	for _, part := range request.Parts {
+		parts = append(parts, &interfaces.StoragePart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		})
+	}
+
+	// 存储侧合并大文件及计算摘要耗时较长
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	fileInfo, err := handler.logicsMultipart.CompleteUpload(ctx, c.Param("uploadID"), parts, sha256)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"id":           fileInfo.ID,
+		"name":         fileInfo.Name,
+		"content_type": fileInfo.ContentType,
+		"size":         fileInfo.Size,
+		"icon":         fileInfo.Icon,
+		"sha256":       fileInfo.SHA256,
+		"create_time":  fileInfo.CreateTime.Format("2006-01-02 15:04:05"),
+		"update_time":  fileInfo.UpdateTime.Format("2006-01-02 15:04:05"),
+	}
+	common.ReplyOK(c, http.StatusOK, data)
+}
+
+// 取消分片上传
+func (handler *MultipartHandler) abortUpload(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := handler.logicsMultipart.AbortUpload(ctx, c.Param("uploadID")); err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	common.ReplyOK(c, http.StatusOK, nil)
+}
+
+func multipartUploadData(upload *interfaces.MultipartUpload) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           upload.ID,
+		"filename":     upload.Filename,
+		"content_type": upload.ContentType,
+		"size":         upload.Size,
+		"part_size":    upload.PartSize,
+		"part_count":   upload.PartCount(),
+		"conflict":     upload.Conflict,
+		"file_id":      upload.FileID,
+		"create_time":  upload.CreateTime.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,169 @@
+package driveradapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"FileEngine/logics"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 表单上传中除文件外单个字段的最大长度
+const maxFormFieldSize = 64 * 1024
+
+var (
+	objectHandlerOnce sync.Once
+	objectHandler     *ObjectHandler
+)
+
+// ObjectHandler 提供FileEngine自身签发的预签名URL的读写入口
+type ObjectHandler struct {
+	logicsObject interfaces.LogicsObject
+}
+
+func NewObjectHandler() interfaces.RESTHandler {
+	objectHandlerOnce.Do(func() {
+		objectHandler = &ObjectHandler{
+			logicsObject: logics.NewLogicsObject(),
+		}
+	})
+	return objectHandler
+}
+
+func (handler *ObjectHandler) RegisterPublic(engine *gin.Engine) {
+	engine.POST(common.ObjectURLPath+"/:bucketID", handler.postObject)
+	engine.PUT(common.ObjectURLPath+"/:bucketID/*objectName", handler.putObject)
+	engine.GET(common.ObjectURLPath+"/:bucketID/*objectName", handler.getObject)
+}
+
+func (handler *ObjectHandler) RegisterPrivate(engine *gin.Engine) {
+}
+
+// 通过预签名URL上传对象
+func (handler *ObjectHandler) putObject(c *gin.Context) {
+	bucketID := c.Param("bucketID")
+	objectName := strings.TrimPrefix(c.Param("objectName"), "/")
+
+	if err := handler.logicsObject.VerifySignedURL(http.MethodPut, bucketID, objectName, c.Request.URL.Query()); err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	// 分片上传的分片，uploadId和partNumber已包含在签名中
+	if uploadID := c.Query("uploadId"); uploadID != "" {
+		partNumber, err := strconv.Atoi(c.Query("partNumber"))
+		if err != nil {
+			err = common.NewHTTPError(http.StatusBadRequest, "Invalid part number", nil)
+			common.ReplyError(c, err)
+			return
+		}
+
+		part, err := handler.logicsObject.PutPart(ctx, bucketID, objectName, uploadID, partNumber, c.Request.Body, c.Request.ContentLength)
+		if err != nil {
+			common.ReplyError(c, err)
+			return
+		}
+
+		c.Header("ETag", "\""+part.ETag+"\"")
+		c.Status(http.StatusOK)
+		return
+	}
+
+	info, err := handler.logicsObject.PutObject(ctx, bucketID, objectName, c.Request.Body, c.Request.ContentLength, c.GetHeader("Content-Type"))
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	c.Header("ETag", "\""+info.ETag+"\"")
+	c.Status(http.StatusOK)
+}
+
+// 通过预签名POST策略以表单上传对象，文件字段file之后的字段被忽略
+func (handler *ObjectHandler) postObject(c *gin.Context) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		err = common.NewHTTPError(http.StatusBadRequest, "Request must be multipart/form-data", nil)
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	fields := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			err = common.NewHTTPError(http.StatusBadRequest, "File field is required", nil)
+			common.ReplyError(c, err)
+			return
+		}
+		if err != nil {
+			err = common.NewHTTPError(http.StatusBadRequest, "Invalid multipart form", []map[string]interface{}{
+				{"error": "Invalid multipart form", "message": err.Error()},
+			})
+			common.ReplyError(c, err)
+			return
+		}
+
+		if part.FormName() == "file" {
+			info, err := handler.logicsObject.PostObject(ctx, c.Param("bucketID"), fields, part)
+			if err != nil {
+				common.ReplyError(c, err)
+				return
+			}
+
+			c.Header("ETag", "\""+info.ETag+"\"")
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+		if err != nil || len(value) > maxFormFieldSize {
+			err = common.NewHTTPError(http.StatusBadRequest, "Invalid form field", []map[string]interface{}{
+				{"error": "Invalid form field", "message": fmt.Sprintf("form field %s is invalid or too large", part.FormName())},
+			})
+			common.ReplyError(c, err)
+			return
+		}
+		fields[part.FormName()] = string(value)
+	}
+}
+
+// 通过预签名URL下载对象
+func (handler *ObjectHandler) getObject(c *gin.Context) {
+	bucketID := c.Param("bucketID")
+	objectName := strings.TrimPrefix(c.Param("objectName"), "/")
+
+	if err := handler.logicsObject.VerifySignedURL(http.MethodGet, bucketID, objectName, c.Request.URL.Query()); err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	objectDownload, err := handler.logicsObject.GetObject(ctx, bucketID, objectName)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+	defer objectDownload.Close()
+
+	extraHeaders := map[string]string{
+		"ETag": "\"" + objectDownload.Info.ETag + "\"",
+	}
+	c.DataFromReader(http.StatusOK, objectDownload.Info.Size, objectDownload.Info.ContentType, objectDownload.Reader, extraHeaders)
+}
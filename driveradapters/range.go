@@ -0,0 +1,193 @@
+package driveradapters
+
+import (
+	"FileEngine/interfaces"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRanges 单次请求的最大范围数，超出时忽略Range返回整个文件
+const maxRanges = 100
+
+var (
+	errInvalidRange = errors.New("invalid range")
+	// errNoOverlap 所有范围的起始位置均超出文件大小
+	errNoOverlap = errors.New("invalid range: failed to overlap")
+)
+
+// httpRange 请求的字节范围
+type httpRange struct {
+	start, length int64
+}
+
+func (r httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+func (r httpRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {r.contentRange(size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+// parseRange 解析Range请求头(RFC 9110)，返回按请求顺序排列的范围，超出文件大小的结束位置截断到文件末尾。
+// 不是bytes单位时返回nil表示忽略Range，所有范围均无法满足时返回errNoOverlap
+func parseRange(header string, size int64) ([]httpRange, error) {
+	unit, specs, found := strings.Cut(header, "=")
+	if !found || strings.TrimSpace(unit) != "bytes" {
+		return nil, nil
+	}
+
+	var ranges []httpRange
+	noOverlap := false
+	for _, spec := range strings.Split(specs, ",") {
+		spec = textproto.TrimString(spec)
+		if spec == "" {
+			continue
+		}
+		first, last, found := strings.Cut(spec, "-")
+		if !found {
+			return nil, errInvalidRange
+		}
+		first, last = textproto.TrimString(first), textproto.TrimString(last)
+
+		var r httpRange
+		if first == "" {
+			// -N表示最后N个字节
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 || strings.HasPrefix(last, "-") {
+				return nil, errInvalidRange
+			}
+			if n > size {
+				n = size
+			}
+			r.start = size - n
+			r.length = n
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errInvalidRange
+			}
+			r.start = start
+			r.length = size - start
+			if last != "" {
+				end, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, errInvalidRange
+				}
+				if end < size {
+					r.length = end - start + 1
+				}
+			}
+		}
+		if r.start >= size || r.length <= 0 {
+			noOverlap = true
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		if noOverlap {
+			return nil, errNoOverlap
+		}
+		return nil, errInvalidRange
+	}
+	return ranges, nil
+}
+
+// ifRangeMatches 判断If-Range是否与文件当前的版本一致，不一致时忽略Range返回整个文件。
+// 实体标签按强比较，日期需与Last-Modified完全相同
+func ifRangeMatches(c *gin.Context, info *interfaces.CacheInfo) bool {
+	ifRange := textproto.TrimString(c.GetHeader("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return info.ETag != "" && ifRange == info.ETag
+	}
+
+	t, err := http.ParseTime(ifRange)
+	if err != nil || info.LastModified == nil {
+		return false
+	}
+	return t.Equal(info.LastModified.Truncate(time.Second))
+}
+
+// requestRanges 获取需要返回的范围，返回nil时返回整个文件。
+// If-Range不匹配、范围过多或范围之和超过文件大小(重叠的范围)时忽略Range
+func requestRanges(c *gin.Context, file *interfaces.FileInfo, info *interfaces.CacheInfo) ([]httpRange, error) {
+	header := c.GetHeader("Range")
+	if header == "" || !ifRangeMatches(c, info) {
+		return nil, nil
+	}
+
+	ranges, err := parseRange(header, file.Size)
+	if err != nil || len(ranges) <= 1 {
+		return ranges, err
+	}
+	if len(ranges) > maxRanges {
+		return nil, nil
+	}
+	var total int64
+	for _, r := range ranges {
+		total += r.length
+	}
+	if total > file.Size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// multipartRangesSize 计算multipart/byteranges响应体的长度
+func multipartRangesSize(ranges []httpRange, boundary, contentType string, size int64) int64 {
+	var counter countingWriter
+	mw := multipart.NewWriter(&counter)
+	mw.SetBoundary(boundary)
+	var total int64
+	for _, r := range ranges {
+		mw.CreatePart(r.mimeHeader(contentType, size))
+		total += r.length
+	}
+	mw.Close()
+	return total + int64(counter)
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// writeMultipartRanges 以multipart/byteranges格式依次写入各范围，每个范围在写入时才从存储读取
+func writeMultipartRanges(w io.Writer, boundary, contentType string, size int64, ranges []httpRange, open func(r httpRange) (io.ReadCloser, error)) error {
+	mw := multipart.NewWriter(w)
+	mw.SetBoundary(boundary)
+	for _, r := range ranges {
+		part, err := mw.CreatePart(r.mimeHeader(contentType, size))
+		if err != nil {
+			return err
+		}
+		reader, err := open(r)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
@@ -0,0 +1,83 @@
+package driveradapters
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	const size = 100
+	tests := []struct {
+		name    string
+		header  string
+		want    []httpRange
+		wantErr error
+	}{
+		{"first bytes", "bytes=0-9", []httpRange{{0, 10}}, nil},
+		{"middle bytes", "bytes=10-19", []httpRange{{10, 10}}, nil},
+		{"open ended", "bytes=90-", []httpRange{{90, 10}}, nil},
+		{"suffix", "bytes=-5", []httpRange{{95, 5}}, nil},
+		{"suffix longer than file", "bytes=-500", []httpRange{{0, 100}}, nil},
+		{"end beyond file", "bytes=95-500", []httpRange{{95, 5}}, nil},
+		{"last byte", "bytes=99-99", []httpRange{{99, 1}}, nil},
+		{"multiple in request order", "bytes=50-59, 0-9", []httpRange{{50, 10}, {0, 10}}, nil},
+		{"whitespace and empty specs", "bytes= 0-1 ,, 5-6 ", []httpRange{{0, 2}, {5, 2}}, nil},
+		{"unsatisfiable specs skipped", "bytes=200-300, 0-0", []httpRange{{0, 1}}, nil},
+		{"other unit ignored", "items=0-9", nil, nil},
+		{"missing equals ignored", "bytes", nil, nil},
+		{"start beyond file", "bytes=100-", nil, errNoOverlap},
+		{"empty suffix", "bytes=-0", nil, errNoOverlap},
+		{"no dash", "bytes=5", nil, errInvalidRange},
+		{"end before start", "bytes=10-5", nil, errInvalidRange},
+		{"negative start", "bytes=--5", nil, errInvalidRange},
+		{"not a number", "bytes=a-b", nil, errInvalidRange},
+		{"no specs", "bytes=", nil, errInvalidRange},
+		{"one invalid spec fails all", "bytes=0-9, x-y", nil, errInvalidRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRange(tt.header, size)
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("parseRange(%q) error = %v, want %v", tt.header, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseRange(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+// multipartRangesSize计算的长度与writeMultipartRanges实际写入的长度一致，用作Content-Length
+func TestMultipartRangesSize(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	size := int64(len(content))
+	tests := []struct {
+		name        string
+		ranges      []httpRange
+		contentType string
+	}{
+		{"two ranges", []httpRange{{0, 10}, {500, 100}}, "text/plain"},
+		{"overlapping ranges", []httpRange{{0, 600}, {100, 200}}, "application/octet-stream"},
+		{"single byte ranges", []httpRange{{0, 1}, {999, 1}, {500, 1}}, "image/png"},
+		{"content type with parameters", []httpRange{{0, 10}, {10, 10}}, "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const boundary = "3d6b6a416f9b5a2c"
+			buf := &bytes.Buffer{}
+			err := writeMultipartRanges(buf, boundary, tt.contentType, size, tt.ranges, func(r httpRange) (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(content[r.start : r.start+r.length])), nil
+			})
+			if err != nil {
+				t.Fatalf("writeMultipartRanges() error = %v", err)
+			}
+			if got := multipartRangesSize(tt.ranges, boundary, tt.contentType, size); got != int64(buf.Len()) {
+				t.Fatalf("multipartRangesSize() = %d, written %d", got, buf.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package driveradapters
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"FileEngine/logics"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,checksum,expiration"
+	tusBasePath   = "/api/v1/file-engine/tus"
+)
+
+var (
+	tusHandlerOnce sync.Once
+	tusHandler     *TusHandler
+)
+
+// TusHandler 实现tus 1.0断点续传协议(core、creation、termination、checksum、expiration)
+type TusHandler struct {
+	logicsTus interfaces.LogicsTus
+}
+
+func NewTusHandler() interfaces.RESTHandler {
+	tusHandlerOnce.Do(func() {
+		tusHandler = &TusHandler{
+			logicsTus: logics.NewLogicsTus(),
+		}
+	})
+	return tusHandler
+}
+
+func (handler *TusHandler) RegisterPublic(engine *gin.Engine) {
+	group := engine.Group(tusBasePath, handler.tusMiddleware())
+	group.OPTIONS("", handler.options)
+	group.POST("", handler.createUpload)
+	group.OPTIONS("/:uploadID", handler.options)
+	group.HEAD("/:uploadID", handler.getOffset)
+	group.PATCH("/:uploadID", handler.appendUpload)
+	group.DELETE("/:uploadID", handler.terminateUpload)
+}
+
+func (handler *TusHandler) RegisterPrivate(engine *gin.Engine) {
+}
+
+// 协议发现
+func (handler *TusHandler) options(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(handler.logicsTus.MaxSize(), 10))
+	c.Header("Tus-Checksum-Algorithm", strings.Join(logics.TusChecksumAlgorithms, ","))
+	c.Status(http.StatusNoContent)
+}
+
+// 创建上传
+func (handler *TusHandler) createUpload(c *gin.Context) {
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		err := common.NewHTTPError(http.StatusBadRequest, "Upload-Defer-Length is not supported", nil)
+		common.ReplyError(c, err)
+		return
+	}
+
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		err = common.NewHTTPError(http.StatusBadRequest, "Invalid Upload-Length", nil)
+		common.ReplyError(c, err)
+		return
+	}
+
+	rawMetadata := c.GetHeader("Upload-Metadata")
+	metadata, err := parseTusMetadata(rawMetadata)
+	if err != nil {
+		err = common.NewHTTPError(http.StatusBadRequest, "Invalid Upload-Metadata", []map[string]interface{}{
+			{"error": "Invalid Upload-Metadata", "message": err.Error()},
+		})
+		common.ReplyError(c, err)
+		return
+	}
+
+	filename := metadata["filename"]
+	if filename == "" {
+		filename = metadata["name"]
+	}
+	contentType := metadata["filetype"]
+	if contentType == "" {
+		contentType = metadata["content_type"]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	upload, err := handler.logicsTus.CreateUpload(ctx, filename, contentType, size, metadata["conflict"], rawMetadata)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	c.Header("Location", tusBasePath+"/"+upload.ID)
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	setTusUploadHeaders(c, upload)
+	c.Status(http.StatusCreated)
+}
+
+// 查询上传偏移量
+func (handler *TusHandler) getOffset(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	upload, err := handler.logicsTus.GetUpload(ctx, c.Param("uploadID"))
+	if err != nil {
+		// HEAD请求不能携带响应体
+		code := http.StatusInternalServerError
+		if httpErr, ok := err.(*common.HTTPError); ok {
+			code = httpErr.StatusCode()
+		}
+		c.Status(code)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if upload.Metadata != "" {
+		c.Header("Upload-Metadata", upload.Metadata)
+	}
+	setTusUploadHeaders(c, upload)
+	c.Status(http.StatusOK)
+}
+
+// 追加数据
+func (handler *TusHandler) appendUpload(c *gin.Context) {
+	if c.ContentType() != "application/offset+octet-stream" {
+		err := common.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream", nil)
+		common.ReplyError(c, err)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		err = common.NewHTTPError(http.StatusBadRequest, "Invalid Upload-Offset", nil)
+		common.ReplyError(c, err)
+		return
+	}
+
+	checksum, err := parseTusChecksum(c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	upload, err := handler.logicsTus.AppendUpload(ctx, c.Param("uploadID"), offset, c.Request.ContentLength, c.Request.Body, checksum)
+	if err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	setTusUploadHeaders(c, upload)
+	c.Status(http.StatusNoContent)
+}
+
+// 终止上传
+func (handler *TusHandler) terminateUpload(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := handler.logicsTus.TerminateUpload(ctx, c.Param("uploadID")); err != nil {
+		common.ReplyError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// tus协议中间件：所有响应携带Tus-Resumable，除OPTIONS外的请求必须声明协议版本
+func (handler *TusHandler) tusMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", tusVersion)
+
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+			c.Header("Tus-Version", tusVersion)
+			common.ReplyError(c, common.NewHTTPError(http.StatusPreconditionFailed, "Unsupported Tus-Resumable version", nil))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setTusUploadHeaders 已完成的上传返回生成的文件ID，未完成的上传返回过期时间
+func setTusUploadHeaders(c *gin.Context, upload *interfaces.TusUpload) {
+	if upload.FileID != "" {
+		c.Header("File-ID", upload.FileID)
+		return
+	}
+	c.Header("Upload-Expires", time.Unix(upload.ExpireAt, 0).UTC().Format(http.TimeFormat))
+}
+
+// parseTusMetadata 解析Upload-Metadata：以逗号分隔的"key base64(value)"
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if header == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("metadata %s is not base64 encoded", fields[0])
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, fmt.Errorf("malformed metadata pair %q", pair)
+		}
+	}
+
+	return metadata, nil
+}
+
+// parseTusChecksum 解析Upload-Checksum："<算法> base64(校验和)"
+func parseTusChecksum(header string) (*interfaces.UploadChecksum, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) != 2 {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Invalid Upload-Checksum", nil)
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Invalid Upload-Checksum", []map[string]interface{}{
+			{"error": "Invalid Upload-Checksum", "message": "checksum is not base64 encoded"},
+		})
+	}
+
+	return &interfaces.UploadChecksum{
+		Algorithm: fields[0],
+		Sum:       sum,
+	}, nil
+}
@@ -2,17 +2,256 @@ package interfaces
 
 import (
 	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrDuplicateEntry 违反唯一约束，如文件名已存在
+	ErrDuplicateEntry = errors.New("Duplicate entry")
+	// ErrOffsetConflict 上传偏移量与当前记录不一致，通常由并发写入导致
+	ErrOffsetConflict = errors.New("upload offset conflict")
+	// ErrUploadCompleted 上传已完成，不能再次完成
+	ErrUploadCompleted = errors.New("upload already completed")
 )
 
 type DBFile interface {
 	// 创建文件记录
 	CreateFile(ctx context.Context, file *FileInfo) error
+	// 将待确认的文件命名为name并置为可用，replacedID不为空时在同一事务中删除被覆盖的同名记录。
+	// 被覆盖的记录已被删除或改名、或name已被其他记录占用时返回ErrDuplicateEntry，由调用方重新读取同名记录后重试
+	ActivateFile(ctx context.Context, fileID, name, replacedID string) error
+	// 以待确认记录fileID的对象和元数据替换可用文件replaced的内容并删除待确认记录，replaced保留其ID和创建时间。
+	// replaced已被删除、改名或内容已被并发替换时返回ErrDuplicateEntry；返回replaced原来的对象是否仍被其他记录引用
+	ReplaceFile(ctx context.Context, fileID string, replaced *FileInfo) (bool, error)
+	// 在同一事务中将一批待确认的记录置为可用，任一记录失败时全部回滚。TargetName为空的记录已以最终名称创建，直接置为可用；
+	// 不为空的记录按ActivateFile、ReplaceFile的方式替换同名文件。返回各记录最终的文件ID，以及被替换后不再被引用的对象
+	ActivateFiles(ctx context.Context, files []*FileInfo) ([]string, []*StorageObject, error)
 	// 根据ID获取文件
 	GetFileByID(ctx context.Context, fileID string) (*FileInfo, error)
 	// 根据名称获取文件
 	GetFileByName(ctx context.Context, name string) (*FileInfo, error)
+	// 按内容查找可用的文件，用于秒传，不存在时返回nil
+	GetFileByContent(ctx context.Context, bucketID, sha256 string, size int64) (*FileInfo, error)
+	// 删除文件记录，并在同一事务中加行锁检查存储对象是否仍被其他记录引用，记录不存在时只做检查
+	DeleteFileReference(ctx context.Context, fileID, bucketID, objectName string) (bool, error)
+	// 加行锁检查除excludeID外是否还有文件记录引用存储对象
+	HasObjectReference(ctx context.Context, bucketID, objectName, excludeID string) (bool, error)
+	// 按桶和对象名顺序列出文件记录引用的存储对象，after不为nil时从其后开始，最多返回limit个
+	ListObjects(ctx context.Context, after *StorageObject, limit int) ([]*StorageObject, error)
+	// 将引用存储对象oldName的文件记录改为引用newName，返回更新的记录数
+	UpdateObjectName(ctx context.Context, bucketID, oldName, newName string) (int64, error)
+	// 更新文件状态
+	UpdateFileStatus(ctx context.Context, fileID, status string) error
+	// 更新文件内容的SHA-256
+	UpdateFileSHA256(ctx context.Context, fileID, sha256 string) error
+	// 更新文件类型
+	UpdateFileContentType(ctx context.Context, fileID, contentType string) error
 	// 删除文件记录
 	DeleteFile(ctx context.Context, fileID string) error
-	// 获取文件列表
+	// 获取文件列表，仅包含可用状态的文件
 	GetFileList(ctx context.Context, bucketID string, page, pageSize int) ([]*FileInfo, int64, error)
 }
+
+type DBTusUpload interface {
+	// 创建上传记录
+	CreateTusUpload(ctx context.Context, upload *TusUpload) error
+	// 根据ID获取上传记录
+	GetTusUpload(ctx context.Context, uploadID string) (*TusUpload, error)
+	// 记录分片、推进偏移量并将过期时间延长至expireAt，仅当当前偏移量等于part.Offset时成功，否则返回ErrOffsetConflict
+	AppendTusPart(ctx context.Context, part *TusPart, expireAt int64) error
+	// 按偏移量顺序获取分片
+	GetTusParts(ctx context.Context, uploadID string) ([]*TusPart, error)
+	// 标记上传完成并删除分片记录
+	CompleteTusUpload(ctx context.Context, uploadID, fileID string) error
+	// 删除上传记录及分片记录
+	DeleteTusUpload(ctx context.Context, uploadID string) error
+	// 获取过期时间早于expireAt的上传记录，最多返回limit条
+	GetExpiredTusUploads(ctx context.Context, expireAt int64, limit int) ([]*TusUpload, error)
+}
+
+type DBMultipartUpload interface {
+	// 创建分片上传会话
+	CreateMultipartUpload(ctx context.Context, upload *MultipartUpload) error
+	// 根据ID获取分片上传会话
+	GetMultipartUpload(ctx context.Context, uploadID string) (*MultipartUpload, error)
+	// 标记分片上传完成，已完成时返回ErrUploadCompleted
+	CompleteMultipartUpload(ctx context.Context, uploadID, fileID string) error
+	// 删除分片上传会话
+	DeleteMultipartUpload(ctx context.Context, uploadID string) error
+}
+
+type DBImportJob interface {
+	// 创建导入任务
+	CreateImportJob(ctx context.Context, job *ImportJob) error
+	// 根据ID获取导入任务
+	GetImportJob(ctx context.Context, jobID string) (*ImportJob, error)
+	// 更新导入任务的状态、导入的文件ID和失败原因
+	UpdateImportJob(ctx context.Context, jobID, status, fileID, errorMessage string) error
+}
+
+type DBIdempotencyKey interface {
+	// 创建幂等键记录，幂等键已存在时返回ErrDuplicateEntry
+	CreateIdempotencyKey(ctx context.Context, record *IdempotencyKey) error
+	// 根据幂等键获取记录，不存在时返回nil
+	GetIdempotencyKey(ctx context.Context, key string) (*IdempotencyKey, error)
+	// 保存响应并置为已完成，仅当记录的令牌为token时更新，返回更新的记录数
+	CompleteIdempotencyKey(ctx context.Context, record *IdempotencyKey) (int64, error)
+	// 删除令牌为token的幂等键记录
+	DeleteIdempotencyKey(ctx context.Context, key, token string) error
+	// 删除过期时间早于expireAt(Unix秒)的记录，返回删除的记录数
+	DeleteExpiredIdempotencyKeys(ctx context.Context, expireAt int64) (int64, error)
+}
+
+// 去重存储的对象与数据块映射，相同内容的对象或块共用一个数据块
+type DBBlob interface {
+	// 获取对象指向的数据块，对象不存在映射时返回nil；分块存储的对象StorageKey为空
+	GetObjectBlob(ctx context.Context, bucketID, objectName string) (*ObjectBlob, error)
+	// 按序号顺序获取分块存储对象的块清单
+	GetObjectChunks(ctx context.Context, bucketID, objectName string) ([]*ObjectChunk, error)
+	// 引用内容为hash的已有数据块，引用计数加一并返回其存储对象名，数据块不存在时found为false
+	AcquireBlob(ctx context.Context, bucketID, hash string) (storageKey string, found bool, err error)
+	// 以storageKey创建内容为hash的数据块，引用计数为1；数据块已存在时引用计数加一并返回已有数据块的存储对象名
+	CreateBlob(ctx context.Context, bucketID, hash, storageKey string, size int64) (string, error)
+	// 释放数据块的引用，每个hash释放一次，返回引用归零的数据块存储对象名
+	ReleaseBlobs(ctx context.Context, bucketID string, hashes []string) ([]string, error)
+	// 将对象指向内容为object.Hash的数据块。数据块已存在时引用计数加一并返回已有数据块的存储对象名，
+	// 否则以object.StorageKey创建数据块；对象原先存在映射时释放其引用的数据块，返回引用归零的数据块存储对象名
+	AttachBlob(ctx context.Context, object *ObjectBlob) (storageKey string, released []string, err error)
+	// 将对象指向按序排列的块，块引用的数据块需已通过AcquireBlob或CreateBlob取得引用；
+	// 对象原先存在映射时释放其引用的数据块，返回引用归零的数据块存储对象名
+	AttachChunks(ctx context.Context, object *ObjectBlob, chunks []*ObjectChunk) (released []string, err error)
+	// 删除对象映射并释放数据块，返回引用归零的数据块存储对象名，对象不存在映射时found为false
+	DetachBlob(ctx context.Context, bucketID, objectName string) (released []string, found bool, err error)
+}
+
+// 文件记录引用的存储对象
+type StorageObject struct {
+	BucketID   string
+	ObjectName string
+}
+
+// tus断点续传上传
+type TusUpload struct {
+	ID          string
+	BucketID    string
+	Filename    string
+	ContentType string
+	Size        int64  // Upload-Length
+	Offset      int64  // Upload-Offset
+	Metadata    string // 原始Upload-Metadata
+	Conflict    string // 同名文件的处理方式
+	FileID      string // 上传完成后生成的文件ID
+	ExpireAt    int64  // 过期时间(Unix秒)，过期未完成的上传及其分片被清理
+	CreateTime  *time.Time
+	UpdateTime  *time.Time
+}
+
+// tus断点续传分片，每个分片对应存储中的一个临时对象
+type TusPart struct {
+	UploadID   string
+	Offset     int64
+	Size       int64
+	ObjectName string
+}
+
+// 分片上传会话，分片直传存储，完成后合并为一个文件
+type MultipartUpload struct {
+	ID              string
+	BucketID        string
+	Filename        string
+	ObjectName      string // 存储中的对象名
+	ContentType     string
+	Size            int64
+	PartSize        int64  // 分片大小，最后一个分片可以更小
+	StorageUploadID string // 存储侧的分片上传ID
+	Conflict        string // 同名文件的处理方式
+	FileID          string // 上传完成后生成的文件ID
+	CreateTime      *time.Time
+	UpdateTime      *time.Time
+}
+
+// URL导入任务，由FileEngine从源URL下载并创建文件
+type ImportJob struct {
+	ID           string
+	BucketID     string
+	SourceURL    string
+	Filename     string // 指定的文件名，为空时从响应中获取
+	Conflict     string // 同名文件的处理方式
+	SHA256       string // 期望的内容SHA-256，为空不校验
+	Status       string
+	FileID       string // 导入成功后生成的文件ID
+	ErrorMessage string // 导入失败的原因
+	ExpireAt     int64  // 截止时间(Unix秒)，超过仍未结束说明执行任务的实例已退出
+	CreateTime   *time.Time
+	UpdateTime   *time.Time
+}
+
+// 导入任务状态
+const (
+	// 等待执行
+	ImportStatusPending = "pending"
+	// 正在下载
+	ImportStatusRunning = "running"
+	// 导入成功
+	ImportStatusSucceeded = "succeeded"
+	// 导入失败
+	ImportStatusFailed = "failed"
+)
+
+// 幂等键记录，保存首次处理请求的指纹和响应
+type IdempotencyKey struct {
+	Key            string
+	Token          string // 处理请求的实例持有的令牌
+	Fingerprint    string // 请求指纹
+	BodyDigest     string // 请求体未计入指纹时，请求完成后保存的请求体SHA-256
+	Status         string
+	ResponseCode   int
+	ResponseHeader string // 响应头(JSON)
+	ResponseBody   []byte
+	ExpireAt       int64 // 过期时间(Unix秒)，处理中的记录过期表示处理中断
+	CreateTime     *time.Time
+	UpdateTime     *time.Time
+}
+
+// 幂等键状态
+const (
+	// 请求正在处理
+	IdempotencyStatusProcessing = "processing"
+	// 已处理并保存了响应
+	IdempotencyStatusCompleted = "completed"
+)
+
+// PartCount 分片总数
+func (u *MultipartUpload) PartCount() int {
+	return int((u.Size + u.PartSize - 1) / u.PartSize)
+}
+
+// PartSizeOf 指定分片号的分片大小
+func (u *MultipartUpload) PartSizeOf(partNumber int) int64 {
+	if partNumber < u.PartCount() {
+		return u.PartSize
+	}
+	return u.Size - int64(u.PartCount()-1)*u.PartSize
+}
+
+// 去重存储中逻辑对象到数据块的映射
+type ObjectBlob struct {
+	BucketID    string
+	ObjectName  string
+	Hash        string // 内容的SHA-256，未分块时数据块以此为键
+	StorageKey  string // 数据块在底层存储中的对象名
+	Size        int64
+	ContentType string
+	ChunkCount  int // 分块存储的块数，为0表示整个对象指向一个数据块
+	CreateTime  *time.Time
+}
+
+// 分块存储对象中的块，块内容以数据块存储
+type ObjectChunk struct {
+	Seq        int
+	Hash       string // 块内容的SHA-256
+	StorageKey string // 数据块在底层存储中的对象名
+	Offset     int64  // 块在对象中的起始偏移量
+	Size       int64
+}
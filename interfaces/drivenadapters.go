@@ -7,10 +7,12 @@ import (
 )
 
 type StorageAdapter interface {
-	// 上传文件到存储
+	// 上传文件到存储，size为-1表示长度未知，按流式分片上传
 	Upload(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error
 	// 从存储下载文件
 	Download(ctx context.Context, bucketID, objectName string) (io.ReadCloser, error)
+	// 从存储读取文件自offset起的length个字节，只从存储获取该范围的内容，范围需在文件大小之内
+	DownloadRange(ctx context.Context, bucketID, objectName string, offset, length int64) (io.ReadCloser, error)
 	// 从存储删除文件
 	Delete(ctx context.Context, bucketID, objectName string) error
 	// 检查文件是否存在
@@ -22,6 +24,28 @@ type StorageAdapter interface {
 	GeneratePresignedDownloadURL(ctx context.Context, bucketID, objectName string, expiration time.Duration) (string, error)
 	// 新增：生成预签名上传URL
 	GeneratePresignedUploadURL(ctx context.Context, bucketID, objectName string, expiration time.Duration) (string, error)
+	// 生成浏览器表单直传的预签名POST策略，由存储按策略校验对象名、类型和大小
+	GeneratePresignedPostPolicy(ctx context.Context, bucketID, objectName string, conditions *UploadConditions, expiration time.Duration) (*PresignedPost, error)
+
+	// 初始化分片上传，返回存储侧的分片上传ID
+	InitiateMultipartUpload(ctx context.Context, bucketID, objectName, contentType string) (string, error)
+	// 上传分片
+	UploadPart(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (*StoragePart, error)
+	// 生成分片的预签名上传URL
+	GeneratePresignedPartURL(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, expiration time.Duration) (string, error)
+	// 按分片号顺序列出已上传的分片
+	ListParts(ctx context.Context, bucketID, objectName, uploadID string) ([]*StoragePart, error)
+	// 按分片号顺序合并分片为对象
+	CompleteMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string, parts []*StoragePart) error
+	// 取消分片上传并清理已上传的分片
+	AbortMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string) error
+}
+
+// URLSigningStorage 预签名URL由FileEngine自身签发并由对象接口校验的存储实现该接口，
+// 第三方驱动使用common.URLSigner签发URL时同样需要实现，启动时据此要求配置签名密钥
+type URLSigningStorage interface {
+	// 是否使用server.signKey签发URL
+	SignsURLs() bool
 }
 
 type StorageFileInfo struct {
@@ -30,3 +54,24 @@ type StorageFileInfo struct {
 	LastModified string
 	ETag         string
 }
+
+// 预签名POST策略的上传条件
+type UploadConditions struct {
+	ContentType string
+	MinSize     int64
+	MaxSize     int64
+}
+
+// 预签名POST策略，客户端以multipart/form-data提交FormData中的全部字段，文件字段file放在最后
+type PresignedPost struct {
+	URL      string
+	FormData map[string]string
+}
+
+// 分片上传中的分片
+type StoragePart struct {
+	PartNumber   int
+	Size         int64
+	ETag         string
+	LastModified string
+}
@@ -4,18 +4,42 @@ import (
 	"context"
 	"io"
 	"mime/multipart"
+	"net/http"
+	"net/url"
 	"time"
 )
 
 type LogicsFile interface {
 	// 上传文件
 	Upload(ctx context.Context, file *multipart.FileHeader) (*FileInfo, error)
+	// 从数据流上传文件，size为-1表示长度未知，sha256为客户端期望的摘要，为空表示不校验，conflict为同名文件的处理方式
+	UploadFromReader(ctx context.Context, filename, contentType string, size int64, sha256, conflict string, reader io.Reader) (*FileInfo, error)
+	// 批量上传文件，按顺序返回每个文件的结果；allOrNothing为true时任一文件失败则删除本次已上传的全部文件
+	BatchUpload(ctx context.Context, files []*multipart.FileHeader, conflict string, allOrNothing bool) ([]*BatchUploadResult, error)
 	// 下载文件
 	Download(ctx context.Context, fileID string) (*FileDownload, error)
+	// 获取可下载的文件，文件不可用或存储中不存在时返回404
+	GetDownloadFile(ctx context.Context, fileID string) (*FileInfo, error)
+	// 读取文件自offset起的length个字节，只从存储获取该范围的内容
+	DownloadRange(ctx context.Context, file *FileInfo, offset, length int64) (io.ReadCloser, error)
+	// 获取文件内容的缓存信息，实体标签为内容的SHA-256，未计算摘要的文件使用存储的ETag
+	GetCacheInfo(ctx context.Context, file *FileInfo) (*CacheInfo, error)
+	// 获取文件元数据的缓存信息，实体标签按元数据计算
+	GetMetaCacheInfo(ctx context.Context, file *FileInfo) (*CacheInfo, error)
+	// 获取打包下载的文件及其在压缩包中的名称，名称按请求顺序去重，任一文件不可用时返回404
+	GetArchiveEntries(ctx context.Context, fileIDs []string) ([]*ArchiveEntry, error)
+	// 秒传预检查：存在内容相同的文件时直接创建引用同一对象的文件记录，否则返回nil由客户端继续上传
+	PrecheckUpload(ctx context.Context, filename, sha256 string, size int64, conflict string) (*FileInfo, error)
 	// 生成预签名上传URL
-	GenerateUploadURL(ctx context.Context, filename string, contentType string, size int64) (*UploadURL, error)
+	GenerateUploadURL(ctx context.Context, filename string, contentType string, size int64, conflict string) (*UploadURL, error)
+	// 生成浏览器表单直传的预签名POST策略，存储侧强制校验对象名、类型和大小
+	GenerateUploadPolicy(ctx context.Context, filename string, contentType string, size int64, conflict string) (*UploadURL, error)
+	// 确认预签名上传完成，校验存储中的对象并计算SHA-256后将文件置为可用
+	CompleteUpload(ctx context.Context, fileID, etag, sha256 string) (*FileInfo, error)
 	// 生成预签名下载URL
 	GenerateDownloadURL(ctx context.Context, fileID string) (*DownloadURL, error)
+	// 获取默认桶的上传策略，供客户端上传前预先校验
+	GetUploadPolicy(ctx context.Context) (*UploadPolicy, error)
 
 	// 删除文件
 	Delete(ctx context.Context, fileID string) error
@@ -36,23 +60,87 @@ type DownloadURL struct {
 
 // 上传URL信息
 type UploadURL struct {
-	ID        string    `json:"id"`
-	URL       string    `json:"url"`
-	ExpiresAt time.Time `json:"expires_at"`
-	ExpiresIn int64     `json:"expires_in"` // 过期时间（秒）
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`   // 文件名，rename模式下为重命名后的名称
+	Method    string            `json:"method"` // PUT或POST
+	URL       string            `json:"url"`
+	FormData  map[string]string `json:"form_data,omitempty"` // POST表单字段
+	ExpiresAt time.Time         `json:"expires_at"`
+	ExpiresIn int64             `json:"expires_in"` // 过期时间（秒）
 }
 
+// 上传策略，所有上传方式按文件所在桶的策略校验
+type UploadPolicy struct {
+	BucketID             string   `json:"bucket_id"`
+	AllowedExtensions    []string `json:"allowed_extensions"` // 包含"*"表示不限制
+	DeniedExtensions     []string `json:"denied_extensions"`
+	AllowedMIMETypes     []string `json:"allowed_mime_types"` // 为空不限制，支持image/*形式的通配
+	DeniedMIMETypes      []string `json:"denied_mime_types"`
+	MinSize              int64    `json:"min_size"`
+	MaxSize              int64    `json:"max_size"`           // 单次请求上传(含预签名上传、tus)的最大文件大小
+	MaxMultipartSize     int64    `json:"max_multipart_size"` // 分片上传的最大文件大小
+	FilenamePattern      string   `json:"filename_pattern"`   // 文件名需匹配的正则表达式(RE2语法)，规范化后匹配
+	UnicodeNormalization string   `json:"unicode_normalization"`
+	MaxFilenameBytes     int      `json:"max_filename_bytes"` // 规范化后文件名的最大字节数(UTF-8)
+}
+
+// 批量上传中单个文件的结果
+// 打包下载的文件
+type ArchiveEntry struct {
+	Name string // 在压缩包中的名称
+	File *FileInfo
+}
+
+type BatchUploadResult struct {
+	Filename   string
+	File       *FileInfo // 上传成功的文件
+	Err        error     // 上传失败的原因
+	RolledBack bool      // allOrNothing模式下因其他文件失败而被删除或未上传
+}
+
+// 上传时同名文件的处理方式
+const (
+	// 同名文件已存在时拒绝上传(默认)
+	ConflictReject = "reject"
+	// 替换同名文件的对象和元数据，文件ID为新上传的文件的ID
+	ConflictOverwrite = "overwrite"
+	// 自动重命名为"name (1).ext"形式
+	ConflictRename = "rename"
+)
+
+// 文件状态
+const (
+	// 已生成预签名上传URL，等待客户端上传并确认
+	FileStatusPending = "pending"
+	// 可用
+	FileStatusActive = "active"
+	// 上传确认时校验失败
+	FileStatusFailed = "failed"
+)
+
 type FileInfo struct {
 	ID          string     `json:"id"`
 	Name        string     `json:"name"`
+	TargetName  string     `json:"target_name,omitempty"` // 覆盖上传确认后替换的文件名，待确认期间Name为临时名称
 	ContentType string     `json:"content_type"`
 	BucketID    string     `json:"bucket_id"`
+	ObjectName  string     `json:"object_name"` // 存储中的对象名，秒传的文件与源文件共用同一对象
 	Size        int64      `json:"size"`
 	Icon        string     `json:"icon"`
+	Status      string     `json:"status"`
+	SHA256      string     `json:"sha256"`              // 服务端计算的内容SHA-256(十六进制)，为空表示未计算
+	ExpireAt    int64      `json:"expire_at,omitempty"` // 待上传状态的过期时间(Unix秒)，过期仍未确认的记录被回收并释放文件名
 	CreateTime  *time.Time `json:"create_time"`
 	UpdateTime  *time.Time `json:"update_time"`
 }
 
+// 缓存信息，用于条件请求和缓存控制
+type CacheInfo struct {
+	ETag         string // 强实体标签(含引号)，为空表示没有实体标签
+	LastModified *time.Time
+	CacheControl string // 为空不返回Cache-Control
+}
+
 // 组合模式的简单实现
 type FileDownload struct {
 	File   *FileInfo
@@ -72,3 +160,93 @@ func GetContentType(file *multipart.FileHeader) string {
 	}
 	return contentType
 }
+
+type LogicsTus interface {
+	// 创建上传，conflict为上传完成时同名文件的处理方式
+	CreateUpload(ctx context.Context, filename, contentType string, size int64, conflict, metadata string) (*TusUpload, error)
+	// 获取上传
+	GetUpload(ctx context.Context, uploadID string) (*TusUpload, error)
+	// 从offset处追加数据，length为-1表示长度未知，checksum为空表示不校验
+	AppendUpload(ctx context.Context, uploadID string, offset, length int64, reader io.Reader, checksum *UploadChecksum) (*TusUpload, error)
+	// 终止上传并清理已上传的分片
+	TerminateUpload(ctx context.Context, uploadID string) error
+	// 允许的最大上传大小
+	MaxSize() int64
+}
+
+// 上传数据的校验和
+type UploadChecksum struct {
+	Algorithm string
+	Sum       []byte
+}
+
+type LogicsImport interface {
+	// 创建URL导入任务，任务在后台执行，filename为空时从响应中获取，sha256为期望的摘要，conflict为同名文件的处理方式
+	CreateImport(ctx context.Context, sourceURL, filename, sha256, conflict string) (*ImportJob, error)
+	// 获取导入任务
+	GetImport(ctx context.Context, jobID string) (*ImportJob, error)
+}
+
+type LogicsIdempotency interface {
+	// 开始处理携带幂等键的请求。首次请求返回令牌，由调用方处理后调用Complete或Release；已处理的请求返回保存的响应。
+	// 相同幂等键的请求正在处理时等待其完成，超时返回409，请求指纹与首次请求不一致时返回422
+	Acquire(ctx context.Context, key, fingerprint string) (string, *IdempotentResponse, error)
+	// 保存请求的响应，有效期内相同幂等键的请求直接返回该响应
+	Complete(ctx context.Context, key, token string, response *IdempotentResponse) error
+	// 放弃保存响应，相同幂等键的请求可重新处理
+	Release(ctx context.Context, key, token string) error
+}
+
+// 幂等请求保存的响应
+type IdempotentResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	BodyDigest string // 请求体未计入指纹时首次请求的请求体SHA-256，重放前需与本次请求体比较
+}
+
+type LogicsMultipart interface {
+	// 初始化分片上传，partSize为0时使用默认分片大小，conflict为完成时同名文件的处理方式
+	InitiateUpload(ctx context.Context, filename, contentType string, size, partSize int64, conflict string) (*MultipartUpload, error)
+	// 生成分片的预签名上传URL，partNumbers为空时生成全部分片的URL
+	GeneratePartURLs(ctx context.Context, uploadID string, partNumbers []int) ([]*PartURL, error)
+	// 获取分片上传会话及已上传的分片
+	ListParts(ctx context.Context, uploadID string) (*MultipartUpload, []*StoragePart, error)
+	// 完成分片上传，parts为空时使用存储中已上传的全部分片，sha256不为空时校验合并后的文件
+	CompleteUpload(ctx context.Context, uploadID string, parts []*StoragePart, sha256 string) (*FileInfo, error)
+	// 取消分片上传并清理已上传的分片
+	AbortUpload(ctx context.Context, uploadID string) error
+}
+
+// 分片上传URL信息
+type PartURL struct {
+	PartNumber int       `json:"part_number"`
+	Size       int64     `json:"size"`
+	URL        string    `json:"url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ExpiresIn  int64     `json:"expires_in"` // 过期时间（秒）
+}
+
+type LogicsObject interface {
+	// 校验FileEngine签发的预签名URL
+	VerifySignedURL(method, bucketID, objectName string, query url.Values) error
+	// 写入对象
+	PutObject(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) (*StorageFileInfo, error)
+	// 校验表单上传策略并写入对象，fields为文件字段之前的全部表单字段
+	PostObject(ctx context.Context, bucketID string, fields map[string]string, reader io.Reader) (*StorageFileInfo, error)
+	// 写入分片上传的分片
+	PutPart(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (*StoragePart, error)
+	// 读取对象
+	GetObject(ctx context.Context, bucketID, objectName string) (*ObjectDownload, error)
+}
+
+type ObjectDownload struct {
+	Info   *StorageFileInfo
+	Reader io.ReadCloser
+}
+
+func (o *ObjectDownload) Close() {
+	if o.Reader != nil {
+		o.Reader.Close()
+	}
+}
@@ -0,0 +1,73 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// MaxArchiveFiles 打包下载单次请求的最大文件数
+const MaxArchiveFiles = 1000
+
+// GetArchiveEntries 在开始写入压缩包之前获取全部文件，重复的文件ID只打包一次。
+// 文件名中的路径分隔符替换为下划线，解压时不会写到目标目录之外；同名文件(不区分大小写)依次命名为name (1).ext、name (2).ext
+func (l *LogicsFile) GetArchiveEntries(ctx context.Context, fileIDs []string) ([]*interfaces.ArchiveEntry, error) {
+	if len(fileIDs) == 0 {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "No file selected, please select files to download", nil)
+	}
+	if len(fileIDs) > MaxArchiveFiles {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Too many files", []map[string]interface{}{
+			{"error": "Too many files", "message": fmt.Sprintf("at most %d files can be downloaded in one archive, got %d", MaxArchiveFiles, len(fileIDs))},
+		})
+	}
+
+	entries := make([]*interfaces.ArchiveEntry, 0, len(fileIDs))
+	seenIDs := make(map[string]bool, len(fileIDs))
+	usedNames := make(map[string]bool, len(fileIDs))
+	for _, fileID := range fileIDs {
+		if seenIDs[fileID] {
+			continue
+		}
+		seenIDs[fileID] = true
+
+		fileInfo, err := l.getActiveFile(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &interfaces.ArchiveEntry{
+			Name: uniqueArchiveName(archiveName(fileInfo), usedNames),
+			File: fileInfo,
+		})
+	}
+
+	return entries, nil
+}
+
+// archiveName 文件在压缩包中的基本名称，文件名为空或只有.时使用文件ID
+func archiveName(file *interfaces.FileInfo) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(file.Name))
+	if strings.Trim(name, ".") == "" {
+		return file.ID
+	}
+	return name
+}
+
+func uniqueArchiveName(name string, used map[string]bool) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if base == "" {
+		// .bashrc等以.开头的文件名没有扩展名
+		base, ext = name, ""
+	}
+
+	candidate := name
+	for i := 1; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,184 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// MaxBatchFiles 批量上传单次请求的最大文件数
+	MaxBatchFiles = 100
+	// batchUploadConcurrency 批量上传时同时上传到存储的文件数
+	batchUploadConcurrency = 4
+)
+
+// BatchUpload 批量上传文件，文件之间相互独立，部分失败时返回每个文件各自的结果。
+// allOrNothing模式下先校验全部文件，文件记录以待上传状态创建，上传过程中任一文件失败则不再上传其余文件，并删除已上传的文件记录和对象；
+// 全部上传成功后在同一事务中将全部记录置为可用并替换overwrite模式的同名文件，回滚时不会影响原文件
+func (l *LogicsFile) BatchUpload(ctx context.Context, files []*multipart.FileHeader, conflict string, allOrNothing bool) ([]*interfaces.BatchUploadResult, error) {
+	if len(files) == 0 {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "No file uploaded, please select a file to upload", nil)
+	}
+	if len(files) > MaxBatchFiles {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Too many files", []map[string]interface{}{
+			{"error": "Too many files", "message": fmt.Sprintf("at most %d files can be uploaded in one request, got %d", MaxBatchFiles, len(files))},
+		})
+	}
+	conflict, err := parseConflict(conflict)
+	if err != nil {
+		return nil, err
+	}
+
+	// 文件校验
+	results := make([]*interfaces.BatchUploadResult, len(files))
+	filenames := make([]string, len(files))
+	for i, file := range files {
+		results[i] = &interfaces.BatchUploadResult{Filename: file.Filename}
+		filenames[i], results[i].Err = l.validateFile(file)
+	}
+	if allOrNothing && batchFailed(results) {
+		l.rollbackBatch(ctx, results)
+		return results, nil
+	}
+
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchUploadConcurrency)
+	for i, file := range files {
+		if results[i].Err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *interfaces.BatchUploadResult, file *multipart.FileHeader, filename string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// allOrNothing模式下已有文件失败时不再上传
+			if allOrNothing && failed.Load() {
+				return
+			}
+			result.File, result.Err = l.uploadBatchFile(ctx, file, filename, conflict, allOrNothing)
+			if result.Err != nil {
+				failed.Store(true)
+			}
+		}(results[i], file, filenames[i])
+	}
+	wg.Wait()
+
+	if !allOrNothing {
+		return results, nil
+	}
+	if failed.Load() {
+		l.rollbackBatch(ctx, results)
+		return results, nil
+	}
+
+	l.activateBatch(ctx, results)
+	return results, nil
+}
+
+// activateBatch 在同一事务中将allOrNothing模式上传的全部记录置为可用，同名文件被并发替换时重新执行，
+// 仍失败时回滚整个批次，全部文件按失败返回
+func (l *LogicsFile) activateBatch(ctx context.Context, results []*interfaces.BatchUploadResult) {
+	files := make([]*interfaces.FileInfo, len(results))
+	for i, result := range results {
+		files[i] = result.File
+	}
+
+	var ids []string
+	var released []*interfaces.StorageObject
+	var err error
+	for attempt := 0; attempt < maxReplaceAttempts; attempt++ {
+		ids, released, err = l.dbFile.ActivateFiles(ctx, files)
+		if !errors.Is(err, interfaces.ErrDuplicateEntry) {
+			break
+		}
+	}
+	if err != nil {
+		l.rollbackBatch(ctx, results)
+		if errors.Is(err, interfaces.ErrDuplicateEntry) {
+			err = common.NewHTTPError(http.StatusConflict, "File is being replaced concurrently", []map[string]interface{}{
+				{"error": "File is being replaced concurrently", "message": "files with the same names are being replaced by other uploads, please retry"},
+			})
+		} else {
+			err = common.NewHTTPError(http.StatusInternalServerError, "Failed to update file status", []map[string]interface{}{
+				{"error": "Failed to update file status", "message": err.Error()},
+			})
+		}
+		for _, result := range results {
+			result.RolledBack = false
+			result.Err = err
+		}
+		return
+	}
+
+	for _, object := range released {
+		if err = l.storage.Delete(context.WithoutCancel(ctx), object.BucketID, object.ObjectName); err != nil {
+			log.Printf("[WARN] failed to delete replaced object %s: %v", object.ObjectName, err)
+		}
+	}
+	for i, result := range results {
+		fileInfo := result.File
+		fileInfo.ID = ids[i]
+		fileInfo.Name = targetName(fileInfo)
+		fileInfo.TargetName = ""
+		fileInfo.Status = interfaces.FileStatusActive
+		result.File, result.Err = l.activatedFile(ctx, fileInfo)
+	}
+}
+
+// uploadBatchFile 上传批量上传中的单个文件，allOrNothing模式下只写入存储和创建记录，由调用方完成或回滚
+func (l *LogicsFile) uploadBatchFile(ctx context.Context, file *multipart.FileHeader, filename, conflict string, allOrNothing bool) (*interfaces.FileInfo, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to open uploaded file", []map[string]interface{}{
+			{"error": "Failed to open uploaded file", "message": err.Error()},
+		})
+	}
+	defer src.Close()
+
+	if allOrNothing {
+		return l.store(ctx, filepath.Base(filename), interfaces.GetContentType(file), file.Size, "", conflict, true, src)
+	}
+	return l.upload(ctx, filepath.Base(filename), interfaces.GetContentType(file), file.Size, "", conflict, src)
+}
+
+// rollbackBatch 删除allOrNothing模式下已上传的文件记录并释放对象，未失败的文件均标记为已回滚
+func (l *LogicsFile) rollbackBatch(ctx context.Context, results []*interfaces.BatchUploadResult) {
+	ctx = context.WithoutCancel(ctx)
+	rolledBack := 0
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		if fileInfo := result.File; fileInfo != nil {
+			if err := l.releaseFile(ctx, fileInfo); err != nil {
+				log.Printf("[WARN] failed to release file %s when rolling back batch upload: %v", fileInfo.ID, err)
+			}
+			rolledBack++
+		}
+		result.File = nil
+		result.RolledBack = true
+	}
+	log.Printf("[INFO] batch upload failed, rolled back %d uploaded files", rolledBack)
+}
+
+func batchFailed(results []*interfaces.BatchUploadResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
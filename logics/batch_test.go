@@ -0,0 +1,147 @@
+package logics
+
+import (
+	"FileEngine/interfaces"
+	"bytes"
+	"context"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+// longName 超过文件名最大字节数，校验时即失败
+var longName = strings.Repeat("x", 1024) + ".txt"
+
+// batchFiles 构造批量上传的文件，依次为文件名和内容
+func batchFiles(t *testing.T, nameContents ...string) []*multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i := 0; i < len(nameContents); i += 2 {
+		part, err := writer.CreateFormFile("files", nameContents[i])
+		if err != nil {
+			t.Fatalf("CreateFormFile() error = %v", err)
+		}
+		part.Write([]byte(nameContents[i+1]))
+	}
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("ReadForm() error = %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["files"]
+}
+
+// allOrNothing模式下任一文件失败，已上传的记录和对象全部删除，同名的原文件保持不变
+func TestBatchUploadAllOrNothingRollsBack(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		failed string
+		files  []string
+	}{
+		// 内容与扩展名不符，上传到存储时才失败
+		{"failure during upload", "bad.png", []string{"a.txt", "new a", "b.txt", "new b", "bad.png", "not a png", "c.txt", "new c"}},
+		{"failure during validation", longName, []string{"a.txt", "new a", "b.txt", "new b", longName, "too long"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			old := e.upload(t, "a.txt", "old a", "")
+
+			results, err := e.file.BatchUpload(ctx, batchFiles(t, tt.files...), interfaces.ConflictOverwrite, true)
+			if err != nil || len(results) != len(tt.files)/2 {
+				t.Fatalf("BatchUpload() = %d results, %v, want %d", len(results), err, len(tt.files)/2)
+			}
+			for _, result := range results {
+				if result.Filename == tt.failed {
+					if result.Err == nil || result.File != nil {
+						t.Fatalf("result of %s = %+v, want error", result.Filename, result)
+					}
+				} else if result.Err != nil || result.File != nil || !result.RolledBack {
+					t.Fatalf("result of %s = %+v, want rolled back", result.Filename, result)
+				}
+			}
+
+			files, total, err := e.file.GetList(ctx, 1, 100)
+			if err != nil || total != 1 || files[0].ID != old.ID || e.content(t, files[0]) != "old a" {
+				t.Fatalf("GetList() = %d files, %v, want only the original a.txt", total, err)
+			}
+			for i := 0; i < len(tt.files); i += 2 {
+				if tt.files[i] == "a.txt" {
+					continue
+				}
+				if record, err := e.dbFile.GetFileByName(ctx, tt.files[i]); err != nil || record != nil {
+					t.Fatalf("%s record = %+v, %v, want none", tt.files[i], record, err)
+				}
+			}
+			if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != old.ObjectName {
+				t.Fatalf("objects = %v, want only %s", objects, old.ObjectName)
+			}
+		})
+	}
+}
+
+func TestBatchUploadAllOrNothingActivates(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	old := e.upload(t, "a.txt", "old a", "")
+
+	results, err := e.file.BatchUpload(ctx, batchFiles(t, "a.txt", "new a", "b.txt", "new b"), interfaces.ConflictOverwrite, true)
+	if err != nil {
+		t.Fatalf("BatchUpload() error = %v", err)
+	}
+	want := map[string]string{"a.txt": "new a", "b.txt": "new b"}
+	for _, result := range results {
+		if result.Err != nil || result.File == nil || result.File.Status != interfaces.FileStatusActive || e.content(t, result.File) != want[result.Filename] {
+			t.Fatalf("result of %s = %+v, want active file with %q", result.Filename, result, want[result.Filename])
+		}
+	}
+	if results[0].File.ID != old.ID {
+		t.Fatalf("a.txt ID = %s, want the replaced file's ID %s", results[0].File.ID, old.ID)
+	}
+	if objects := e.storage.objects(t); len(objects) != 2 {
+		t.Fatalf("objects = %v, want the two uploaded objects", objects)
+	}
+}
+
+// 非allOrNothing模式下文件各自上传，每个文件都有结果
+func TestBatchUploadPartial(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	e.upload(t, "exists.txt", "old", "")
+
+	results, err := e.file.BatchUpload(ctx, batchFiles(t,
+		"a.txt", "new a",
+		"bad.png", "not a png",
+		"exists.txt", "new",
+		longName, "too long",
+		"b.txt", "new b",
+	), interfaces.ConflictReject, false)
+	if err != nil || len(results) != 5 {
+		t.Fatalf("BatchUpload() = %d results, %v, want 5", len(results), err)
+	}
+	wantOK := map[string]bool{"a.txt": true, "b.txt": true}
+	for _, result := range results {
+		if result.RolledBack {
+			t.Fatalf("result of %s rolled back in partial mode", result.Filename)
+		}
+		if wantOK[result.Filename] {
+			if result.Err != nil || result.File == nil || result.File.Name != result.Filename {
+				t.Fatalf("result of %s = %+v, want uploaded", result.Filename, result)
+			}
+			continue
+		}
+		if result.Err == nil || result.File != nil {
+			t.Fatalf("result of %s = %+v, want error", result.Filename, result)
+		}
+	}
+	if _, total, err := e.file.GetList(ctx, 1, 100); err != nil || total != 3 {
+		t.Fatalf("GetList() total = %d, %v, want 3", total, err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 3 {
+		t.Fatalf("objects = %v, want 3", objects)
+	}
+}
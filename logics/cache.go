@@ -0,0 +1,84 @@
+package logics
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"strings"
+	"time"
+)
+
+// defaultMetaCacheControl 元数据可能随状态变化，默认每次使用前向服务端校验
+const defaultMetaCacheControl = "no-cache"
+
+func (l *LogicsFile) GetCacheInfo(ctx context.Context, file *interfaces.FileInfo) (*interfaces.CacheInfo, error) {
+	info := &interfaces.CacheInfo{
+		LastModified: file.UpdateTime,
+		CacheControl: cacheControlFor(file.BucketID, file.ContentType),
+	}
+	if file.SHA256 != "" {
+		info.ETag = `"` + file.SHA256 + `"`
+		return info, nil
+	}
+
+	// 旧版本上传的文件未计算摘要，使用存储的ETag，获取失败时不返回实体标签
+	storageInfo, err := l.storage.GetFileInfo(ctx, file.BucketID, file.ObjectName)
+	if err != nil {
+		log.Printf("[WARN] failed to get storage ETag of file %s: %v", file.ID, err)
+		return info, nil
+	}
+	if etag := strings.Trim(storageInfo.ETag, `"`); etag != "" {
+		info.ETag = `"` + etag + `"`
+	}
+	return info, nil
+}
+
+func (l *LogicsFile) GetMetaCacheInfo(ctx context.Context, file *interfaces.FileInfo) (*interfaces.CacheInfo, error) {
+	cacheControl := defaultMetaCacheControl
+	if c := config.CacheControl; c != nil && c.Meta != "" {
+		cacheControl = c.Meta
+	}
+
+	// 实体标签覆盖元数据接口返回的全部字段
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%d\n%s\n%s\n%s\n", file.ID, file.Name, file.ContentType, file.Size, file.Icon, file.Status, file.SHA256)
+	for _, t := range []*time.Time{file.CreateTime, file.UpdateTime} {
+		if t != nil {
+			fmt.Fprintf(h, "%d\n", t.Unix())
+		}
+	}
+
+	return &interfaces.CacheInfo{
+		ETag:         `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`,
+		LastModified: file.UpdateTime,
+		CacheControl: cacheControl,
+	}, nil
+}
+
+// cacheControlFor 按内容类型(精确匹配优先于通配)、桶、默认值的顺序取下载使用的Cache-Control
+func cacheControlFor(bucketID, contentType string) string {
+	c := config.CacheControl
+	if c == nil {
+		return ""
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if value, ok := c.ContentTypes[mediaType]; ok {
+			return value
+		}
+		for pattern, value := range c.ContentTypes {
+			if strings.HasSuffix(pattern, "/*") && mediaTypeMatches(pattern, mediaType) {
+				return value
+			}
+		}
+	}
+	if value, ok := c.Buckets[bucketID]; ok {
+		return value
+	}
+	return c.Default
+}
@@ -0,0 +1,176 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/dbaccess"
+	"FileEngine/drivenadapters"
+	"FileEngine/interfaces"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testBucketID = "test-bucket"
+
+func TestMain(m *testing.M) {
+	cfg := &common.Config{
+		Server: &common.ServerConfig{
+			UploadTimeout:   time.Hour,
+			DownloadTimeout: time.Hour,
+			PublicURL:       "http://127.0.0.1:9700",
+			SignKey:         "test-sign-key",
+		},
+		Storage: &common.StorageConfig{Driver: "memory", BucketID: testBucketID},
+	}
+	SetConfig(cfg)
+	drivenadapters.SetConfig(cfg)
+	SetAuditLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testEnv 使用内存数据库和内存存储的业务逻辑，各测试互不影响
+type testEnv struct {
+	dbFile            interfaces.DBFile
+	dbTusUpload       interfaces.DBTusUpload
+	dbMultipartUpload interfaces.DBMultipartUpload
+	storage           *recordingStorage
+	file              *LogicsFile
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	storage, err := drivenadapters.NewMemoryAdapter(config.Storage)
+	if err != nil {
+		t.Fatalf("NewMemoryAdapter() error = %v", err)
+	}
+
+	e := &testEnv{
+		dbFile:            dbaccess.NewMemoryDBFile(),
+		dbTusUpload:       dbaccess.NewMemoryDBTusUpload(),
+		dbMultipartUpload: dbaccess.NewMemoryDBMultipartUpload(),
+		storage:           &recordingStorage{StorageAdapter: storage, written: make(map[string]bool)},
+	}
+	loadUploadPolicies()
+	e.file = &LogicsFile{
+		uploadTimeout:   time.Hour,
+		downloadTimeout: time.Hour,
+		defaultBucketID: testBucketID,
+		dbFile:          e.dbFile,
+		storage:         e.storage,
+	}
+	return e
+}
+
+func (e *testEnv) tus() *LogicsTus {
+	return &LogicsTus{
+		defaultBucketID: testBucketID,
+		expiration:      time.Hour,
+		dbFile:          e.dbFile,
+		dbTusUpload:     e.dbTusUpload,
+		storage:         e.storage,
+		logicsFile:      e.file,
+	}
+}
+
+func (e *testEnv) multipart() *LogicsMultipart {
+	return &LogicsMultipart{
+		uploadTimeout:     time.Hour,
+		defaultBucketID:   testBucketID,
+		dbFile:            e.dbFile,
+		dbMultipartUpload: e.dbMultipartUpload,
+		storage:           e.storage,
+		logicsFile:        e.file,
+	}
+}
+
+// upload 以reader方式上传文本文件
+func (e *testEnv) upload(t *testing.T, name, content, conflict string) *interfaces.FileInfo {
+	t.Helper()
+	file, err := e.file.UploadFromReader(context.Background(), name, "text/plain", int64(len(content)), "", conflict, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("UploadFromReader(%s) error = %v", name, err)
+	}
+	return file
+}
+
+// content 读取文件的内容
+func (e *testEnv) content(t *testing.T, file *interfaces.FileInfo) string {
+	t.Helper()
+	reader, err := e.storage.Download(context.Background(), file.BucketID, file.ObjectName)
+	if err != nil {
+		t.Fatalf("Download(%s) error = %v", file.ObjectName, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read %s error = %v", file.ObjectName, err)
+	}
+	return string(data)
+}
+
+// recordingStorage 记录写入过的对象，用于检查失败或删除后是否有残留的对象
+type recordingStorage struct {
+	interfaces.StorageAdapter
+	mu      sync.Mutex
+	written map[string]bool
+}
+
+func (s *recordingStorage) Upload(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) error {
+	if err := s.StorageAdapter.Upload(ctx, bucketID, objectName, reader, size, contentType); err != nil {
+		return err
+	}
+	s.record(objectName)
+	return nil
+}
+
+func (s *recordingStorage) CompleteMultipartUpload(ctx context.Context, bucketID, objectName, uploadID string, parts []*interfaces.StoragePart) error {
+	if err := s.StorageAdapter.CompleteMultipartUpload(ctx, bucketID, objectName, uploadID, parts); err != nil {
+		return err
+	}
+	s.record(objectName)
+	return nil
+}
+
+func (s *recordingStorage) record(objectName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written[objectName] = true
+}
+
+// objects 返回仍存在的对象名，按名称排序
+func (s *recordingStorage) objects(t *testing.T) []string {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name := range s.written {
+		exists, err := s.FileExists(context.Background(), testBucketID, name)
+		if err != nil {
+			t.Fatalf("FileExists(%s) error = %v", name, err)
+		}
+		if exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// httpStatus 返回业务错误的HTTP状态码，不是HTTPError时返回0
+func httpStatus(err error) int {
+	var httpErr *common.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return 0
+}
@@ -0,0 +1,190 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// rename模式下"name (n).ext"形式的最大序号，仍冲突时使用带时间戳和随机串的名称
+	maxRenameSeq = 99
+	// overwrite模式下同名记录被并发替换时的最大重试次数
+	maxReplaceAttempts = 5
+)
+
+// parseConflict 校验同名文件的处理方式，为空时默认拒绝
+func parseConflict(conflict string) (string, error) {
+	switch conflict {
+	case "":
+		return interfaces.ConflictReject, nil
+	case interfaces.ConflictReject, interfaces.ConflictOverwrite, interfaces.ConflictRename:
+		return conflict, nil
+	default:
+		return "", common.NewHTTPError(http.StatusBadRequest, "Invalid conflict mode", []map[string]interface{}{
+			{"error": "Invalid conflict mode", "message": fmt.Sprintf("conflict must be one of reject, overwrite, rename, got %s", conflict)},
+		})
+	}
+}
+
+// renameCandidate 第seq次重命名使用的文件名，如"a (1).txt"
+func renameCandidate(filename string, seq int) string {
+	ext := filepath.Ext(filename)
+	if seq > maxRenameSeq {
+		// 组合为: 原文件名_时间戳_随机字符串.扩展名
+		return fmt.Sprintf("%s_%s_%s%s", strings.TrimSuffix(filename, ext), time.Now().Format("20060102150405"), uuid.New().String()[:8], ext)
+	}
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(filename, ext), seq, ext)
+}
+
+// targetName 返回文件最终使用的名称，overwrite模式下待确认的记录以临时名称保存
+func targetName(fileInfo *interfaces.FileInfo) string {
+	if fileInfo.TargetName != "" {
+		return fileInfo.TargetName
+	}
+	return fileInfo.Name
+}
+
+func fileExistsError(filename string) error {
+	return common.NewHTTPError(http.StatusBadRequest, "File with name already exists", []map[string]interface{}{
+		{"error": "File with name already exists", "message": fmt.Sprintf("file with name %s already exists", filename)},
+	})
+}
+
+// createFile 按冲突处理方式创建文件记录，名称是否冲突以数据库唯一索引为准，多实例并发时不会重复。
+// rename模式下fileInfo.Name为最终使用的名称；overwrite模式下记录以文件ID为临时名称、以TargetName记录目标名称，
+// 由activateFile替换同名文件
+func (l *LogicsFile) createFile(ctx context.Context, fileInfo *interfaces.FileInfo, conflict string) error {
+	filename := fileInfo.Name
+	if conflict == interfaces.ConflictOverwrite {
+		fileInfo.Name = fileInfo.ID
+		fileInfo.TargetName = filename
+		fileInfo.Status = interfaces.FileStatusPending
+	}
+	if fileInfo.Status == interfaces.FileStatusPending {
+		// 过期时间以Unix秒保存，不依赖数据库时间列的时区
+		fileInfo.ExpireAt = time.Now().Add(l.uploadTimeout).Unix()
+	}
+
+	for seq := 0; ; seq++ {
+		if seq > 0 {
+			// 重命名后的名称仍需符合上传策略，如不超过文件名最大字节数
+			candidate, err := uploadPolicyFor(fileInfo.BucketID).checkFilename(renameCandidate(filename, seq))
+			if err != nil {
+				return fileExistsError(filename)
+			}
+			fileInfo.Name = candidate
+		}
+
+		err := l.dbFile.CreateFile(ctx, fileInfo)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, interfaces.ErrDuplicateEntry) {
+			return common.NewHTTPError(http.StatusInternalServerError, "Failed to create file record", []map[string]interface{}{
+				{"error": "Failed to create file record", "message": err.Error()},
+			})
+		}
+		if conflict != interfaces.ConflictRename || seq > maxRenameSeq {
+			return fileExistsError(filename)
+		}
+	}
+}
+
+// activateFile 将overwrite模式创建的记录置为可用。同名文件可用时在同一事务中将其内容替换为新上传的对象和元数据，
+// 文件ID保持不变，fileInfo.ID改为该文件的ID；同名记录为待上传或上传失败的记录时删除该记录并将新记录改为目标名称。
+// 之后释放被替换的对象
+func (l *LogicsFile) activateFile(ctx context.Context, fileInfo *interfaces.FileInfo) error {
+	for attempt := 0; attempt < maxReplaceAttempts; attempt++ {
+		replaced, err := l.dbFile.GetFileByName(ctx, fileInfo.TargetName)
+		if err != nil {
+			return common.NewHTTPError(http.StatusInternalServerError, "Failed to get file by name", []map[string]interface{}{
+				{"error": "Failed to get file by name", "message": err.Error()},
+			})
+		}
+
+		if replaced != nil && replaced.Status == interfaces.FileStatusActive {
+			referenced, err := l.dbFile.ReplaceFile(ctx, fileInfo.ID, replaced)
+			if errors.Is(err, interfaces.ErrDuplicateEntry) {
+				// 同名文件在读取后被其他请求替换或删除，重新读取后重试
+				continue
+			}
+			if err != nil {
+				return common.NewHTTPError(http.StatusInternalServerError, "Failed to replace file", []map[string]interface{}{
+					{"error": "Failed to replace file", "message": err.Error()},
+				})
+			}
+
+			log.Printf("[INFO] upload %s replaced content of file %s with name %s", fileInfo.ID, replaced.ID, fileInfo.TargetName)
+			if !referenced {
+				if err = l.storage.Delete(context.WithoutCancel(ctx), replaced.BucketID, replaced.ObjectName); err != nil {
+					log.Printf("[WARN] failed to delete object %s of replaced file %s: %v", replaced.ObjectName, replaced.ID, err)
+				}
+			}
+			fileInfo.ID = replaced.ID
+			fileInfo.Name = fileInfo.TargetName
+			fileInfo.TargetName = ""
+			fileInfo.Status = interfaces.FileStatusActive
+			return nil
+		}
+
+		replacedID := ""
+		if replaced != nil {
+			replacedID = replaced.ID
+		}
+
+		err = l.dbFile.ActivateFile(ctx, fileInfo.ID, fileInfo.TargetName, replacedID)
+		if errors.Is(err, interfaces.ErrDuplicateEntry) {
+			// 同名文件在读取后被其他请求替换或创建，重新读取后重试
+			continue
+		}
+		if err != nil {
+			return common.NewHTTPError(http.StatusInternalServerError, "Failed to update file status", []map[string]interface{}{
+				{"error": "Failed to update file status", "message": err.Error()},
+			})
+		}
+
+		if replaced != nil {
+			log.Printf("[INFO] file %s replaced %s file %s with name %s", fileInfo.ID, replaced.Status, replaced.ID, fileInfo.TargetName)
+			// 被替换的记录已在事务中删除，这里只释放对象
+			if err = l.releaseFile(context.WithoutCancel(ctx), replaced); err != nil {
+				log.Printf("[WARN] failed to release object %s of replaced file %s: %v", replaced.ObjectName, replaced.ID, err)
+			}
+		}
+		fileInfo.Name = fileInfo.TargetName
+		fileInfo.TargetName = ""
+		fileInfo.Status = interfaces.FileStatusActive
+		return nil
+	}
+
+	return common.NewHTTPError(http.StatusConflict, "File is being replaced concurrently", []map[string]interface{}{
+		{"error": "File is being replaced concurrently", "message": fmt.Sprintf("file with name %s is being replaced by other uploads, please retry", fileInfo.TargetName)},
+	})
+}
+
+// activatedFile 重新读取activateFile置为可用的记录，获取数据库生成的创建时间和更新时间。
+// 记录可能已被并发删除，此时本次上传仍已成功，返回已写入的信息
+func (l *LogicsFile) activatedFile(ctx context.Context, fileInfo *interfaces.FileInfo) (*interfaces.FileInfo, error) {
+	current, err := l.dbFile.GetFileByID(ctx, fileInfo.ID)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusNotFound, "File not found", []map[string]interface{}{
+			{"error": "File not found", "message": err.Error()},
+		})
+	}
+	if current == nil {
+		// 记录已被删除，创建时间和更新时间以置为可用的时间为准
+		now := time.Now().Truncate(time.Second)
+		fileInfo.CreateTime, fileInfo.UpdateTime = &now, &now
+		return fileInfo, nil
+	}
+	return current, nil
+}
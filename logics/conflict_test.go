@@ -0,0 +1,158 @@
+package logics
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRenameCandidate(t *testing.T) {
+	tests := []struct {
+		filename string
+		seq      int
+		want     string
+	}{
+		{"a.txt", 1, "a (1).txt"},
+		{"a.tar.gz", 2, "a.tar (2).gz"},
+		{"README", 3, "README (3)"},
+		{"a.txt", maxRenameSeq, "a (99).txt"},
+		{"a.txt", maxRenameSeq + 1, `^a_\d{14}_[0-9a-f]{8}\.txt$`},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d", tt.filename, tt.seq), func(t *testing.T) {
+			got := renameCandidate(tt.filename, tt.seq)
+			if tt.seq > maxRenameSeq {
+				if !regexp.MustCompile(tt.want).MatchString(got) {
+					t.Fatalf("renameCandidate() = %s, want match %s", got, tt.want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Fatalf("renameCandidate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// "a (1).txt"到"a (99).txt"均已存在时，使用带时间戳和随机串的名称
+func TestRenameFallsBackAfterMaxSeq(t *testing.T) {
+	e := newTestEnv(t)
+	e.upload(t, "a.txt", "hello", "")
+	for seq := 1; seq <= maxRenameSeq; seq++ {
+		e.upload(t, renameCandidate("a.txt", seq), "hello", "")
+	}
+
+	file := e.upload(t, "a.txt", "world", interfaces.ConflictRename)
+	if !regexp.MustCompile(`^a_\d{14}_[0-9a-f]{8}\.txt$`).MatchString(file.Name) {
+		t.Fatalf("renamed file name = %s, want a_<timestamp>_<random>.txt", file.Name)
+	}
+	if e.content(t, file) != "world" {
+		t.Fatalf("renamed file content = %q, want %q", e.content(t, file), "world")
+	}
+}
+
+// 覆盖同名文件时保留原文件的ID，原对象被删除
+func TestOverwriteKeepsFileID(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	old := e.upload(t, "a.txt", "hello", "")
+
+	file := e.upload(t, "a.txt", "world", interfaces.ConflictOverwrite)
+	if file.ID != old.ID || file.Name != "a.txt" || file.ObjectName == old.ObjectName {
+		t.Fatalf("overwrite = %+v, want file %s with a new object", file, old.ID)
+	}
+	current, err := e.file.GetMeta(ctx, old.ID)
+	if err != nil || e.content(t, current) != "world" {
+		t.Fatalf("GetMeta() = %+v, %v, want the new content", current, err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != file.ObjectName {
+		t.Fatalf("objects = %v, want only %s", objects, file.ObjectName)
+	}
+}
+
+// 并发覆盖同一文件，最终只有一条记录和一个对象，内容为某次成功的覆盖
+func TestOverwriteRacesOverwrite(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	old := e.upload(t, "a.txt", "hello", "")
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			content := fmt.Sprintf("content %d", i)
+			_, errs[i] = e.file.UploadFromReader(ctx, "a.txt", "text/plain", int64(len(content)), "", interfaces.ConflictOverwrite, strings.NewReader(content))
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := make(map[string]bool)
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded[fmt.Sprintf("content %d", i)] = true
+		case httpStatus(err) != http.StatusConflict:
+			t.Fatalf("overwrite %d error = %v, want nil or 409", i, err)
+		}
+	}
+	if len(succeeded) == 0 {
+		t.Fatal("no overwrite succeeded")
+	}
+
+	files, total, err := e.file.GetList(ctx, 1, 100)
+	if err != nil || total != 1 || files[0].ID != old.ID {
+		t.Fatalf("GetList() = %d files, %v, want only file %s", total, err, old.ID)
+	}
+	if content := e.content(t, files[0]); !succeeded[content] {
+		t.Fatalf("content = %q, want one of the successful overwrites", content)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != files[0].ObjectName {
+		t.Fatalf("objects = %v, want only %s", objects, files[0].ObjectName)
+	}
+}
+
+func TestOverwriteThroughTus(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	tus := e.tus()
+	old := e.upload(t, "a.txt", "hello", "")
+
+	upload, err := tus.CreateUpload(ctx, "a.txt", "text/plain", 5, interfaces.ConflictOverwrite, "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	completed, err := tus.AppendUpload(ctx, upload.ID, 0, 5, strings.NewReader("world"), nil)
+	if err != nil || completed.FileID != old.ID {
+		t.Fatalf("AppendUpload() = %+v, %v, want file %s", completed, err, old.ID)
+	}
+	file, err := e.file.GetMeta(ctx, old.ID)
+	if err != nil || file.Name != "a.txt" || e.content(t, file) != "world" {
+		t.Fatalf("GetMeta() = %+v, %v, want a.txt with the new content", file, err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != file.ObjectName {
+		t.Fatalf("objects = %v, want only %s", objects, file.ObjectName)
+	}
+}
+
+func TestOverwriteThroughMultipart(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	old := e.upload(t, "a.txt", "hello", "")
+
+	upload, content := initiateMultipart(t, e, "a.txt", interfaces.ConflictOverwrite)
+	file, err := e.multipart().CompleteUpload(ctx, upload.ID, nil, "")
+	if err != nil || file.ID != old.ID || file.Name != "a.txt" || e.content(t, file) != string(content) {
+		t.Fatalf("CompleteUpload() = %+v, %v, want file %s with the uploaded content", file, err, old.ID)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != file.ObjectName {
+		t.Fatalf("objects = %v, want only %s", objects, file.ObjectName)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"FileEngine/interfaces"
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -32,10 +34,12 @@ var (
 
 func NewLogicsFile() interfaces.LogicsFile {
 	logicsFileOnce.Do(func() {
+		// 启动时编译上传策略，配置有误时尽早失败
+		loadUploadPolicies()
 		logicsFile = &LogicsFile{
 			uploadTimeout:   config.Server.UploadTimeout,
 			downloadTimeout: config.Server.DownloadTimeout,
-			defaultBucketID: config.Minio.BucketID,
+			defaultBucketID: config.DefaultBucketID(),
 			dbFile:          dbFile,
 			storage:         storageAdapter,
 		}
@@ -46,26 +50,11 @@ func NewLogicsFile() interfaces.LogicsFile {
 func (l *LogicsFile) Upload(ctx context.Context, file *multipart.FileHeader) (fileInfo *interfaces.FileInfo, err error) {
 	log.Printf("[DEBUG] file header: %+v", file.Header)
 	// 文件校验
-	if err = l.validateFile(file); err != nil {
-		return
-	}
-
-	// 2. 生成唯一文件名
-	originalName := filepath.Base(file.Filename)
-
-	// 检查文件是否已存在
-	existingFile, err := l.dbFile.GetFileByName(ctx, originalName)
-	if err == nil && existingFile != nil {
-		err = common.NewHTTPError(http.StatusBadRequest, "File with name already exists", []map[string]interface{}{
-			{
-				"error":   "File with name already exists",
-				"message": fmt.Sprintf("file with name %s already exists", originalName),
-			},
-		})
+	filename, err := l.validateFile(file)
+	if err != nil {
 		return
 	}
 
-	// 上传到MinIO
 	// 打开文件
 	src, err := file.Open()
 	if err != nil {
@@ -79,19 +68,73 @@ func (l *LogicsFile) Upload(ctx context.Context, file *multipart.FileHeader) (fi
 	}
 	defer src.Close()
 
-	// 获取文件大小
-	fileSize := file.Size
-	// 获取Content-Type
-	contentType := interfaces.GetContentType(file)
-	// 上传到存储
-	err = l.storage.Upload(ctx, l.defaultBucketID, originalName, src, fileSize, contentType)
+	return l.upload(ctx, filepath.Base(filename), interfaces.GetContentType(file), file.Size, "", interfaces.ConflictReject, src)
+}
+
+func (l *LogicsFile) UploadFromReader(ctx context.Context, filename, contentType string, size int64, sha256, conflict string, reader io.Reader) (fileInfo *interfaces.FileInfo, err error) {
+	// 文件校验
+	if filename, err = l.validateUpload(filename, size); err != nil {
+		return
+	}
+	if conflict, err = parseConflict(conflict); err != nil {
+		return
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return l.upload(ctx, filepath.Base(filename), contentType, size, sha256, conflict, reader)
+}
+
+// 上传到存储并创建文件记录，调用前需完成文件校验，fileSize为-1表示长度未知，expectedSHA256不为空时校验摘要，conflict为同名文件的处理方式
+func (l *LogicsFile) upload(ctx context.Context, originalName, contentType string, fileSize int64, expectedSHA256, conflict string, src io.Reader) (*interfaces.FileInfo, error) {
+	fileInfo, err := l.store(ctx, originalName, contentType, fileSize, expectedSHA256, conflict, false, src)
 	if err != nil {
-		err = common.NewHTTPError(http.StatusInternalServerError, "Failed to upload file to storage", []map[string]interface{}{
-			{
-				"error":   "Failed to upload file to storage",
-				"message": err.Error(),
-			},
-		})
+		return nil, err
+	}
+	return l.finishUpload(ctx, fileInfo)
+}
+
+// store 上传到存储并创建文件记录，overwrite模式下记录处于待确认状态，由finishUpload替换同名文件。
+// pending为true时其他模式的记录也以最终名称、待上传状态创建，由调用方统一置为可用
+func (l *LogicsFile) store(ctx context.Context, originalName, contentType string, fileSize int64, expectedSHA256, conflict string, pending bool, src io.Reader) (fileInfo *interfaces.FileInfo, err error) {
+	// 检查文件是否已存在，仅用于尽早拒绝，是否冲突以创建记录时的唯一索引为准
+	if err = l.checkNameAvailable(ctx, originalName, conflict); err != nil {
+		return
+	}
+
+	// 边读边上传到存储，同时完成大小校验、哈希计算和类型嗅探。对象名唯一，避免覆盖被其他文件引用的对象
+	policy := l.uploadPolicy()
+	objectName := newObjectKey()
+	stream := newUploadStream(src, policy.sizeLimit(MaxFileSize))
+
+	// 开头的数据在写入存储前已完成嗅探，内容与扩展名不符或类型不在策略允许范围内时直接拒绝，记录的类型以嗅探结果为准
+	err = policy.checkContentType(originalName, stream.detected)
+	auditContentType(originalName, contentType, stream.detected, err)
+	if err != nil {
+		return
+	}
+	contentType = stream.ContentType(contentType)
+
+	err = l.storage.Upload(ctx, l.defaultBucketID, objectName, stream, fileSize, contentType)
+	if err != nil {
+		err = stream.Error(err, fileSize)
+		return
+	}
+	if fileSize < 0 {
+		// 长度未知时上传完成后才能校验最小文件大小
+		fileSize = stream.size
+		if err = policy.checkSize(fileSize, MaxFileSize); err != nil {
+			l.storage.Delete(ctx, l.defaultBucketID, objectName)
+			return
+		}
+	}
+
+	// 摘要不一致时回滚已上传的对象
+	sha256 := stream.SHA256()
+	if err = checkSHA256(expectedSHA256, sha256); err != nil {
+		l.storage.Delete(ctx, l.defaultBucketID, objectName)
 		return
 	}
 
@@ -100,82 +143,207 @@ func (l *LogicsFile) Upload(ctx context.Context, file *multipart.FileHeader) (fi
 		ID:          uuid.New().String(),
 		Name:        originalName,
 		BucketID:    l.defaultBucketID,
+		ObjectName:  objectName,
 		Icon:        "",
 		Size:        fileSize,
 		ContentType: contentType,
+		Status:      interfaces.FileStatusActive,
+		SHA256:      sha256,
+	}
+	if pending {
+		fileInfo.Status = interfaces.FileStatusPending
 	}
 
-	err = l.dbFile.CreateFile(ctx, fileInfo)
-	if err != nil {
+	if err = l.createFile(ctx, fileInfo, conflict); err != nil {
 		// 如果数据库插入失败，需要从存储中删除已上传的文件
-		l.storage.Delete(ctx, l.defaultBucketID, originalName)
-		err = common.NewHTTPError(http.StatusInternalServerError, "Failed to create file record", []map[string]interface{}{
-			{
-				"error":   "Failed to create file record",
-				"message": err.Error(),
-			},
-		})
-		return
+		l.storage.Delete(ctx, l.defaultBucketID, objectName)
+		return nil, err
 	}
 
 	return fileInfo, nil
 }
 
+// finishUpload 完成store创建的文件：overwrite模式下替换同名文件并置为可用，失败时删除记录和对象
+func (l *LogicsFile) finishUpload(ctx context.Context, fileInfo *interfaces.FileInfo) (*interfaces.FileInfo, error) {
+	if fileInfo.TargetName != "" {
+		if err := l.activateFile(ctx, fileInfo); err != nil {
+			l.dbFile.DeleteFile(ctx, fileInfo.ID)
+			l.storage.Delete(ctx, fileInfo.BucketID, fileInfo.ObjectName)
+			return nil, err
+		}
+		return l.activatedFile(ctx, fileInfo)
+	}
+
+	// 重新读取记录，获取数据库生成的创建时间和更新时间
+	return l.getFile(ctx, fileInfo.ID)
+}
+
 func (l *LogicsFile) Download(ctx context.Context, fileID string) (fileDownloadInfo *interfaces.FileDownload, err error) {
-	// 从数据库获取文件信息
-	fileInfo, err := l.dbFile.GetFileByID(ctx, fileID)
+	fileInfo, err := l.GetDownloadFile(ctx, fileID)
 	if err != nil {
-		err = common.NewHTTPError(http.StatusNotFound, "File not found", []map[string]interface{}{
+		return
+	}
+
+	fileReaderCloser, err := l.storage.Download(ctx, fileInfo.BucketID, fileInfo.ObjectName)
+	if err != nil {
+		err = common.NewHTTPError(http.StatusInternalServerError, "Failed to download file", []map[string]interface{}{
 			{
-				"error":   "File not found",
+				"error":   "Failed to download file",
 				"message": err.Error(),
 			},
 		})
 		return
 	}
 
+	fileDownloadInfo = &interfaces.FileDownload{
+		File:   fileInfo,
+		Reader: fileReaderCloser,
+	}
+	return
+}
+
+func (l *LogicsFile) GetDownloadFile(ctx context.Context, fileID string) (*interfaces.FileInfo, error) {
+	// 从数据库获取文件信息
+	fileInfo, err := l.getActiveFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查存储中文件是否存在
-	exists, err := l.storage.FileExists(ctx, fileInfo.BucketID, fileInfo.Name)
+	exists, err := l.storage.FileExists(ctx, fileInfo.BucketID, fileInfo.ObjectName)
 	if err != nil {
-		err = common.NewHTTPError(http.StatusInternalServerError, "Failed to check file existence", []map[string]interface{}{
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to check file existence", []map[string]interface{}{
 			{
 				"error":   "Failed to check file existence",
 				"message": err.Error(),
 			},
 		})
-		return
 	}
 	if !exists {
-		err = common.NewHTTPError(http.StatusNotFound, "File not found in storage", nil)
+		return nil, common.NewHTTPError(http.StatusNotFound, "File not found in storage", nil)
+	}
+
+	return fileInfo, nil
+}
+
+func (l *LogicsFile) DownloadRange(ctx context.Context, file *interfaces.FileInfo, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length < 0 || offset+length > file.Size {
+		return nil, common.NewHTTPError(http.StatusRequestedRangeNotSatisfiable, "Range not satisfiable", []map[string]interface{}{
+			{"error": "Range not satisfiable", "message": fmt.Sprintf("range %d-%d is out of file size %d", offset, offset+length-1, file.Size)},
+		})
 	}
 
-	fileReaderCloser, err := l.storage.Download(ctx, fileInfo.BucketID, fileInfo.Name)
+	// 读取整个文件时不使用范围请求
+	var reader io.ReadCloser
+	var err error
+	if offset == 0 && length == file.Size {
+		reader, err = l.storage.Download(ctx, file.BucketID, file.ObjectName)
+	} else {
+		reader, err = l.storage.DownloadRange(ctx, file.BucketID, file.ObjectName, offset, length)
+	}
 	if err != nil {
-		err = common.NewHTTPError(http.StatusInternalServerError, "Failed to download file", []map[string]interface{}{
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to download file", []map[string]interface{}{
 			{
 				"error":   "Failed to download file",
 				"message": err.Error(),
 			},
 		})
-		return
 	}
 
-	fileDownloadInfo = &interfaces.FileDownload{
-		File:   fileInfo,
-		Reader: fileReaderCloser,
+	return reader, nil
+}
+
+// 秒传预检查：存在内容相同的可用文件时创建引用同一对象的文件记录，不传输数据
+func (l *LogicsFile) PrecheckUpload(ctx context.Context, filename, sha256 string, size int64, conflict string) (*interfaces.FileInfo, error) {
+	// 文件校验
+	filename, err := l.validateUpload(filename, size)
+	if err != nil {
+		return nil, err
 	}
-	return
+	if conflict, err = parseConflict(conflict); err != nil {
+		return nil, err
+	}
+	if err = l.checkNameAvailable(ctx, filename, conflict); err != nil {
+		return nil, err
+	}
+
+	source, err := l.dbFile.GetFileByContent(ctx, l.defaultBucketID, sha256, size)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to query file by content", []map[string]interface{}{
+			{"error": "Failed to query file by content", "message": err.Error()},
+		})
+	}
+	if source == nil {
+		return nil, nil
+	}
+
+	// 内容与已有文件相同，按其嗅探到的类型校验新文件名的扩展名和策略允许的类型
+	detected := lookupContentType(source.ContentType)
+	err = l.uploadPolicy().checkContentType(filename, detected)
+	auditContentType(filename, source.ContentType, detected, err)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo := &interfaces.FileInfo{
+		ID:          uuid.New().String(),
+		Name:        filename,
+		BucketID:    source.BucketID,
+		ObjectName:  source.ObjectName,
+		Icon:        "",
+		Size:        source.Size,
+		ContentType: source.ContentType,
+		Status:      interfaces.FileStatusActive,
+		SHA256:      source.SHA256,
+	}
+	if err = l.createFile(ctx, fileInfo, conflict); err != nil {
+		return nil, err
+	}
+
+	// 源文件可能已被并发删除，记录创建后加锁确认对象仍被其他记录引用才算引用成功，
+	// 否则撤销记录并按最后一个引用释放对象，由客户端正常上传。
+	// 删除方在同一事务中删除记录并检查引用，两者之一必然看到对方，对象不会在被引用时删除。
+	// overwrite模式下此时尚未替换同名文件，撤销不影响原文件
+	referenced, err := l.dbFile.HasObjectReference(ctx, fileInfo.BucketID, fileInfo.ObjectName, fileInfo.ID)
+	if err != nil || !referenced {
+		if releaseErr := l.releaseFile(context.WithoutCancel(ctx), fileInfo); releaseErr != nil {
+			log.Printf("[WARN] failed to release instant upload file %s: %v", fileInfo.ID, releaseErr)
+		}
+		if err != nil {
+			return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to check object references", []map[string]interface{}{
+				{"error": "Failed to check object references", "message": err.Error()},
+			})
+		}
+		return nil, nil
+	}
+	if conflict == interfaces.ConflictOverwrite {
+		if err = l.activateFile(ctx, fileInfo); err != nil {
+			l.releaseFile(context.WithoutCancel(ctx), fileInfo)
+			return nil, err
+		}
+		return l.activatedFile(ctx, fileInfo)
+	}
+
+	return l.getFile(ctx, fileInfo.ID)
 }
 
 // 生成预签名上传URL
-func (l *LogicsFile) GenerateUploadURL(ctx context.Context, filename string, contentType string, size int64) (*interfaces.UploadURL, error) {
+func (l *LogicsFile) GenerateUploadURL(ctx context.Context, filename string, contentType string, size int64, conflict string) (*interfaces.UploadURL, error) {
 	// 文件校验
-	if err := l.validateFileInfo(filename, contentType, size); err != nil {
+	filename, err := l.validateFileInfo(filename, contentType, size)
+	if err != nil {
+		return nil, err
+	}
+	if conflict, err = parseConflict(conflict); err != nil {
+		return nil, err
+	}
+	if err = l.checkNameAvailable(ctx, filename, conflict); err != nil {
 		return nil, err
 	}
 
 	// 生成预签名上传URL
-	presignedURL, err := l.storage.GeneratePresignedUploadURL(ctx, l.defaultBucketID, filename, l.uploadTimeout)
+	objectName := newObjectKey()
+	presignedURL, err := l.storage.GeneratePresignedUploadURL(ctx, l.defaultBucketID, objectName, l.uploadTimeout)
 	if err != nil {
 		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to generate upload URL", []map[string]interface{}{
 			{"error": "Failed to generate upload URL", "message": err.Error()},
@@ -189,38 +357,193 @@ func (l *LogicsFile) GenerateUploadURL(ctx context.Context, filename string, con
 	}
 
 	// 创建数据库记录
-	fileInfo := &interfaces.FileInfo{
-		ID:          uuid.New().String(),
-		Name:        filename,
-		BucketID:    l.defaultBucketID,
-		Icon:        "",
-		Size:        size,
-		ContentType: contentType,
+	fileInfo, err := l.createPendingFile(ctx, filename, objectName, contentType, size, conflict)
+	if err != nil {
+		return nil, err
 	}
-	err = l.dbFile.CreateFile(ctx, fileInfo)
+
+	// 计算过期时间
+	expiresAt := time.Now().Add(l.uploadTimeout)
+	expiresIn := int64(l.uploadTimeout.Seconds())
+
+	return &interfaces.UploadURL{
+		ID:        fileInfo.ID,
+		Name:      targetName(fileInfo),
+		Method:    http.MethodPut,
+		URL:       presignedURL,
+		ExpiresAt: expiresAt,
+		ExpiresIn: expiresIn,
+	}, nil
+}
+
+// 生成预签名POST策略，策略绑定对象名、Content-Type和文件大小，不符合的上传由存储直接拒绝
+func (l *LogicsFile) GenerateUploadPolicy(ctx context.Context, filename string, contentType string, size int64, conflict string) (*interfaces.UploadURL, error) {
+	// 文件校验
+	filename, err := l.validateFileInfo(filename, contentType, size)
 	if err != nil {
-		if strings.Contains(err.Error(), "Duplicate entry") {
-			return nil, common.NewHTTPError(http.StatusBadRequest, "File with name already exists", []map[string]interface{}{
-				{"error": "File with name already exists", "message": fmt.Sprintf("file with name %s already exists", filename)},
-			})
-		}
-		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to create file record", []map[string]interface{}{
-			{"error": "Failed to create file record", "message": err.Error()},
+		return nil, err
+	}
+	if conflict, err = parseConflict(conflict); err != nil {
+		return nil, err
+	}
+	if err = l.checkNameAvailable(ctx, filename, conflict); err != nil {
+		return nil, err
+	}
+
+	// 策略必须绑定Content-Type
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	objectName := newObjectKey()
+	presignedPost, err := l.storage.GeneratePresignedPostPolicy(ctx, l.defaultBucketID, objectName, &interfaces.UploadConditions{
+		ContentType: contentType,
+		MinSize:     size,
+		MaxSize:     size,
+	}, l.uploadTimeout)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to generate upload policy", []map[string]interface{}{
+			{"error": "Failed to generate upload policy", "message": err.Error()},
 		})
 	}
 
+	// 创建数据库记录
+	fileInfo, err := l.createPendingFile(ctx, filename, objectName, contentType, size, conflict)
+	if err != nil {
+		return nil, err
+	}
+
 	// 计算过期时间
 	expiresAt := time.Now().Add(l.uploadTimeout)
 	expiresIn := int64(l.uploadTimeout.Seconds())
 
 	return &interfaces.UploadURL{
 		ID:        fileInfo.ID,
-		URL:       presignedURL,
+		Name:      targetName(fileInfo),
+		Method:    http.MethodPost,
+		URL:       presignedPost.URL,
+		FormData:  presignedPost.FormData,
 		ExpiresAt: expiresAt,
 		ExpiresIn: expiresIn,
 	}, nil
 }
 
+// 创建待上传的文件记录，客户端上传后需调用CompleteUpload确认
+func (l *LogicsFile) createPendingFile(ctx context.Context, filename, objectName, contentType string, size int64, conflict string) (*interfaces.FileInfo, error) {
+	fileInfo := &interfaces.FileInfo{
+		ID:          uuid.New().String(),
+		Name:        filename,
+		BucketID:    l.defaultBucketID,
+		ObjectName:  objectName,
+		Icon:        "",
+		Size:        size,
+		ContentType: contentType,
+		Status:      interfaces.FileStatusPending,
+	}
+	// rename模式在此时确定文件名并占用，overwrite模式在确认上传时替换同名文件
+	if err := l.createFile(ctx, fileInfo, conflict); err != nil {
+		return nil, err
+	}
+
+	return fileInfo, nil
+}
+
+// 确认预签名上传完成：校验存储中对象的大小、类型、ETag、SHA-256以及内容与扩展名是否一致，通过后将文件置为可用，失败时删除对象并置为失败
+func (l *LogicsFile) CompleteUpload(ctx context.Context, fileID, etag, sha256 string) (*interfaces.FileInfo, error) {
+	fileInfo, err := l.getFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fileInfo.Status {
+	case interfaces.FileStatusActive:
+		return fileInfo, nil
+	case interfaces.FileStatusFailed:
+		return nil, common.NewHTTPError(http.StatusConflict, "File upload has failed", []map[string]interface{}{
+			{"error": "File upload has failed", "message": fmt.Sprintf("file %s failed verification, please upload again", fileID)},
+		})
+	}
+
+	exists, err := l.storage.FileExists(ctx, fileInfo.BucketID, fileInfo.ObjectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to check file existence", []map[string]interface{}{
+			{"error": "Failed to check file existence", "message": err.Error()},
+		})
+	}
+	if !exists {
+		// 客户端可能尚未上传完成，保持待上传状态
+		return nil, common.NewHTTPError(http.StatusConflict, "File has not been uploaded", []map[string]interface{}{
+			{"error": "File has not been uploaded", "message": fmt.Sprintf("object %s not found in storage", fileInfo.ObjectName)},
+		})
+	}
+
+	objectInfo, err := l.storage.GetFileInfo(ctx, fileInfo.BucketID, fileInfo.ObjectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get file info", []map[string]interface{}{
+			{"error": "Failed to get file info", "message": err.Error()},
+		})
+	}
+
+	if reason := verifyUploadedObject(fileInfo, objectInfo, etag); reason != "" {
+		l.failUpload(ctx, fileInfo)
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Uploaded file verification failed", []map[string]interface{}{
+			{"error": "Uploaded file verification failed", "message": reason},
+		})
+	}
+
+	// 对象由客户端直传，需读取一遍计算摘要并嗅探内容类型
+	inspected, err := inspectObject(ctx, l.storage, fileInfo.BucketID, fileInfo.ObjectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to compute file digest", []map[string]interface{}{
+			{"error": "Failed to compute file digest", "message": err.Error()},
+		})
+	}
+	if err = checkSHA256(sha256, inspected.SHA256()); err != nil {
+		l.failUpload(ctx, fileInfo)
+		return nil, err
+	}
+	err = uploadPolicyFor(fileInfo.BucketID).checkContentType(targetName(fileInfo), inspected.detected)
+	auditContentType(targetName(fileInfo), fileInfo.ContentType, inspected.detected, err)
+	if err != nil {
+		l.failUpload(ctx, fileInfo)
+		return nil, err
+	}
+	if err = l.dbFile.UpdateFileSHA256(ctx, fileID, inspected.SHA256()); err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to update file digest", []map[string]interface{}{
+			{"error": "Failed to update file digest", "message": err.Error()},
+		})
+	}
+	if err = l.dbFile.UpdateFileContentType(ctx, fileID, inspected.ContentType(fileInfo.ContentType)); err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to update file content type", []map[string]interface{}{
+			{"error": "Failed to update file content type", "message": err.Error()},
+		})
+	}
+
+	// overwrite模式在此时替换同名文件
+	if fileInfo.TargetName != "" {
+		if err = l.activateFile(ctx, fileInfo); err != nil {
+			return nil, err
+		}
+		return l.activatedFile(ctx, fileInfo)
+	}
+
+	if err = l.dbFile.UpdateFileStatus(ctx, fileID, interfaces.FileStatusActive); err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to update file status", []map[string]interface{}{
+			{"error": "Failed to update file status", "message": err.Error()},
+		})
+	}
+
+	return l.getFile(ctx, fileID)
+}
+
+// 预签名上传校验失败时删除对象并将文件置为失败，同名文件再次上传时清理记录
+func (l *LogicsFile) failUpload(ctx context.Context, fileInfo *interfaces.FileInfo) {
+	l.storage.Delete(ctx, fileInfo.BucketID, fileInfo.ObjectName)
+	if err := l.dbFile.UpdateFileStatus(ctx, fileInfo.ID, interfaces.FileStatusFailed); err != nil {
+		log.Printf("[WARN] failed to mark file %s as failed: %v", fileInfo.ID, err)
+	}
+}
+
 // 生成预签名下载URL
 func (l *LogicsFile) GenerateDownloadURL(ctx context.Context, fileID string) (*interfaces.DownloadURL, error) {
 	// 权限检查（可以添加用户权限验证）
@@ -229,15 +552,13 @@ func (l *LogicsFile) GenerateDownloadURL(ctx context.Context, fileID string) (*i
 	}
 
 	// 获取文件信息
-	fileInfo, err := l.dbFile.GetFileByID(ctx, fileID)
+	fileInfo, err := l.getActiveFile(ctx, fileID)
 	if err != nil {
-		return nil, common.NewHTTPError(http.StatusNotFound, "File not found", []map[string]interface{}{
-			{"error": "File not found", "message": err.Error()},
-		})
+		return nil, err
 	}
 
 	// 检查存储中文件是否存在
-	exists, err := l.storage.FileExists(ctx, fileInfo.BucketID, fileInfo.Name)
+	exists, err := l.storage.FileExists(ctx, fileInfo.BucketID, fileInfo.ObjectName)
 	if err != nil {
 		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to check file existence", []map[string]interface{}{
 			{"error": "Failed to check file existence", "message": err.Error()},
@@ -249,7 +570,7 @@ func (l *LogicsFile) GenerateDownloadURL(ctx context.Context, fileID string) (*i
 	}
 
 	// 生成预签名URL
-	presignedURL, err := l.storage.GeneratePresignedDownloadURL(ctx, fileInfo.BucketID, fileInfo.Name, l.downloadTimeout)
+	presignedURL, err := l.storage.GeneratePresignedDownloadURL(ctx, fileInfo.BucketID, fileInfo.ObjectName, l.downloadTimeout)
 	if err != nil {
 		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to generate download URL", []map[string]interface{}{
 			{"error": "Failed to generate download URL", "message": err.Error()},
@@ -268,30 +589,42 @@ func (l *LogicsFile) GenerateDownloadURL(ctx context.Context, fileID string) (*i
 	}, nil
 }
 
+// 获取默认桶的上传策略
+func (l *LogicsFile) GetUploadPolicy(ctx context.Context) (*interfaces.UploadPolicy, error) {
+	return l.uploadPolicy().toInterface(), nil
+}
+
 func (l *LogicsFile) Delete(ctx context.Context, fileID string) error {
 	// 从数据库获取文件信息
-	fileInfo, err := l.dbFile.GetFileByID(ctx, fileID)
+	fileInfo, err := l.getFile(ctx, fileID)
 	if err != nil {
-		return fmt.Errorf("file not found: %w", err)
+		return err
 	}
 
-	// 从存储中删除文件
-	err = l.storage.Delete(ctx, fileInfo.BucketID, fileInfo.Name)
-	if err != nil {
-		return fmt.Errorf("failed to delete file from storage: %w", err)
-	}
+	// 删除记录，对象不再被任何文件引用时从存储中删除
+	return l.releaseFile(ctx, fileInfo)
+}
 
-	// 从数据库删除记录
-	err = l.dbFile.DeleteFile(ctx, fileID)
+// 删除文件记录并释放其对存储对象的引用，最后一个引用释放后才删除对象。
+// 记录已被删除时只释放对象，删除记录和检查引用在同一事务中加锁完成，避免与并发的秒传交错后删除仍被引用的对象
+func (l *LogicsFile) releaseFile(ctx context.Context, fileInfo *interfaces.FileInfo) error {
+	referenced, err := l.dbFile.DeleteFileReference(ctx, fileInfo.ID, fileInfo.BucketID, fileInfo.ObjectName)
 	if err != nil {
 		return fmt.Errorf("failed to delete file record: %w", err)
 	}
+	if referenced {
+		return nil
+	}
+
+	if err = l.storage.Delete(ctx, fileInfo.BucketID, fileInfo.ObjectName); err != nil {
+		return fmt.Errorf("failed to delete file from storage: %w", err)
+	}
 
 	return nil
 }
 
 func (l *LogicsFile) GetMeta(ctx context.Context, fileID string) (*interfaces.FileInfo, error) {
-	return l.dbFile.GetFileByID(ctx, fileID)
+	return l.getFile(ctx, fileID)
 }
 
 func (l *LogicsFile) GetList(ctx context.Context, page, pageSize int) ([]*interfaces.FileInfo, int64, error) {
@@ -305,26 +638,78 @@ func (l *LogicsFile) GetList(ctx context.Context, page, pageSize int) ([]*interf
 	return l.dbFile.GetFileList(ctx, l.defaultBucketID, page, pageSize)
 }
 
-// 文件校验
-func (l *LogicsFile) validateFile(file *multipart.FileHeader) (err error) {
-	// 检查文件名是否合法
-	if err = ValidFileName(file.Filename); err != nil {
-		return
+// 获取文件记录，记录不存在时返回404
+func (l *LogicsFile) getFile(ctx context.Context, fileID string) (*interfaces.FileInfo, error) {
+	fileInfo, err := l.dbFile.GetFileByID(ctx, fileID)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusNotFound, "File not found", []map[string]interface{}{
+			{"error": "File not found", "message": err.Error()},
+		})
+	}
+	if fileInfo == nil {
+		return nil, common.NewHTTPError(http.StatusNotFound, "File not found", nil)
 	}
 
-	// 检查文件大小
-	if err = ValidFileSize(file.Size); err != nil {
-		return
+	return fileInfo, nil
+}
+
+// 获取可用状态的文件记录，待上传或上传失败的文件视为不存在
+func (l *LogicsFile) getActiveFile(ctx context.Context, fileID string) (*interfaces.FileInfo, error) {
+	fileInfo, err := l.getFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if fileInfo.Status != interfaces.FileStatusActive {
+		return nil, common.NewHTTPError(http.StatusNotFound, "File not found", []map[string]interface{}{
+			{"error": "File not found", "message": fmt.Sprintf("file %s is %s", fileID, fileInfo.Status)},
+		})
 	}
 
-	// 检查文件扩展名
-	if err = ValidFileExtension(file); err != nil {
-		return
+	return fileInfo, nil
+}
+
+// 检查文件名是否可用。上传失败或超过过期时间仍未确认的待上传记录会被清理，释放文件名；
+// 仅reject模式在同名文件存在时返回错误，overwrite和rename模式在创建记录时处理
+func (l *LogicsFile) checkNameAvailable(ctx context.Context, filename, conflict string) error {
+	existingFile, err := l.dbFile.GetFileByName(ctx, filename)
+	if err != nil || existingFile == nil {
+		return nil
+	}
+
+	stale := existingFile.Status == interfaces.FileStatusFailed ||
+		(existingFile.Status == interfaces.FileStatusPending && existingFile.ExpireAt > 0 && time.Now().Unix() >= existingFile.ExpireAt)
+	if !stale {
+		if conflict != interfaces.ConflictReject {
+			return nil
+		}
+		return fileExistsError(filename)
+	}
+
+	log.Printf("[INFO] reclaiming %s file record %s for name %s", existingFile.Status, existingFile.ID, filename)
+	if err = l.releaseFile(ctx, existingFile); err != nil {
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to delete file", []map[string]interface{}{
+			{"error": "Failed to delete file", "message": err.Error()},
+		})
 	}
 
 	return nil
 }
 
+// 文件校验
+func (l *LogicsFile) validateFile(file *multipart.FileHeader) (string, error) {
+	return l.validateUpload(file.Filename, file.Size)
+}
+
+// 按默认桶的上传策略校验待上传文件的名称、大小和扩展名，返回规范化后的文件名
+func (l *LogicsFile) validateUpload(filename string, size int64) (string, error) {
+	return l.uploadPolicy().validate(filename, size, MaxFileSize)
+}
+
+// 上传到默认桶的文件使用的上传策略
+func (l *LogicsFile) uploadPolicy() *uploadPolicy {
+	return uploadPolicyFor(l.defaultBucketID)
+}
+
 // 权限检查（示例实现）
 func (l *LogicsFile) checkDownloadPermission(ctx context.Context, fileID string) error {
 	// 这里可以添加：
@@ -337,17 +722,42 @@ func (l *LogicsFile) checkDownloadPermission(ctx context.Context, fileID string)
 	return nil
 }
 
-// 验证文件信息
-func (l *LogicsFile) validateFileInfo(filename string, contentType string, size int64) (err error) {
-	err = ValidFileName(filename)
+// 验证预签名上传的文件信息，声明的类型在生成URL时先行校验，内容类型在确认上传时按嗅探结果校验
+func (l *LogicsFile) validateFileInfo(filename string, contentType string, size int64) (string, error) {
+	policy := l.uploadPolicy()
+	filename, err := policy.validate(filename, size, MaxFileSize)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if err = policy.checkDeclaredType(contentType); err != nil {
+		return "", err
 	}
+	return filename, nil
+}
 
-	err = ValidFileSize(size)
-	if err != nil {
-		return err
+// 校验存储中的对象与文件记录是否一致，返回不一致的原因
+func verifyUploadedObject(fileInfo *interfaces.FileInfo, objectInfo *interfaces.StorageFileInfo, etag string) string {
+	if objectInfo.Size != fileInfo.Size {
+		return fmt.Sprintf("file size mismatch, expected %d, got %d", fileInfo.Size, objectInfo.Size)
 	}
 
-	return nil
+	if fileInfo.ContentType != "" && !sameMediaType(fileInfo.ContentType, objectInfo.ContentType) {
+		return fmt.Sprintf("content type mismatch, expected %s, got %s", fileInfo.ContentType, objectInfo.ContentType)
+	}
+
+	if etag = strings.Trim(etag, "\""); etag != "" && etag != strings.Trim(objectInfo.ETag, "\"") {
+		return fmt.Sprintf("etag mismatch, expected %s, got %s", etag, objectInfo.ETag)
+	}
+
+	return ""
+}
+
+// 比较媒体类型，忽略大小写和charset等参数
+func sameMediaType(a, b string) bool {
+	mediaTypeA, _, errA := mime.ParseMediaType(a)
+	mediaTypeB, _, errB := mime.ParseMediaType(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return mediaTypeA == mediaTypeB
 }
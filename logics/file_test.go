@@ -0,0 +1,143 @@
+package logics
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 待上传的记录在过期前占用文件名，过期或上传失败后被回收
+func TestCheckNameAvailable(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+
+	e.upload(t, "active.txt", "hello", "")
+	if _, err := e.file.createPendingFile(ctx, "pending.txt", newObjectKey(), "text/plain", 5, ""); err != nil {
+		t.Fatalf("createPendingFile() error = %v", err)
+	}
+	failed, err := e.file.createPendingFile(ctx, "failed.txt", newObjectKey(), "text/plain", 5, "")
+	if err != nil {
+		t.Fatalf("createPendingFile() error = %v", err)
+	}
+	if err = e.dbFile.UpdateFileStatus(ctx, failed.ID, interfaces.FileStatusFailed); err != nil {
+		t.Fatalf("UpdateFileStatus() error = %v", err)
+	}
+	e.file.uploadTimeout = -time.Minute
+	if _, err = e.file.createPendingFile(ctx, "expired.txt", newObjectKey(), "text/plain", 5, ""); err != nil {
+		t.Fatalf("createPendingFile() error = %v", err)
+	}
+	e.file.uploadTimeout = time.Hour
+
+	tests := []struct {
+		name       string
+		conflict   string
+		wantStatus int
+		reclaimed  bool
+	}{
+		{"active.txt", interfaces.ConflictReject, http.StatusBadRequest, false},
+		{"active.txt", interfaces.ConflictOverwrite, 0, false},
+		{"pending.txt", interfaces.ConflictReject, http.StatusBadRequest, false},
+		{"failed.txt", interfaces.ConflictReject, 0, true},
+		{"expired.txt", interfaces.ConflictReject, 0, true},
+		{"missing.txt", interfaces.ConflictReject, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.conflict, func(t *testing.T) {
+			before, _ := e.dbFile.GetFileByName(ctx, tt.name)
+			err := e.file.checkNameAvailable(ctx, tt.name, tt.conflict)
+			if httpStatus(err) != tt.wantStatus || (tt.wantStatus == 0) != (err == nil) {
+				t.Fatalf("checkNameAvailable() error = %v, want status %d", err, tt.wantStatus)
+			}
+			after, _ := e.dbFile.GetFileByName(ctx, tt.name)
+			if reclaimed := before != nil && after == nil; reclaimed != tt.reclaimed {
+				t.Fatalf("record reclaimed = %v, want %v", reclaimed, tt.reclaimed)
+			}
+		})
+	}
+}
+
+// 秒传的文件与源文件共用同一个对象，最后一个引用删除后才删除对象
+func TestSharedObjectReleasedByLastDelete(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	source := e.upload(t, "a.txt", "hello", "")
+
+	shared, err := e.file.PrecheckUpload(ctx, "b.txt", source.SHA256, source.Size, "")
+	if err != nil || shared == nil || shared.ObjectName != source.ObjectName {
+		t.Fatalf("PrecheckUpload() = %+v, %v, want a file sharing %s", shared, err, source.ObjectName)
+	}
+
+	if err = e.file.Delete(ctx, source.ID); err != nil {
+		t.Fatalf("Delete(source) error = %v", err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 || e.content(t, shared) != "hello" {
+		t.Fatalf("objects = %v, want the shared object kept", objects)
+	}
+	if err = e.file.Delete(ctx, shared.ID); err != nil {
+		t.Fatalf("Delete(shared) error = %v", err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 0 {
+		t.Fatalf("objects = %v, want none after the last delete", objects)
+	}
+}
+
+// hookDBFile 在创建记录或检查对象引用前执行一次hook，用于模拟并发的请求
+type hookDBFile struct {
+	interfaces.DBFile
+	beforeCreate func()
+	beforeCheck  func()
+}
+
+func (d *hookDBFile) CreateFile(ctx context.Context, file *interfaces.FileInfo) error {
+	if hook := d.beforeCreate; hook != nil {
+		d.beforeCreate = nil
+		hook()
+	}
+	return d.DBFile.CreateFile(ctx, file)
+}
+
+func (d *hookDBFile) HasObjectReference(ctx context.Context, bucketID, objectName, excludeID string) (bool, error) {
+	if hook := d.beforeCheck; hook != nil {
+		d.beforeCheck = nil
+		hook()
+	}
+	return d.DBFile.HasObjectReference(ctx, bucketID, objectName, excludeID)
+}
+
+// 秒传过程中源文件被删除，秒传退回为普通上传，不留下引用已删除对象的记录
+func TestPrecheckUploadRacesDelete(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		setup func(db *hookDBFile, remove func())
+	}{
+		{"delete before the record is created", func(db *hookDBFile, remove func()) { db.beforeCreate = remove }},
+		{"delete before the reference check", func(db *hookDBFile, remove func()) { db.beforeCheck = remove }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			source := e.upload(t, "a.txt", "hello", "")
+			db := &hookDBFile{DBFile: e.dbFile}
+			e.file.dbFile = db
+			tt.setup(db, func() {
+				if err := e.file.Delete(ctx, source.ID); err != nil {
+					t.Errorf("Delete() error = %v", err)
+				}
+			})
+
+			file, err := e.file.PrecheckUpload(ctx, "b.txt", source.SHA256, source.Size, "")
+			if err != nil || file != nil {
+				t.Fatalf("PrecheckUpload() = %+v, %v, want nil so the client uploads the data", file, err)
+			}
+			if record, err := e.dbFile.GetFileByName(ctx, "b.txt"); err != nil || record != nil {
+				t.Fatalf("b.txt record = %+v, %v, want none", record, err)
+			}
+			if objects := e.storage.objects(t); len(objects) != 0 {
+				t.Fatalf("objects = %v, want none", objects)
+			}
+		})
+	}
+}
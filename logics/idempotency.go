@@ -0,0 +1,194 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultIdempotencyTTL         = 24 * time.Hour
+	defaultIdempotencyLockTimeout = time.Hour
+	defaultIdempotencyWaitTimeout = 10 * time.Second
+	// idempotencyPollInterval 等待正在处理的相同请求时查询记录的间隔
+	idempotencyPollInterval = 200 * time.Millisecond
+	// idempotencyPurgeInterval 清理过期记录的最小间隔
+	idempotencyPurgeInterval = 10 * time.Minute
+)
+
+type LogicsIdempotency struct {
+	ttl              time.Duration
+	lockTimeout      time.Duration
+	waitTimeout      time.Duration
+	lastPurge        atomic.Int64
+	dbIdempotencyKey interfaces.DBIdempotencyKey
+}
+
+var (
+	logicsIdempotencyOnce sync.Once
+	logicsIdempotency     *LogicsIdempotency
+)
+
+func NewLogicsIdempotency() interfaces.LogicsIdempotency {
+	logicsIdempotencyOnce.Do(func() {
+		c := config.Idempotency
+		if c == nil {
+			c = &common.IdempotencyConfig{}
+		}
+		ttl := c.TTL
+		if ttl <= 0 {
+			ttl = defaultIdempotencyTTL
+		}
+		lockTimeout := c.LockTimeout
+		if lockTimeout <= 0 {
+			lockTimeout = defaultIdempotencyLockTimeout
+		}
+		waitTimeout := c.WaitTimeout
+		if waitTimeout <= 0 {
+			waitTimeout = defaultIdempotencyWaitTimeout
+		}
+
+		logicsIdempotency = &LogicsIdempotency{
+			ttl:              ttl,
+			lockTimeout:      lockTimeout,
+			waitTimeout:      waitTimeout,
+			dbIdempotencyKey: dbIdempotencyKey,
+		}
+	})
+	return logicsIdempotency
+}
+
+func (l *LogicsIdempotency) Acquire(ctx context.Context, key, fingerprint string) (string, *interfaces.IdempotentResponse, error) {
+	l.purge()
+
+	deadline := time.Now().Add(l.waitTimeout)
+	for {
+		// 以插入记录的方式加锁，多实例并发处理相同幂等键的请求时只有一个能插入成功
+		now := time.Now()
+		record := &interfaces.IdempotencyKey{
+			Key:         key,
+			Token:       uuid.New().String(),
+			Fingerprint: fingerprint,
+			Status:      interfaces.IdempotencyStatusProcessing,
+			ExpireAt:    now.Add(l.lockTimeout).Unix(),
+		}
+		err := l.dbIdempotencyKey.CreateIdempotencyKey(ctx, record)
+		if err == nil {
+			return record.Token, nil, nil
+		}
+		if !errors.Is(err, interfaces.ErrDuplicateEntry) {
+			return "", nil, idempotencyError(err)
+		}
+
+		existing, err := l.dbIdempotencyKey.GetIdempotencyKey(ctx, key)
+		if err != nil {
+			return "", nil, idempotencyError(err)
+		}
+		if existing == nil {
+			// 记录已被释放，重新获取
+			continue
+		}
+		if existing.ExpireAt <= now.Unix() {
+			// 响应已过期或处理已中断，删除后重新获取；按令牌删除，不会误删其他请求刚获取的记录
+			if err = l.dbIdempotencyKey.DeleteIdempotencyKey(ctx, key, existing.Token); err != nil {
+				return "", nil, idempotencyError(err)
+			}
+			continue
+		}
+		if existing.Fingerprint != fingerprint {
+			return "", nil, common.NewHTTPError(http.StatusUnprocessableEntity, "Idempotency key reused with a different request", []map[string]interface{}{
+				{"error": "Idempotency key reused with a different request", "message": "idempotency key " + key + " was used by a request with different method, path or body"},
+			})
+		}
+		if existing.Status == interfaces.IdempotencyStatusCompleted {
+			response := &interfaces.IdempotentResponse{
+				StatusCode: existing.ResponseCode,
+				Body:       existing.ResponseBody,
+				BodyDigest: existing.BodyDigest,
+			}
+			if err = json.Unmarshal([]byte(existing.ResponseHeader), &response.Header); err != nil {
+				return "", nil, idempotencyError(err)
+			}
+			return "", response, nil
+		}
+
+		// 相同的请求正在处理，等待其完成
+		if time.Now().After(deadline) {
+			return "", nil, common.NewHTTPError(http.StatusConflict, "Request with the same idempotency key is in progress", []map[string]interface{}{
+				{"error": "Request with the same idempotency key is in progress", "message": "retry after the original request completes"},
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return "", nil, idempotencyError(ctx.Err())
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+func (l *LogicsIdempotency) Complete(ctx context.Context, key, token string, response *interfaces.IdempotentResponse) error {
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+
+	affected, err := l.dbIdempotencyKey.CompleteIdempotencyKey(ctx, &interfaces.IdempotencyKey{
+		Key:            key,
+		Token:          token,
+		BodyDigest:     response.BodyDigest,
+		ResponseCode:   response.StatusCode,
+		ResponseHeader: string(header),
+		ResponseBody:   response.Body,
+		ExpireAt:       time.Now().Add(l.ttl).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// 处理时间超过lockTimeout，记录已被其他请求重新获取
+		log.Printf("[WARN] idempotency key %s was taken over by another request, response not saved", key)
+	}
+	return nil
+}
+
+func (l *LogicsIdempotency) Release(ctx context.Context, key, token string) error {
+	return l.dbIdempotencyKey.DeleteIdempotencyKey(ctx, key, token)
+}
+
+// purge 清理过期记录，每个实例每idempotencyPurgeInterval最多执行一次
+func (l *LogicsIdempotency) purge() {
+	now := time.Now()
+	last := l.lastPurge.Load()
+	if now.Unix()-last < int64(idempotencyPurgeInterval/time.Second) || !l.lastPurge.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		deleted, err := l.dbIdempotencyKey.DeleteExpiredIdempotencyKeys(ctx, now.Unix())
+		if err != nil {
+			log.Printf("[WARN] failed to delete expired idempotency keys: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("[INFO] deleted %d expired idempotency keys", deleted)
+		}
+	}()
+}
+
+func idempotencyError(err error) error {
+	return common.NewHTTPError(http.StatusInternalServerError, "Failed to check idempotency key", []map[string]interface{}{
+		{"error": "Failed to check idempotency key", "message": err.Error()},
+	})
+}
@@ -0,0 +1,251 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultImportTimeout      = 30 * time.Minute
+	defaultImportMaxRedirects = 5
+	defaultImportConcurrency  = 4
+	// maxSourceURLLength 源URL的最大长度，与t_import_job.source_url一致
+	maxSourceURLLength = 2048
+	// maxImportErrorLength 保存的失败原因的最大长度，与t_import_job.error_message一致
+	maxImportErrorLength = 1024
+)
+
+var defaultImportSchemes = []string{"http", "https"}
+
+type LogicsImport struct {
+	defaultBucketID string
+	allowedSchemes  []string
+	allowPrivate    bool
+	timeout         time.Duration
+	client          *http.Client
+	sem             chan struct{}
+	dbImportJob     interfaces.DBImportJob
+	logicsFile      interfaces.LogicsFile
+}
+
+var (
+	logicsImportOnce sync.Once
+	logicsImport     *LogicsImport
+)
+
+func NewLogicsImport() interfaces.LogicsImport {
+	logicsImportOnce.Do(func() {
+		c := config.Import
+		if c == nil {
+			c = &common.ImportConfig{}
+		}
+		allowedSchemes := defaultImportSchemes
+		if len(c.AllowedSchemes) > 0 {
+			allowedSchemes = make([]string, 0, len(c.AllowedSchemes))
+			for _, scheme := range c.AllowedSchemes {
+				allowedSchemes = append(allowedSchemes, strings.ToLower(scheme))
+			}
+		}
+		timeout := c.Timeout
+		if timeout <= 0 {
+			timeout = defaultImportTimeout
+		}
+		maxRedirects := c.MaxRedirects
+		if maxRedirects <= 0 {
+			maxRedirects = defaultImportMaxRedirects
+		}
+		concurrency := c.Concurrency
+		if concurrency <= 0 {
+			concurrency = defaultImportConcurrency
+		}
+
+		logicsImport = &LogicsImport{
+			defaultBucketID: config.DefaultBucketID(),
+			allowedSchemes:  allowedSchemes,
+			allowPrivate:    c.AllowPrivateNetworks,
+			timeout:         timeout,
+			client:          newImportClient(allowedSchemes, c.AllowPrivateNetworks, maxRedirects),
+			sem:             make(chan struct{}, concurrency),
+			dbImportJob:     dbImportJob,
+			logicsFile:      NewLogicsFile(),
+		}
+	})
+	return logicsImport
+}
+
+func (l *LogicsImport) CreateImport(ctx context.Context, sourceURL, filename, sha256, conflict string) (*interfaces.ImportJob, error) {
+	u, err := url.Parse(sourceURL)
+	if err == nil && len(sourceURL) > maxSourceURLLength {
+		err = fmt.Errorf("url exceeds %d bytes", maxSourceURLLength)
+	}
+	if err == nil {
+		err = checkSourceURL(u, l.allowedSchemes, l.allowPrivate)
+	}
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Invalid source URL", []map[string]interface{}{
+			{"error": "Invalid source URL", "message": err.Error()},
+		})
+	}
+
+	// 指定了文件名时提前校验，未指定时在获取到响应后校验
+	policy := uploadPolicyFor(l.defaultBucketID)
+	if filename != "" {
+		if filename, err = policy.checkFilename(filename); err != nil {
+			return nil, err
+		}
+	}
+	if conflict, err = parseConflict(conflict); err != nil {
+		return nil, err
+	}
+
+	job := &interfaces.ImportJob{
+		ID:        uuid.New().String(),
+		BucketID:  l.defaultBucketID,
+		SourceURL: sourceURL,
+		Filename:  filename,
+		Conflict:  conflict,
+		SHA256:    sha256,
+		Status:    interfaces.ImportStatusPending,
+		// 任务的执行时间(含排队时间)不超过timeout，留1分钟余量写入结果；以Unix秒保存，不依赖数据库时间列的时区
+		ExpireAt: time.Now().Add(l.timeout + time.Minute).Unix(),
+	}
+	if err = l.dbImportJob.CreateImportJob(ctx, job); err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to create import job", []map[string]interface{}{
+			{"error": "Failed to create import job", "message": err.Error()},
+		})
+	}
+
+	go l.run(job)
+
+	return l.GetImport(ctx, job.ID)
+}
+
+func (l *LogicsImport) GetImport(ctx context.Context, jobID string) (*interfaces.ImportJob, error) {
+	job, err := l.dbImportJob.GetImportJob(ctx, jobID)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get import job", []map[string]interface{}{
+			{"error": "Failed to get import job", "message": err.Error()},
+		})
+	}
+	if job == nil {
+		return nil, common.NewHTTPError(http.StatusNotFound, "Import job not found", nil)
+	}
+
+	// 超过截止时间仍未结束说明执行任务的实例已退出
+	if (job.Status == interfaces.ImportStatusPending || job.Status == interfaces.ImportStatusRunning) &&
+		job.ExpireAt > 0 && time.Now().Unix() > job.ExpireAt {
+		job.Status = interfaces.ImportStatusFailed
+		job.ErrorMessage = "import job was interrupted"
+	}
+
+	return job, nil
+}
+
+// run 在后台执行导入任务，同时执行的任务数受sem限制，排队时间计入任务超时时间
+func (l *LogicsImport) run(job *interfaces.ImportJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	select {
+	case l.sem <- struct{}{}:
+		defer func() { <-l.sem }()
+	case <-ctx.Done():
+		l.finish(ctx, job, nil, fmt.Errorf("timed out waiting to run: %w", ctx.Err()))
+		return
+	}
+
+	if err := l.dbImportJob.UpdateImportJob(ctx, job.ID, interfaces.ImportStatusRunning, "", ""); err != nil {
+		log.Printf("[WARN] failed to update import job %s: %v", job.ID, err)
+	}
+
+	fileInfo, err := l.fetch(ctx, job)
+	l.finish(ctx, job, fileInfo, err)
+}
+
+// fetch 下载源URL并按普通上传创建文件，大小、类型和文件名均按上传策略校验
+func (l *LogicsImport) fetch(ctx context.Context, job *interfaces.ImportJob) (*interfaces.FileInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.SourceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "FileEngine-Import/1.0")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("source URL returned status %d", resp.StatusCode)
+	}
+
+	filename := job.Filename
+	if filename == "" {
+		filename = responseFilename(resp)
+	}
+
+	// 长度已知时超过上限的文件在读取前即被拒绝，未知时边读边校验
+	return l.logicsFile.UploadFromReader(ctx, filename, resp.Header.Get("Content-Type"), resp.ContentLength, job.SHA256, job.Conflict, resp.Body)
+}
+
+// finish 记录任务结果
+func (l *LogicsImport) finish(ctx context.Context, job *interfaces.ImportJob, fileInfo *interfaces.FileInfo, err error) {
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		log.Printf("[WARN] import job %s failed: %v", job.ID, err)
+		err = l.dbImportJob.UpdateImportJob(ctx, job.ID, interfaces.ImportStatusFailed, "", importErrorMessage(err))
+	} else {
+		log.Printf("[INFO] import job %s created file %s", job.ID, fileInfo.ID)
+		err = l.dbImportJob.UpdateImportJob(ctx, job.ID, interfaces.ImportStatusSucceeded, fileInfo.ID, "")
+	}
+	if err != nil {
+		log.Printf("[WARN] failed to update import job %s: %v", job.ID, err)
+	}
+}
+
+// responseFilename 从Content-Disposition或最终请求的URL路径获取文件名
+func responseFilename(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return path.Base(strings.ReplaceAll(params["filename"], `\`, "/"))
+	}
+	if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+		return name
+	}
+	return ""
+}
+
+// importErrorMessage 失败原因，接口错误取其详细信息
+func importErrorMessage(err error) string {
+	message := err.Error()
+	var httpErr *common.HTTPError
+	if errors.As(err, &httpErr) {
+		message = httpErr.Message
+		if len(httpErr.Details) > 0 {
+			if detail, ok := httpErr.Details[0]["message"].(string); ok && detail != "" {
+				message += ": " + detail
+			}
+		}
+	}
+	if len(message) > maxImportErrorLength {
+		message = message[:maxImportErrorLength]
+		for !utf8.ValidString(message) {
+			message = message[:len(message)-1]
+		}
+	}
+	return message
+}
@@ -0,0 +1,41 @@
+package logics
+
+import (
+	"FileEngine/dbaccess"
+	"FileEngine/interfaces"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 未结束的任务按创建时记录的截止时间判断是否中断，与数据库中创建时间的时区无关
+func TestGetImportInterrupted(t *testing.T) {
+	ctx := context.Background()
+	l := &LogicsImport{timeout: time.Hour, dbImportJob: dbaccess.NewMemoryDBImportJob()}
+
+	tests := []struct {
+		name       string
+		status     string
+		expireAt   int64
+		wantStatus string
+	}{
+		{"running before deadline", interfaces.ImportStatusRunning, time.Now().Add(time.Minute).Unix(), interfaces.ImportStatusRunning},
+		{"pending after deadline", interfaces.ImportStatusPending, time.Now().Add(-time.Minute).Unix(), interfaces.ImportStatusFailed},
+		{"running after deadline", interfaces.ImportStatusRunning, time.Now().Add(-time.Minute).Unix(), interfaces.ImportStatusFailed},
+		{"succeeded after deadline", interfaces.ImportStatusSucceeded, time.Now().Add(-time.Minute).Unix(), interfaces.ImportStatusSucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &interfaces.ImportJob{ID: uuid.New().String(), Status: tt.status, ExpireAt: tt.expireAt}
+			if err := l.dbImportJob.CreateImportJob(ctx, job); err != nil {
+				t.Fatalf("CreateImportJob() error = %v", err)
+			}
+			got, err := l.GetImport(ctx, job.ID)
+			if err != nil || got.Status != tt.wantStatus {
+				t.Fatalf("GetImport() = %+v, %v, want status %s", got, err, tt.wantStatus)
+			}
+		})
+	}
+}
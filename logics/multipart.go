@@ -0,0 +1,389 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxMultipartFileSize 分片上传允许的最大文件大小，与S3单个对象上限一致
+	MaxMultipartFileSize = 5 * 1024 * 1024 * 1024 * 1024
+	// 分片大小限制，与S3保持一致，最后一个分片不受最小值限制
+	MinPartSize     = 5 * 1024 * 1024
+	MaxPartSize     = 5 * 1024 * 1024 * 1024
+	DefaultPartSize = 16 * 1024 * 1024
+	// MaxPartCount 分片数量上限
+	MaxPartCount = 10000
+)
+
+type LogicsMultipart struct {
+	uploadTimeout     time.Duration
+	defaultBucketID   string
+	dbFile            interfaces.DBFile
+	dbMultipartUpload interfaces.DBMultipartUpload
+	storage           interfaces.StorageAdapter
+	logicsFile        *LogicsFile
+}
+
+var (
+	logicsMultipartOnce sync.Once
+	logicsMultipart     *LogicsMultipart
+)
+
+func NewLogicsMultipart() interfaces.LogicsMultipart {
+	logicsMultipartOnce.Do(func() {
+		logicsMultipart = &LogicsMultipart{
+			uploadTimeout:     config.Server.UploadTimeout,
+			defaultBucketID:   config.DefaultBucketID(),
+			dbFile:            dbFile,
+			dbMultipartUpload: dbMultipartUpload,
+			storage:           storageAdapter,
+			logicsFile:        NewLogicsFile().(*LogicsFile),
+		}
+	})
+	return logicsMultipart
+}
+
+func (l *LogicsMultipart) InitiateUpload(ctx context.Context, filename, contentType string, size, partSize int64, conflict string) (*interfaces.MultipartUpload, error) {
+	policy := uploadPolicyFor(l.defaultBucketID)
+	filename, err := policy.checkFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 || size > policy.sizeLimit(MaxMultipartFileSize) {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Invalid file size", []map[string]interface{}{
+			{"error": "Invalid file size", "message": fmt.Sprintf("file size must be between 1 and %d", policy.sizeLimit(MaxMultipartFileSize))},
+		})
+	}
+	if err = policy.checkSize(size, MaxMultipartFileSize); err != nil {
+		return nil, err
+	}
+	if err = policy.checkDeclaredType(contentType); err != nil {
+		return nil, err
+	}
+
+	partSize, err = multipartPartSize(size, partSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// 提前检查同名文件，避免上传完成后才失败，完成时按conflict再次处理
+	if conflict, err = parseConflict(conflict); err != nil {
+		return nil, err
+	}
+	if err = l.logicsFile.checkNameAvailable(ctx, filename, conflict); err != nil {
+		return nil, err
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	objectName := newObjectKey()
+	storageUploadID, err := l.storage.InitiateMultipartUpload(ctx, l.defaultBucketID, objectName, contentType)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to initiate multipart upload", []map[string]interface{}{
+			{"error": "Failed to initiate multipart upload", "message": err.Error()},
+		})
+	}
+
+	upload := &interfaces.MultipartUpload{
+		ID:              uuid.New().String(),
+		BucketID:        l.defaultBucketID,
+		Filename:        filename,
+		ObjectName:      objectName,
+		ContentType:     contentType,
+		Size:            size,
+		PartSize:        partSize,
+		StorageUploadID: storageUploadID,
+		Conflict:        conflict,
+	}
+	if err = l.dbMultipartUpload.CreateMultipartUpload(ctx, upload); err != nil {
+		l.storage.AbortMultipartUpload(ctx, upload.BucketID, upload.ObjectName, storageUploadID)
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to create multipart upload", []map[string]interface{}{
+			{"error": "Failed to create multipart upload", "message": err.Error()},
+		})
+	}
+
+	// 重新读取记录，获取数据库生成的创建时间和更新时间
+	return l.getUpload(ctx, upload.ID)
+}
+
+func (l *LogicsMultipart) GeneratePartURLs(ctx context.Context, uploadID string, partNumbers []int) ([]*interfaces.PartURL, error) {
+	upload, err := l.getPendingUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(partNumbers) == 0 {
+		for partNumber := 1; partNumber <= upload.PartCount(); partNumber++ {
+			partNumbers = append(partNumbers, partNumber)
+		}
+	}
+
+	expiresAt := time.Now().Add(l.uploadTimeout)
+	expiresIn := int64(l.uploadTimeout.Seconds())
+
+	partURLs := make([]*interfaces.PartURL, 0, len(partNumbers))
+	for _, partNumber := range partNumbers {
+		if partNumber < 1 || partNumber > upload.PartCount() {
+			return nil, common.NewHTTPError(http.StatusBadRequest, "Invalid part number", []map[string]interface{}{
+				{"error": "Invalid part number", "message": fmt.Sprintf("part number must be between 1 and %d, got %d", upload.PartCount(), partNumber)},
+			})
+		}
+
+		presignedURL, err := l.storage.GeneratePresignedPartURL(ctx, upload.BucketID, upload.ObjectName, upload.StorageUploadID, partNumber, l.uploadTimeout)
+		if err != nil {
+			return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to generate part upload URL", []map[string]interface{}{
+				{"error": "Failed to generate part upload URL", "message": err.Error()},
+			})
+		}
+
+		partURLs = append(partURLs, &interfaces.PartURL{
+			PartNumber: partNumber,
+			Size:       upload.PartSizeOf(partNumber),
+			URL:        presignedURL,
+			ExpiresAt:  expiresAt,
+			ExpiresIn:  expiresIn,
+		})
+	}
+
+	return partURLs, nil
+}
+
+func (l *LogicsMultipart) ListParts(ctx context.Context, uploadID string) (*interfaces.MultipartUpload, []*interfaces.StoragePart, error) {
+	upload, err := l.getUpload(ctx, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 上传完成后存储侧不再保留分片
+	if upload.FileID != "" {
+		return upload, []*interfaces.StoragePart{}, nil
+	}
+
+	parts, err := l.storage.ListParts(ctx, upload.BucketID, upload.ObjectName, upload.StorageUploadID)
+	if err != nil {
+		return nil, nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to list parts", []map[string]interface{}{
+			{"error": "Failed to list parts", "message": err.Error()},
+		})
+	}
+
+	return upload, parts, nil
+}
+
+func (l *LogicsMultipart) CompleteUpload(ctx context.Context, uploadID string, parts []*interfaces.StoragePart, sha256 string) (*interfaces.FileInfo, error) {
+	upload, err := l.getUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重复完成时直接返回已生成的文件
+	if upload.FileID != "" {
+		return l.logicsFile.GetMeta(ctx, upload.FileID)
+	}
+
+	uploadedParts, err := l.storage.ListParts(ctx, upload.BucketID, upload.ObjectName, upload.StorageUploadID)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to list parts", []map[string]interface{}{
+			{"error": "Failed to list parts", "message": err.Error()},
+		})
+	}
+
+	parts, err = l.checkParts(upload, parts, uploadedParts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 合并前再次检查同名文件，此时失败的会话仍可在释放文件名后重新完成
+	if err = l.logicsFile.checkNameAvailable(ctx, upload.Filename, upload.Conflict); err != nil {
+		return nil, err
+	}
+
+	err = l.storage.CompleteMultipartUpload(ctx, upload.BucketID, upload.ObjectName, upload.StorageUploadID, parts)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to complete multipart upload", []map[string]interface{}{
+			{"error": "Failed to complete multipart upload", "message": err.Error()},
+		})
+	}
+
+	// 分片由客户端直传，合并后读取一遍计算摘要并嗅探内容类型，摘要不一致、内容与扩展名不符或类型不被上传策略允许时删除合并后的文件并结束会话
+	inspected, err := inspectObject(ctx, l.storage, upload.BucketID, upload.ObjectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to compute file digest", []map[string]interface{}{
+			{"error": "Failed to compute file digest", "message": err.Error()},
+		})
+	}
+	err = checkSHA256(sha256, inspected.SHA256())
+	if err == nil {
+		err = uploadPolicyFor(upload.BucketID).checkContentType(upload.Filename, inspected.detected)
+		auditContentType(upload.Filename, upload.ContentType, inspected.detected, err)
+	}
+	if err != nil {
+		l.storage.Delete(ctx, upload.BucketID, upload.ObjectName)
+		l.endUpload(ctx, upload)
+		return nil, err
+	}
+
+	fileInfo := &interfaces.FileInfo{
+		ID:          uuid.New().String(),
+		Name:        upload.Filename,
+		BucketID:    upload.BucketID,
+		ObjectName:  upload.ObjectName,
+		Icon:        "",
+		Size:        upload.Size,
+		ContentType: inspected.ContentType(upload.ContentType),
+		Status:      interfaces.FileStatusActive,
+		SHA256:      inspected.SHA256(),
+	}
+	// 按会话的conflict创建记录，rename模式在此时确定名称，overwrite模式在此时替换同名文件
+	if err = l.logicsFile.createFile(ctx, fileInfo, upload.Conflict); err != nil {
+		// 如果数据库插入失败，需要从存储中删除已合并的文件
+		l.storage.Delete(ctx, upload.BucketID, upload.ObjectName)
+		l.endUpload(ctx, upload)
+		return nil, err
+	}
+	file, err := l.logicsFile.finishUpload(ctx, fileInfo)
+	if err != nil {
+		// finishUpload失败时已删除记录和合并的对象
+		l.endUpload(ctx, upload)
+		return nil, err
+	}
+
+	if err = l.dbMultipartUpload.CompleteMultipartUpload(ctx, upload.ID, file.ID); err != nil {
+		log.Printf("[WARN] failed to mark multipart upload %s as completed: %v", upload.ID, err)
+	}
+
+	return file, nil
+}
+
+// endUpload 分片已合并，会话无法再次完成，直接结束会话
+func (l *LogicsMultipart) endUpload(ctx context.Context, upload *interfaces.MultipartUpload) {
+	if err := l.dbMultipartUpload.DeleteMultipartUpload(ctx, upload.ID); err != nil {
+		log.Printf("[WARN] failed to delete multipart upload %s: %v", upload.ID, err)
+	}
+}
+
+func (l *LogicsMultipart) AbortUpload(ctx context.Context, uploadID string) error {
+	upload, err := l.getPendingUpload(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+
+	err = l.storage.AbortMultipartUpload(ctx, upload.BucketID, upload.ObjectName, upload.StorageUploadID)
+	if err != nil {
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to abort multipart upload", []map[string]interface{}{
+			{"error": "Failed to abort multipart upload", "message": err.Error()},
+		})
+	}
+
+	if err = l.dbMultipartUpload.DeleteMultipartUpload(ctx, upload.ID); err != nil {
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to delete multipart upload", []map[string]interface{}{
+			{"error": "Failed to delete multipart upload", "message": err.Error()},
+		})
+	}
+
+	return nil
+}
+
+// checkParts 校验分片是否完整且大小符合会话约定，parts为空时使用存储中已上传的分片，返回用于合并的分片列表
+func (l *LogicsMultipart) checkParts(upload *interfaces.MultipartUpload, parts, uploadedParts []*interfaces.StoragePart) ([]*interfaces.StoragePart, error) {
+	uploaded := make(map[int]*interfaces.StoragePart, len(uploadedParts))
+	for _, part := range uploadedParts {
+		uploaded[part.PartNumber] = part
+	}
+
+	if len(parts) == 0 {
+		parts = uploadedParts
+	}
+
+	var missing []string
+	for partNumber := 1; partNumber <= upload.PartCount(); partNumber++ {
+		part, ok := uploaded[partNumber]
+		if !ok || part.Size != upload.PartSizeOf(partNumber) {
+			missing = append(missing, fmt.Sprint(partNumber))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Multipart upload is incomplete", []map[string]interface{}{
+			{"error": "Multipart upload is incomplete", "message": fmt.Sprintf("missing or incomplete parts: %s", strings.Join(missing, ","))},
+		})
+	}
+
+	if len(parts) != upload.PartCount() {
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Invalid part list", []map[string]interface{}{
+			{"error": "Invalid part list", "message": fmt.Sprintf("expected %d parts, got %d", upload.PartCount(), len(parts))},
+		})
+	}
+
+	completeParts := make([]*interfaces.StoragePart, 0, len(parts))
+	for i, part := range parts {
+		stored := uploaded[i+1]
+		if part.PartNumber != i+1 || (part.ETag != "" && strings.Trim(part.ETag, "\"") != stored.ETag) {
+			return nil, common.NewHTTPError(http.StatusBadRequest, "Invalid part list", []map[string]interface{}{
+				{"error": "Invalid part list", "message": fmt.Sprintf("part %d does not match the uploaded part", part.PartNumber)},
+			})
+		}
+		completeParts = append(completeParts, stored)
+	}
+
+	return completeParts, nil
+}
+
+// 获取分片上传会话，会话不存在时返回404
+func (l *LogicsMultipart) getUpload(ctx context.Context, uploadID string) (*interfaces.MultipartUpload, error) {
+	upload, err := l.dbMultipartUpload.GetMultipartUpload(ctx, uploadID)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get multipart upload", []map[string]interface{}{
+			{"error": "Failed to get multipart upload", "message": err.Error()},
+		})
+	}
+	if upload == nil {
+		return nil, common.NewHTTPError(http.StatusNotFound, "Multipart upload not found", nil)
+	}
+
+	return upload, nil
+}
+
+// 获取未完成的分片上传会话，已完成时返回409
+func (l *LogicsMultipart) getPendingUpload(ctx context.Context, uploadID string) (*interfaces.MultipartUpload, error) {
+	upload, err := l.getUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.FileID != "" {
+		return nil, common.NewHTTPError(http.StatusConflict, "Multipart upload already completed", []map[string]interface{}{
+			{"error": "Multipart upload already completed", "message": fmt.Sprintf("multipart upload %s has been completed as file %s", upload.ID, upload.FileID)},
+		})
+	}
+
+	return upload, nil
+}
+
+// multipartPartSize 确定分片大小：未指定时使用默认值，分片数量超过上限时按MiB向上调整
+func multipartPartSize(size, partSize int64) (int64, error) {
+	if partSize == 0 {
+		partSize = DefaultPartSize
+	}
+	if partSize < MinPartSize || partSize > MaxPartSize {
+		return 0, common.NewHTTPError(http.StatusBadRequest, "Invalid part size", []map[string]interface{}{
+			{"error": "Invalid part size", "message": fmt.Sprintf("part size must be between %d and %d", MinPartSize, int64(MaxPartSize))},
+		})
+	}
+
+	const mib = 1024 * 1024
+	if minPartSize := (size + MaxPartCount - 1) / MaxPartCount; partSize < minPartSize {
+		partSize = (minPartSize + mib - 1) / mib * mib
+	}
+
+	return partSize, nil
+}
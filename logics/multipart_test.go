@@ -0,0 +1,147 @@
+package logics
+
+import (
+	"FileEngine/interfaces"
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckParts(t *testing.T) {
+	// 3个分片：10、10、5字节
+	upload := &interfaces.MultipartUpload{Size: 25, PartSize: 10}
+	uploaded := []*interfaces.StoragePart{
+		{PartNumber: 1, Size: 10, ETag: "e1"},
+		{PartNumber: 2, Size: 10, ETag: "e2"},
+		{PartNumber: 3, Size: 5, ETag: "e3"},
+	}
+	tests := []struct {
+		name     string
+		parts    []*interfaces.StoragePart
+		uploaded []*interfaces.StoragePart
+		wantErr  string
+	}{
+		{"uploaded parts when list is empty", nil, uploaded, ""},
+		{"matching etags", []*interfaces.StoragePart{{PartNumber: 1, ETag: "e1"}, {PartNumber: 2, ETag: `"e2"`}, {PartNumber: 3, ETag: "e3"}}, uploaded, ""},
+		{"etags omitted", []*interfaces.StoragePart{{PartNumber: 1}, {PartNumber: 2}, {PartNumber: 3}}, uploaded, ""},
+		{"missing part", nil, uploaded[:2], "Multipart upload is incomplete"},
+		{"undersized part", nil, []*interfaces.StoragePart{uploaded[0], {PartNumber: 2, Size: 9, ETag: "e2"}, uploaded[2]}, "Multipart upload is incomplete"},
+		{"oversized last part", nil, []*interfaces.StoragePart{uploaded[0], uploaded[1], {PartNumber: 3, Size: 10, ETag: "e3"}}, "Multipart upload is incomplete"},
+		{"etag mismatch", []*interfaces.StoragePart{{PartNumber: 1, ETag: "e1"}, {PartNumber: 2, ETag: "other"}, {PartNumber: 3, ETag: "e3"}}, uploaded, "Invalid part list"},
+		{"too few parts listed", []*interfaces.StoragePart{{PartNumber: 1}, {PartNumber: 2}}, uploaded, "Invalid part list"},
+		{"parts out of order", []*interfaces.StoragePart{{PartNumber: 2}, {PartNumber: 1}, {PartNumber: 3}}, uploaded, "Invalid part list"},
+	}
+	l := &LogicsMultipart{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := l.checkParts(upload, tt.parts, tt.uploaded)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || httpStatus(err) != http.StatusBadRequest {
+					t.Fatalf("checkParts() error = %v, want 400 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkParts() error = %v", err)
+			}
+			// 合并时使用存储中记录的分片信息
+			for i, part := range parts {
+				if part != uploaded[i] {
+					t.Fatalf("part %d = %+v, want %+v", i+1, part, uploaded[i])
+				}
+			}
+		})
+	}
+}
+
+// initiateMultipart 创建分片上传会话并上传全部分片，返回会话和文件内容
+func initiateMultipart(t *testing.T, e *testEnv, filename, conflict string) (*interfaces.MultipartUpload, []byte) {
+	t.Helper()
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("0123456789abcdef"), (MinPartSize+1024)/16)
+	upload, err := e.multipart().InitiateUpload(ctx, filename, "text/plain", int64(len(content)), MinPartSize, conflict)
+	if err != nil {
+		t.Fatalf("InitiateUpload() error = %v", err)
+	}
+	for partNumber := 1; partNumber <= upload.PartCount(); partNumber++ {
+		start := int64(partNumber-1) * upload.PartSize
+		data := content[start : start+upload.PartSizeOf(partNumber)]
+		if _, err = e.storage.UploadPart(ctx, upload.BucketID, upload.ObjectName, upload.StorageUploadID, partNumber, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("UploadPart(%d) error = %v", partNumber, err)
+		}
+	}
+	return upload, content
+}
+
+func TestMultipartCompleteUpload(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	multipart := e.multipart()
+	upload, content := initiateMultipart(t, e, "a.txt", "")
+
+	file, err := multipart.CompleteUpload(ctx, upload.ID, nil, "")
+	if err != nil {
+		t.Fatalf("CompleteUpload() error = %v", err)
+	}
+	if file.Name != "a.txt" || file.Size != int64(len(content)) || e.content(t, file) != string(content) {
+		t.Fatalf("CompleteUpload() = %+v, want a.txt with the uploaded content", file)
+	}
+
+	// 重复完成返回同一个文件，不会再次合并
+	again, err := multipart.CompleteUpload(ctx, upload.ID, nil, "")
+	if err != nil || again.ID != file.ID {
+		t.Fatalf("repeated CompleteUpload() = %+v, %v, want file %s", again, err, file.ID)
+	}
+	if _, err = multipart.GeneratePartURLs(ctx, upload.ID, nil); httpStatus(err) != http.StatusConflict {
+		t.Fatalf("GeneratePartURLs() after completion error = %v, want 409", err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 {
+		t.Fatalf("objects = %v, want only the merged object", objects)
+	}
+}
+
+func TestMultipartCompleteRejectsDigestMismatch(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	multipart := e.multipart()
+	upload, _ := initiateMultipart(t, e, "a.txt", "")
+
+	_, err := multipart.CompleteUpload(ctx, upload.ID, nil, strings.Repeat("0", 64))
+	if httpStatus(err) != http.StatusBadRequest {
+		t.Fatalf("CompleteUpload() error = %v, want 400", err)
+	}
+	// 合并后的对象被删除，会话结束
+	if objects := e.storage.objects(t); len(objects) != 0 {
+		t.Fatalf("objects = %v, want none", objects)
+	}
+	if _, err = multipart.CompleteUpload(ctx, upload.ID, nil, ""); httpStatus(err) != http.StatusNotFound {
+		t.Fatalf("CompleteUpload() after failure error = %v, want 404", err)
+	}
+}
+
+// 创建会话后同名文件被其他请求创建，完成时返回错误且不合并分片，释放文件名后可重新完成
+func TestMultipartCompleteLosesNameRace(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	multipart := e.multipart()
+	upload, content := initiateMultipart(t, e, "a.txt", interfaces.ConflictReject)
+	winner := e.upload(t, "a.txt", "other", "")
+
+	if _, err := multipart.CompleteUpload(ctx, upload.ID, nil, ""); httpStatus(err) != http.StatusBadRequest {
+		t.Fatalf("CompleteUpload() error = %v, want 400", err)
+	}
+	_, parts, err := multipart.ListParts(ctx, upload.ID)
+	if err != nil || len(parts) != upload.PartCount() {
+		t.Fatalf("ListParts() = %d parts, %v, want %d", len(parts), err, upload.PartCount())
+	}
+
+	if err = e.file.Delete(ctx, winner.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	file, err := multipart.CompleteUpload(ctx, upload.ID, nil, "")
+	if err != nil || file.Name != "a.txt" || e.content(t, file) != string(content) {
+		t.Fatalf("CompleteUpload() retry = %+v, %v, want a.txt with the uploaded content", file, err)
+	}
+}
@@ -0,0 +1,175 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// LogicsObject 为不具备预签名能力的存储（如本地文件系统）提供对象读写，请求需携带FileEngine签发的签名
+type LogicsObject struct {
+	signer  *common.URLSigner
+	storage interfaces.StorageAdapter
+}
+
+var (
+	logicsObjectOnce sync.Once
+	logicsObject     *LogicsObject
+)
+
+func NewLogicsObject() interfaces.LogicsObject {
+	logicsObjectOnce.Do(func() {
+		logicsObject = &LogicsObject{
+			signer:  common.NewURLSigner(config.Server.SignKey),
+			storage: storageAdapter,
+		}
+	})
+	return logicsObject
+}
+
+func (l *LogicsObject) VerifySignedURL(method, bucketID, objectName string, query url.Values) error {
+	err := l.signer.Verify(method, bucketID, objectName, query)
+	if err == nil {
+		return nil
+	}
+
+	code := http.StatusForbidden
+	if errors.Is(err, common.ErrSignatureMissing) {
+		code = http.StatusUnauthorized
+	}
+	return common.NewHTTPError(code, "Invalid signature", []map[string]interface{}{
+		{"error": "Invalid signature", "message": err.Error()},
+	})
+}
+
+func (l *LogicsObject) PutObject(ctx context.Context, bucketID, objectName string, reader io.Reader, size int64, contentType string) (*interfaces.StorageFileInfo, error) {
+	if err := l.storage.Upload(ctx, bucketID, objectName, reader, size, contentType); err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to upload object", []map[string]interface{}{
+			{"error": "Failed to upload object", "message": err.Error()},
+		})
+	}
+
+	info, err := l.storage.GetFileInfo(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get object info", []map[string]interface{}{
+			{"error": "Failed to get object info", "message": err.Error()},
+		})
+	}
+
+	return info, nil
+}
+
+func (l *LogicsObject) PostObject(ctx context.Context, bucketID string, fields map[string]string, reader io.Reader) (*interfaces.StorageFileInfo, error) {
+	policy, err := l.signer.VerifyPolicy(fields["policy"], fields["signature"])
+	if err != nil {
+		code := http.StatusForbidden
+		if errors.Is(err, common.ErrSignatureMissing) {
+			code = http.StatusUnauthorized
+		}
+		return nil, common.NewHTTPError(code, "Invalid policy", []map[string]interface{}{
+			{"error": "Invalid policy", "message": err.Error()},
+		})
+	}
+
+	// 表单字段必须与策略完全一致
+	if policy.Bucket != bucketID || fields["key"] != policy.Key || fields["Content-Type"] != policy.ContentType {
+		return nil, common.NewHTTPError(http.StatusForbidden, "Policy condition failed", []map[string]interface{}{
+			{"error": "Policy condition failed", "message": "bucket, key or Content-Type does not match the policy"},
+		})
+	}
+
+	limited := &sizeLimitReader{reader: reader, remaining: policy.MaxSize}
+	if err = l.storage.Upload(ctx, bucketID, policy.Key, limited, -1, policy.ContentType); err != nil {
+		if limited.exceeded {
+			return nil, common.NewHTTPError(http.StatusBadRequest, "Entity too large", []map[string]interface{}{
+				{"error": "Entity too large", "message": fmt.Sprintf("object size exceeds %d bytes allowed by the policy", policy.MaxSize)},
+			})
+		}
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to upload object", []map[string]interface{}{
+			{"error": "Failed to upload object", "message": err.Error()},
+		})
+	}
+
+	info, err := l.storage.GetFileInfo(ctx, bucketID, policy.Key)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get object info", []map[string]interface{}{
+			{"error": "Failed to get object info", "message": err.Error()},
+		})
+	}
+	if info.Size < policy.MinSize {
+		l.storage.Delete(ctx, bucketID, policy.Key)
+		return nil, common.NewHTTPError(http.StatusBadRequest, "Entity too small", []map[string]interface{}{
+			{"error": "Entity too small", "message": fmt.Sprintf("object size is less than %d bytes required by the policy", policy.MinSize)},
+		})
+	}
+
+	return info, nil
+}
+
+func (l *LogicsObject) PutPart(ctx context.Context, bucketID, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (*interfaces.StoragePart, error) {
+	part, err := l.storage.UploadPart(ctx, bucketID, objectName, uploadID, partNumber, reader, size)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to upload part", []map[string]interface{}{
+			{"error": "Failed to upload part", "message": err.Error()},
+		})
+	}
+
+	return part, nil
+}
+
+func (l *LogicsObject) GetObject(ctx context.Context, bucketID, objectName string) (*interfaces.ObjectDownload, error) {
+	exists, err := l.storage.FileExists(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to check object existence", []map[string]interface{}{
+			{"error": "Failed to check object existence", "message": err.Error()},
+		})
+	}
+	if !exists {
+		return nil, common.NewHTTPError(http.StatusNotFound, "Object not found", nil)
+	}
+
+	info, err := l.storage.GetFileInfo(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get object info", []map[string]interface{}{
+			{"error": "Failed to get object info", "message": err.Error()},
+		})
+	}
+
+	reader, err := l.storage.Download(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to download object", []map[string]interface{}{
+			{"error": "Failed to download object", "message": err.Error()},
+		})
+	}
+
+	return &interfaces.ObjectDownload{
+		Info:   info,
+		Reader: reader,
+	}, nil
+}
+
+// sizeLimitReader 读取超过remaining字节时返回错误，避免超出策略的对象写入存储
+type sizeLimitReader struct {
+	reader    io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	if int64(n) > r.remaining {
+		r.exceeded = true
+		return 0, errors.New("object size exceeds the policy")
+	}
+	r.remaining -= int64(n)
+	return n, err
+}
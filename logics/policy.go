@@ -0,0 +1,338 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxFileSize 单次请求上传允许的最大文件大小，上传策略未配置maxSize时使用
+const MaxFileSize = 5 * 1024 * 1024 * 1024
+
+// defaultUploadPolicy 内置的默认上传策略，桶未配置上传策略或策略未配置某项时使用
+var defaultUploadPolicy = common.UploadPolicy{
+	AllowedExtensions: []string{
+		".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", // 图片
+		".mp4", ".avi", ".mov", ".wmv", ".flv", ".mkv", // 视频
+		".exe", ".msi", ".dmg", ".pkg", // 应用
+		".zip", ".rar", ".7z", ".tar", ".gz", // 压缩包
+		".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", // 文档
+		".txt", ".md", ".json", ".xml", ".csv", // 文本
+	},
+	FilenamePattern:  `^[^<>:"|?*\\/]+$`,
+	MaxFilenameBytes: 255,
+}
+
+var normalizationForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// uploadPolicy 编译后的上传策略
+type uploadPolicy struct {
+	bucketID          string
+	allowedExtensions []string // nil表示不限制
+	deniedExtensions  []string
+	allowedMIMETypes  []string
+	deniedMIMETypes   []string
+	minSize           int64
+	maxSize           int64 // 为0时使用各上传方式自身的上限
+	pattern           *regexp.Regexp
+	normalization     string
+	form              *norm.Form // nil表示不规范化
+	maxFilenameBytes  int
+}
+
+var (
+	uploadPoliciesOnce  sync.Once
+	uploadPolicies      map[string]*uploadPolicy
+	uploadPolicyDefault *uploadPolicy
+)
+
+// loadUploadPolicies 编译配置中各桶的上传策略，配置有误时panic，与配置文件解析失败的处理一致
+func loadUploadPolicies() map[string]*uploadPolicy {
+	uploadPoliciesOnce.Do(func() {
+		uploadPolicyDefault, _ = newUploadPolicy("", nil)
+		uploadPolicies = make(map[string]*uploadPolicy)
+		for bucketID, c := range config.UploadPolicies {
+			policy, err := newUploadPolicy(bucketID, c)
+			if err != nil {
+				panic(fmt.Errorf("invalid upload policy for bucket %s: %w", bucketID, err))
+			}
+			uploadPolicies[bucketID] = policy
+		}
+	})
+	return uploadPolicies
+}
+
+// uploadPolicyFor 返回桶的上传策略，未配置时使用默认策略
+func uploadPolicyFor(bucketID string) *uploadPolicy {
+	if policy, ok := loadUploadPolicies()[bucketID]; ok {
+		return policy
+	}
+	policy := *uploadPolicyDefault
+	policy.bucketID = bucketID
+	return &policy
+}
+
+func newUploadPolicy(bucketID string, c *common.UploadPolicy) (*uploadPolicy, error) {
+	if c == nil {
+		c = &common.UploadPolicy{}
+	}
+
+	policy := &uploadPolicy{
+		bucketID:         bucketID,
+		deniedExtensions: normalizeExtensions(c.DeniedExtensions),
+		allowedMIMETypes: normalizeMediaTypes(c.AllowedMIMETypes),
+		deniedMIMETypes:  normalizeMediaTypes(c.DeniedMIMETypes),
+		minSize:          c.MinSize,
+		maxSize:          c.MaxSize,
+		maxFilenameBytes: c.MaxFilenameBytes,
+	}
+	if policy.minSize < 0 || policy.maxSize < 0 || (policy.maxSize > 0 && policy.minSize > policy.maxSize) {
+		return nil, fmt.Errorf("invalid size range [%d, %d]", c.MinSize, c.MaxSize)
+	}
+	if policy.maxFilenameBytes <= 0 {
+		policy.maxFilenameBytes = defaultUploadPolicy.MaxFilenameBytes
+	}
+
+	allowedExtensions := c.AllowedExtensions
+	if allowedExtensions == nil {
+		allowedExtensions = defaultUploadPolicy.AllowedExtensions
+	}
+	for _, ext := range allowedExtensions {
+		if ext == "*" {
+			allowedExtensions = nil
+			break
+		}
+	}
+	policy.allowedExtensions = normalizeExtensions(allowedExtensions)
+
+	pattern := c.FilenamePattern
+	if pattern == "" {
+		pattern = defaultUploadPolicy.FilenamePattern
+	}
+	var err error
+	if policy.pattern, err = regexp.Compile(pattern); err != nil {
+		return nil, fmt.Errorf("invalid filename pattern: %w", err)
+	}
+
+	if c.UnicodeNormalization != "" {
+		policy.normalization = strings.ToUpper(c.UnicodeNormalization)
+		form, ok := normalizationForms[policy.normalization]
+		if !ok {
+			return nil, fmt.Errorf("unsupported unicode normalization %s", c.UnicodeNormalization)
+		}
+		policy.form = &form
+	}
+
+	return policy, nil
+}
+
+// normalizeExtensions 扩展名统一为小写并以.开头，配置时可省略.
+func normalizeExtensions(exts []string) []string {
+	if exts == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized = append(normalized, ext)
+	}
+	return normalized
+}
+
+func normalizeMediaTypes(mediaTypes []string) []string {
+	normalized := make([]string, 0, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(mediaType)))
+	}
+	return normalized
+}
+
+// sizeLimit 返回上传方式可接受的最大文件大小，策略未配置或超过上传方式自身的上限时使用该上限
+func (p *uploadPolicy) sizeLimit(limit int64) int64 {
+	if p.maxSize > 0 && p.maxSize < limit {
+		return p.maxSize
+	}
+	return limit
+}
+
+// checkFilename 规范化并校验文件名，返回规范化后的文件名
+func (p *uploadPolicy) checkFilename(filename string) (string, error) {
+	if p.form != nil {
+		filename = p.form.String(filename)
+	}
+
+	if filename == "" || len(filename) > p.maxFilenameBytes || !utf8.ValidString(filename) || !p.pattern.MatchString(filename) {
+		return "", common.NewHTTPError(http.StatusBadRequest, "Invalid filename", []map[string]interface{}{
+			{
+				"error":   "Invalid filename",
+				"message": fmt.Sprintf("file name %s is invalid", filename),
+			},
+		})
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if containsString(p.deniedExtensions, ext) || (p.allowedExtensions != nil && !containsString(p.allowedExtensions, ext)) {
+		return "", common.NewHTTPError(http.StatusBadRequest, "File extension is not allowed", []map[string]interface{}{
+			{
+				"error":   "File extension is not allowed",
+				"message": fmt.Sprintf("file extension %s is not allowed", ext),
+			},
+		})
+	}
+
+	return filename, nil
+}
+
+// checkSize 校验文件大小，size为-1表示长度未知，由上传完成后再校验
+func (p *uploadPolicy) checkSize(size, limit int64) error {
+	if size < 0 {
+		return nil
+	}
+
+	limit = p.sizeLimit(limit)
+	if size > limit {
+		return common.NewHTTPError(http.StatusBadRequest, "File size exceeds maximum allowed size", []map[string]interface{}{
+			{
+				"error":   "File size exceeds maximum allowed size",
+				"message": fmt.Sprintf("file size %d exceeds maximum allowed size %d", size, limit),
+			},
+		})
+	}
+	if size < p.minSize {
+		return common.NewHTTPError(http.StatusBadRequest, "File size is less than minimum allowed size", []map[string]interface{}{
+			{
+				"error":   "File size is less than minimum allowed size",
+				"message": fmt.Sprintf("file size %d is less than minimum allowed size %d", size, p.minSize),
+			},
+		})
+	}
+
+	return nil
+}
+
+// validate 校验文件名和大小，返回规范化后的文件名
+func (p *uploadPolicy) validate(filename string, size, limit int64) (string, error) {
+	filename, err := p.checkFilename(filename)
+	if err != nil {
+		return "", err
+	}
+	if err = p.checkSize(size, limit); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// checkDeclaredType 校验客户端声明的类型，声明为空或application/octet-stream时留待嗅探后校验
+func (p *uploadPolicy) checkDeclaredType(contentType string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "application/octet-stream" {
+		return nil
+	}
+
+	listed := func(list []string) bool {
+		for _, pattern := range list {
+			if mediaTypeMatches(pattern, mediaType) {
+				return true
+			}
+		}
+		return false
+	}
+	if listed(p.deniedMIMETypes) || (len(p.allowedMIMETypes) > 0 && !listed(p.allowedMIMETypes)) {
+		return mimeTypeNotAllowed(mediaType)
+	}
+	return nil
+}
+
+// checkContentType 校验嗅探到的内容类型与扩展名是否一致以及是否为策略允许的类型，detected为nil表示内容为空，不校验
+func (p *uploadPolicy) checkContentType(filename string, detected *mimetype.MIME) error {
+	if err := checkContentType(filename, detected); err != nil || detected == nil {
+		return err
+	}
+
+	if mimeTypeListed(detected, p.deniedMIMETypes) || (len(p.allowedMIMETypes) > 0 && !mimeTypeListed(detected, p.allowedMIMETypes)) {
+		return mimeTypeNotAllowed(detected.String())
+	}
+	return nil
+}
+
+// mimeTypeListed 嗅探到的类型或其上级类型(不含application/octet-stream)命中列表中任一项
+func mimeTypeListed(detected *mimetype.MIME, list []string) bool {
+	for m := detected; m != nil; m = m.Parent() {
+		if m != detected && m.Parent() == nil {
+			break
+		}
+		mediaType, _, _ := mime.ParseMediaType(m.String())
+		for _, pattern := range list {
+			// Is会同时比较类型的别名
+			if mediaTypeMatches(pattern, mediaType) || m.Is(pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mediaTypeMatches 比较媒体类型，pattern可以是image/*形式的通配
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return pattern == mediaType
+}
+
+func mimeTypeNotAllowed(mediaType string) error {
+	return common.NewHTTPError(http.StatusBadRequest, "File content type is not allowed", []map[string]interface{}{
+		{
+			"error":   "File content type is not allowed",
+			"message": fmt.Sprintf("file content type %s is not allowed", mediaType),
+		},
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// toInterface 转换为接口返回的策略，列表为nil时返回空列表
+func (p *uploadPolicy) toInterface() *interfaces.UploadPolicy {
+	allowedExtensions := p.allowedExtensions
+	if allowedExtensions == nil {
+		allowedExtensions = []string{"*"}
+	}
+	return &interfaces.UploadPolicy{
+		BucketID:             p.bucketID,
+		AllowedExtensions:    allowedExtensions,
+		DeniedExtensions:     append([]string{}, p.deniedExtensions...),
+		AllowedMIMETypes:     p.allowedMIMETypes,
+		DeniedMIMETypes:      p.deniedMIMETypes,
+		MinSize:              p.minSize,
+		MaxSize:              p.sizeLimit(MaxFileSize),
+		MaxMultipartSize:     p.sizeLimit(MaxMultipartFileSize),
+		FilenamePattern:      p.pattern.String(),
+		UnicodeNormalization: p.normalization,
+		MaxFilenameBytes:     p.maxFilenameBytes,
+	}
+}
@@ -0,0 +1,102 @@
+package logics
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+)
+
+// rekeyBatchSize 迁移对象名时每次读取的对象数
+const rekeyBatchSize = 100
+
+// objectKeyPattern newObjectKey生成的对象名
+var objectKeyPattern = regexp.MustCompile(`^[0-9a-f]{2}/[0-9a-f]{2}/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// RekeyResult 对象名迁移的结果
+type RekeyResult struct {
+	Rekeyed int // 迁移到新对象名的对象数
+	Skipped int // 存储中不存在而跳过的对象数，如尚未完成的预签名上传
+}
+
+// RekeyObjects 将对象名不是按ID分片生成的对象(早期以文件名或"文件名_时间戳_随机串"为对象名)复制到新的对象名，
+// 更新引用它的文件记录后删除原对象。已迁移的对象不会重复处理，中断后可重新执行
+func RekeyObjects(ctx context.Context) (*RekeyResult, error) {
+	result := &RekeyResult{}
+
+	var after *interfaces.StorageObject
+	for {
+		objects, err := dbFile.ListObjects(ctx, after, rekeyBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, object := range objects {
+			if objectKeyPattern.MatchString(object.ObjectName) {
+				continue
+			}
+
+			newName, err := rekeyObject(ctx, object)
+			if err != nil {
+				return result, fmt.Errorf("failed to rekey object %s/%s: %w", object.BucketID, object.ObjectName, err)
+			}
+			if newName == "" {
+				log.Printf("[WARN] object %s/%s does not exist in storage, skipped", object.BucketID, object.ObjectName)
+				result.Skipped++
+				continue
+			}
+			log.Printf("[INFO] rekeyed object %s/%s to %s", object.BucketID, object.ObjectName, newName)
+			result.Rekeyed++
+		}
+
+		if len(objects) < rekeyBatchSize {
+			return result, nil
+		}
+		after = objects[len(objects)-1]
+	}
+}
+
+// rekeyObject 复制对象到新的对象名并更新文件记录，对象不存在时返回空字符串
+func rekeyObject(ctx context.Context, object *interfaces.StorageObject) (string, error) {
+	exists, err := storageAdapter.FileExists(ctx, object.BucketID, object.ObjectName)
+	if err != nil || !exists {
+		return "", err
+	}
+	info, err := storageAdapter.GetFileInfo(ctx, object.BucketID, object.ObjectName)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := storageAdapter.Download(ctx, object.BucketID, object.ObjectName)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	newName := newObjectKey()
+	if err = storageAdapter.Upload(ctx, object.BucketID, newName, reader, info.Size, info.ContentType); err != nil {
+		return "", err
+	}
+
+	if _, err = dbFile.UpdateObjectName(ctx, object.BucketID, object.ObjectName, newName); err != nil {
+		storageAdapter.Delete(ctx, object.BucketID, newName)
+		return "", err
+	}
+
+	// 更新后仍有引用说明迁移期间有秒传引用了原对象，保留原对象，再次执行时迁移
+	// 加锁检查，与秒传创建记录后的引用确认互斥，秒传方看不到原对象的引用时会撤销记录
+	referenced, err := dbFile.HasObjectReference(ctx, object.BucketID, object.ObjectName, "")
+	if err != nil {
+		return "", err
+	}
+	if referenced {
+		log.Printf("[WARN] object %s/%s is referenced by new files, kept", object.BucketID, object.ObjectName)
+		return newName, nil
+	}
+	if err = storageAdapter.Delete(ctx, object.BucketID, object.ObjectName); err != nil {
+		log.Printf("[WARN] failed to delete rekeyed object %s/%s: %v", object.BucketID, object.ObjectName, err)
+	}
+
+	return newName, nil
+}
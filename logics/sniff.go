@@ -0,0 +1,148 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// extensionContentTypes 允许的扩展名对应的内容类型，嗅探结果或其上级类型(如docx的上级zip)命中任一即视为一致
+var extensionContentTypes = map[string][]string{
+	// 图片
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".png":  {"image/png"},
+	".gif":  {"image/gif"},
+	".bmp":  {"image/bmp"},
+	".webp": {"image/webp"},
+	// 视频
+	".mp4": {"video/mp4", "video/x-m4v", "audio/mp4"},
+	".avi": {"video/x-msvideo"},
+	".mov": {"video/quicktime"},
+	".wmv": {"video/x-ms-asf"},
+	".flv": {"video/x-flv"},
+	".mkv": {"video/x-matroska"},
+	// 应用，dmg没有固定的文件头，无法识别时为application/octet-stream
+	".exe": {"application/vnd.microsoft.portable-executable"},
+	".msi": {"application/x-ms-installer", "application/x-ole-storage"},
+	".dmg": {"application/octet-stream"},
+	".pkg": {"application/x-xar"},
+	// 压缩包
+	".zip": {"application/zip"},
+	".rar": {"application/x-rar-compressed"},
+	".7z":  {"application/x-7z-compressed"},
+	".tar": {"application/x-tar"},
+	".gz":  {"application/gzip"},
+	// 文档，旧版Office文档可能只识别为OLE复合文档，新版为zip
+	".pdf":  {"application/pdf"},
+	".doc":  {"application/msword", "application/x-ole-storage"},
+	".xls":  {"application/vnd.ms-excel", "application/x-ole-storage"},
+	".ppt":  {"application/vnd.ms-powerpoint", "application/x-ole-storage"},
+	".docx": {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/zip"},
+	".xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "application/zip"},
+	".pptx": {"application/vnd.openxmlformats-officedocument.presentationml.presentation", "application/zip"},
+	// 文本
+	".txt":  {"text/plain"},
+	".md":   {"text/plain"},
+	".json": {"application/json", "text/plain"},
+	".xml":  {"text/xml", "text/plain"},
+	".csv":  {"text/csv", "text/plain"},
+}
+
+// activeContentTypes 浏览器会执行脚本的类型，必须由扩展名明确允许，不能通过上级类型text/plain匹配
+var activeContentTypes = []string{"text/html", "image/svg+xml"}
+
+// checkContentType 校验嗅探到的内容类型与扩展名是否一致，detected为nil表示内容为空，不校验
+func checkContentType(filename string, detected *mimetype.MIME) error {
+	if detected == nil {
+		return nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	expected, ok := extensionContentTypes[ext]
+	if !ok || contentTypeMatches(detected, expected) {
+		return nil
+	}
+
+	return common.NewHTTPError(http.StatusBadRequest, "File content does not match extension", []map[string]interface{}{
+		{
+			"error":   "File content does not match extension",
+			"message": fmt.Sprintf("file extension %s does not match detected content type %s", ext, detected.String()),
+		},
+	})
+}
+
+func contentTypeMatches(detected *mimetype.MIME, expected []string) bool {
+	for _, active := range activeContentTypes {
+		if detected.Is(active) && !containsMediaType(expected, active) {
+			return false
+		}
+	}
+
+	// 所有类型的最上级都是application/octet-stream，只有无法识别的内容才与其匹配
+	for m := detected; m != nil; m = m.Parent() {
+		if m != detected && m.Parent() == nil {
+			break
+		}
+		for _, e := range expected {
+			if m.Is(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsMediaType(list []string, mediaType string) bool {
+	for _, item := range list {
+		if sameMediaType(item, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupContentType 按记录的类型查找，历史文件记录的可能是客户端声明的未知类型，此时返回nil不校验
+func lookupContentType(contentType string) *mimetype.MIME {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "application/octet-stream" {
+		return nil
+	}
+	return mimetype.Lookup(mediaType)
+}
+
+// auditContentType 以结构化审计事件记录客户端声明的类型和嗅探到的类型，便于追查伪装扩展名的上传
+func auditContentType(filename, declared string, detected *mimetype.MIME, err error) {
+	detectedType := ""
+	if detected != nil {
+		detectedType = detected.String()
+	}
+	attrs := []any{
+		slog.String("event", "content_type_check"),
+		slog.String("result", "accepted"),
+		slog.String("file", filename),
+		slog.String("declared", declared),
+		slog.String("detected", detectedType),
+	}
+	if err != nil {
+		attrs[1] = slog.String("result", "rejected")
+		attrs = append(attrs, slog.String("reason", auditReason(err)))
+	}
+	auditLogger.Info("content type check", attrs...)
+}
+
+// auditReason 取拒绝原因，HTTPError使用其对外的消息
+func auditReason(err error) string {
+	var httpErr *common.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,118 @@
+package logics
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errForbiddenAddress 导入时目标地址不是公网地址
+var errForbiddenAddress = errors.New("destination address is not allowed")
+
+// blockedNetworks IsPrivate、IsLoopback等方法未覆盖的保留地址段
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // 本网络
+	"100.64.0.0/10",   // 运营商级NAT
+	"192.0.0.0/24",    // IETF协议分配
+	"192.0.2.0/24",    // 文档示例
+	"198.18.0.0/15",   // 网络基准测试
+	"198.51.100.0/24", // 文档示例
+	"203.0.113.0/24",  // 文档示例
+	"240.0.0.0/4",     // 保留地址及广播地址
+	"64:ff9b::/96",    // NAT64，可映射到内网IPv4地址
+	"64:ff9b:1::/48",  // 本地NAT64
+	"2001:db8::/32",   // 文档示例
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isPublicIP 是否为公网地址，内网、本机、链路本地(含云厂商元数据服务169.254.169.254)、组播和保留地址均不是
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSourceURL 校验源URL的协议和主机，主机为IP地址时同时校验地址，域名在建立连接时按解析结果校验
+func checkSourceURL(u *url.URL, allowedSchemes []string, allowPrivate bool) error {
+	if !containsString(allowedSchemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("host is required")
+	}
+	if u.User != nil {
+		// 凭据会随任务保存并在查询任务时返回
+		return errors.New("credentials in URL are not supported")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !allowPrivate && !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// newImportClient 创建导入使用的HTTP客户端。目标地址在DNS解析之后、建立连接之前校验，
+// 重定向后的地址和DNS重绑定返回的地址同样受限；不使用环境变量中的代理，否则只能校验代理地址
+func newImportClient(allowedSchemes []string, allowPrivate bool, maxRedirects int) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", errForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConns:          10,
+			// 按原样保存源文件，Content-Length也与实际读取的长度一致
+			DisableCompression: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkSourceURL(req.URL, allowedSchemes, allowPrivate)
+		},
+	}
+}
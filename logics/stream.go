@@ -0,0 +1,156 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 类型嗅探预读的字节数，与mimetype默认的检测长度一致
+const sniffLength = 3072
+
+var errStreamTooLarge = errors.New("upload stream exceeds maximum allowed size")
+
+// uploadStream 在写入存储的同一次读取中完成大小校验和SHA-256计算，并记录数据流本身的读取错误，
+// 开头的数据预读后用于识别内容类型，不会落盘暂存
+type uploadStream struct {
+	reader   io.Reader
+	limit    int64
+	size     int64
+	hash     hash.Hash
+	detected *mimetype.MIME // 嗅探到的内容类型，内容为空时为nil
+	eof      bool
+	exceeded bool
+	err      error // 读取源数据时的错误，不含io.EOF
+}
+
+func newUploadStream(src io.Reader, limit int64) *uploadStream {
+	buffered := bufio.NewReaderSize(src, sniffLength)
+	// 预读出错时错误会保留在bufio中，后续读取时再返回
+	head, _ := buffered.Peek(sniffLength)
+
+	stream := &uploadStream{
+		reader: buffered,
+		limit:  limit,
+		hash:   sha256.New(),
+	}
+	if len(head) > 0 {
+		stream.detected = mimetype.Detect(head)
+	}
+	return stream
+}
+
+func (s *uploadStream) Read(p []byte) (int, error) {
+	if s.exceeded {
+		return 0, errStreamTooLarge
+	}
+
+	n, err := s.reader.Read(p)
+	s.size += int64(n)
+	if s.size > s.limit {
+		s.exceeded = true
+		return 0, errStreamTooLarge
+	}
+	s.hash.Write(p[:n])
+
+	if err == io.EOF {
+		s.eof = true
+	} else if err != nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// ContentType 以嗅探结果为准，内容为空无法嗅探时使用客户端声明的类型
+func (s *uploadStream) ContentType(declared string) string {
+	if s.detected != nil {
+		return s.detected.String()
+	}
+	if declared == "" {
+		return "application/octet-stream"
+	}
+	return declared
+}
+
+// SHA256 返回已读取数据的SHA-256，仅在数据流读取完毕后有意义
+func (s *uploadStream) SHA256() string {
+	return hex.EncodeToString(s.hash.Sum(nil))
+}
+
+// Error 写入存储失败时区分数据流本身的问题和存储错误，expectedSize为-1表示长度未知
+func (s *uploadStream) Error(storageErr error, expectedSize int64) error {
+	switch {
+	case s.exceeded:
+		return common.NewHTTPError(http.StatusBadRequest, "File size exceeds maximum allowed size", []map[string]interface{}{
+			{
+				"error":   "File size exceeds maximum allowed size",
+				"message": fmt.Sprintf("file size exceeds maximum allowed size %d", s.limit),
+			},
+		})
+	case errors.Is(s.err, io.ErrUnexpectedEOF) || (s.eof && expectedSize >= 0 && s.size < expectedSize):
+		message := fmt.Sprintf("upload stream ended unexpectedly after %d bytes", s.size)
+		if expectedSize >= 0 {
+			message = fmt.Sprintf("upload stream ended unexpectedly after %d of %d bytes", s.size, expectedSize)
+		}
+		return common.NewHTTPError(http.StatusBadRequest, "Upload stream truncated", []map[string]interface{}{
+			{
+				"error":   "Upload stream truncated",
+				"message": message,
+			},
+		})
+	case s.err != nil:
+		return common.NewHTTPError(http.StatusBadRequest, "Failed to read upload stream", []map[string]interface{}{
+			{
+				"error":   "Failed to read upload stream",
+				"message": s.err.Error(),
+			},
+		})
+	default:
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to upload file to storage", []map[string]interface{}{
+			{
+				"error":   "Failed to upload file to storage",
+				"message": storageErr.Error(),
+			},
+		})
+	}
+}
+
+// inspectObject 读取一遍存储中的对象，计算SHA-256并嗅探内容类型
+func inspectObject(ctx context.Context, storage interfaces.StorageAdapter, bucketID, objectName string) (*uploadStream, error) {
+	reader, err := storage.Download(ctx, bucketID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	stream := newUploadStream(reader, math.MaxInt64)
+	if _, err = io.Copy(io.Discard, stream); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// checkSHA256 校验客户端期望的摘要，expected为空表示不校验
+func checkSHA256(expected, actual string) error {
+	if expected == "" || strings.EqualFold(expected, actual) {
+		return nil
+	}
+	return common.NewHTTPError(http.StatusBadRequest, "Digest mismatch", []map[string]interface{}{
+		{
+			"error":   "Digest mismatch",
+			"message": fmt.Sprintf("expected sha-256 %s, got %s", expected, actual),
+		},
+	})
+}
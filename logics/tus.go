@@ -0,0 +1,453 @@
+package logics
+
+import (
+	"FileEngine/common"
+	"FileEngine/interfaces"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// tus分片在存储中的对象名前缀
+	tusPartPrefix = ".tus"
+	// 未携带校验和时，每接收tusChunkSize字节提交一个分片，连接中断后已提交的数据不会丢失
+	tusChunkSize = 8 * 1024 * 1024
+	// StatusChecksumMismatch tus checksum扩展定义的校验失败状态码
+	StatusChecksumMismatch = 460
+	defaultTusExpiration   = 24 * time.Hour
+	// tusSweepInterval 清理过期上传的间隔
+	tusSweepInterval = 10 * time.Minute
+	// tusSweepBatch 每次查询的过期上传数
+	tusSweepBatch = 100
+)
+
+// TusChecksumAlgorithms 支持的校验和算法
+var TusChecksumAlgorithms = []string{"md5", "sha1", "sha256"}
+
+type LogicsTus struct {
+	defaultBucketID string
+	expiration      time.Duration
+	dbFile          interfaces.DBFile
+	dbTusUpload     interfaces.DBTusUpload
+	storage         interfaces.StorageAdapter
+	logicsFile      *LogicsFile
+}
+
+var (
+	logicsTusOnce sync.Once
+	logicsTus     *LogicsTus
+)
+
+func NewLogicsTus() interfaces.LogicsTus {
+	logicsTusOnce.Do(func() {
+		expiration := defaultTusExpiration
+		if config.Tus != nil && config.Tus.Expiration > 0 {
+			expiration = config.Tus.Expiration
+		}
+
+		logicsTus = &LogicsTus{
+			defaultBucketID: config.DefaultBucketID(),
+			expiration:      expiration,
+			dbFile:          dbFile,
+			dbTusUpload:     dbTusUpload,
+			storage:         storageAdapter,
+			logicsFile:      NewLogicsFile().(*LogicsFile),
+		}
+		go logicsTus.sweep()
+	})
+	return logicsTus
+}
+
+// MaxSize 默认桶的上传策略允许的最大文件大小
+func (l *LogicsTus) MaxSize() int64 {
+	return uploadPolicyFor(l.defaultBucketID).sizeLimit(MaxFileSize)
+}
+
+func (l *LogicsTus) CreateUpload(ctx context.Context, filename, contentType string, size int64, conflict, metadata string) (*interfaces.TusUpload, error) {
+	policy := uploadPolicyFor(l.defaultBucketID)
+	filename, err := policy.checkFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err = policy.checkSize(size, MaxFileSize); err != nil {
+		// tus协议要求超过最大长度时返回413
+		if httpErr, ok := err.(*common.HTTPError); ok && size > policy.sizeLimit(MaxFileSize) {
+			httpErr.Code = http.StatusRequestEntityTooLarge
+		}
+		return nil, err
+	}
+
+	// 提前检查同名文件，避免上传完成后才失败，完成时按conflict再次处理
+	if conflict, err = parseConflict(conflict); err != nil {
+		return nil, err
+	}
+	if err = l.logicsFile.checkNameAvailable(ctx, filename, conflict); err != nil {
+		return nil, err
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if err = policy.checkDeclaredType(contentType); err != nil {
+		return nil, err
+	}
+
+	upload := &interfaces.TusUpload{
+		ID:          uuid.New().String(),
+		BucketID:    l.defaultBucketID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		Metadata:    metadata,
+		Conflict:    conflict,
+		ExpireAt:    time.Now().Add(l.expiration).Unix(),
+	}
+	if err = l.dbTusUpload.CreateTusUpload(ctx, upload); err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to create upload", []map[string]interface{}{
+			{"error": "Failed to create upload", "message": err.Error()},
+		})
+	}
+
+	// 空文件无需追加数据，直接完成
+	if size == 0 {
+		return l.complete(ctx, upload)
+	}
+
+	return upload, nil
+}
+
+func (l *LogicsTus) GetUpload(ctx context.Context, uploadID string) (*interfaces.TusUpload, error) {
+	upload, err := l.dbTusUpload.GetTusUpload(ctx, uploadID)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get upload", []map[string]interface{}{
+			{"error": "Failed to get upload", "message": err.Error()},
+		})
+	}
+	if upload == nil {
+		return nil, common.NewHTTPError(http.StatusNotFound, "Upload not found", nil)
+	}
+	// 过期的上传在清理前也不能继续追加
+	if upload.FileID == "" && time.Now().Unix() >= upload.ExpireAt {
+		return nil, common.NewHTTPError(http.StatusGone, "Upload expired", nil)
+	}
+
+	return upload, nil
+}
+
+func (l *LogicsTus) AppendUpload(ctx context.Context, uploadID string, offset, length int64, reader io.Reader, checksum *interfaces.UploadChecksum) (*interfaces.TusUpload, error) {
+	upload, err := l.GetUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.FileID != "" || upload.Offset != offset {
+		return nil, common.NewHTTPError(http.StatusConflict, "Upload offset mismatch", []map[string]interface{}{
+			{"error": "Upload offset mismatch", "message": fmt.Sprintf("current offset is %d, got %d", upload.Offset, offset)},
+		})
+	}
+
+	remaining := upload.Size - upload.Offset
+	if length > remaining {
+		return nil, common.NewHTTPError(http.StatusRequestEntityTooLarge, "Upload exceeds Upload-Length", []map[string]interface{}{
+			{"error": "Upload exceeds Upload-Length", "message": fmt.Sprintf("%d bytes remaining, got %d", remaining, length)},
+		})
+	}
+	body := io.LimitReader(reader, remaining)
+
+	if checksum != nil {
+		err = l.appendWithChecksum(ctx, upload, body, checksum)
+	} else {
+		err = l.appendInChunks(ctx, upload, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 数据接收完整后合并分片生成文件；合并失败时可再次发送空的PATCH请求重试
+	if upload.Offset == upload.Size {
+		return l.complete(ctx, upload)
+	}
+
+	return upload, nil
+}
+
+func (l *LogicsTus) TerminateUpload(ctx context.Context, uploadID string) error {
+	upload, err := l.GetUpload(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+
+	parts, err := l.dbTusUpload.GetTusParts(ctx, upload.ID)
+	if err != nil {
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to get upload parts", []map[string]interface{}{
+			{"error": "Failed to get upload parts", "message": err.Error()},
+		})
+	}
+
+	if err = l.dbTusUpload.DeleteTusUpload(ctx, upload.ID); err != nil {
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to delete upload", []map[string]interface{}{
+			{"error": "Failed to delete upload", "message": err.Error()},
+		})
+	}
+
+	l.deleteParts(ctx, upload.BucketID, parts)
+	return nil
+}
+
+// appendInChunks 分块提交数据，连接中断时保留已完整接收的分块
+func (l *LogicsTus) appendInChunks(ctx context.Context, upload *interfaces.TusUpload, body io.Reader) error {
+	buf := make([]byte, tusChunkSize)
+	for {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			if err := l.commitPart(ctx, upload, bytes.NewReader(buf[:n]), int64(n)); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if readErr != nil {
+			return common.NewHTTPError(http.StatusBadRequest, "Failed to read request body", []map[string]interface{}{
+				{"error": "Failed to read request body", "message": readErr.Error()},
+			})
+		}
+	}
+}
+
+// appendWithChecksum 整体接收请求体并校验，校验失败时丢弃本次数据
+func (l *LogicsTus) appendWithChecksum(ctx context.Context, upload *interfaces.TusUpload, body io.Reader, checksum *interfaces.UploadChecksum) error {
+	h, ok := newChecksumHash(checksum.Algorithm)
+	if !ok {
+		return common.NewHTTPError(http.StatusBadRequest, "Unsupported checksum algorithm", []map[string]interface{}{
+			{"error": "Unsupported checksum algorithm", "message": fmt.Sprintf("checksum algorithm %s is not supported", checksum.Algorithm)},
+		})
+	}
+
+	counter := &countingReader{reader: io.TeeReader(body, h)}
+	objectName := l.partObjectName(upload.ID)
+	if err := l.storage.Upload(ctx, upload.BucketID, objectName, counter, -1, "application/offset+octet-stream"); err != nil {
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to store upload chunk", []map[string]interface{}{
+			{"error": "Failed to store upload chunk", "message": err.Error()},
+		})
+	}
+
+	if !bytes.Equal(h.Sum(nil), checksum.Sum) {
+		l.storage.Delete(ctx, upload.BucketID, objectName)
+		return common.NewHTTPError(StatusChecksumMismatch, "Checksum Mismatch", []map[string]interface{}{
+			{"error": "Checksum Mismatch", "message": fmt.Sprintf("%s checksum of the uploaded chunk does not match", checksum.Algorithm)},
+		})
+	}
+
+	if counter.n == 0 {
+		l.storage.Delete(ctx, upload.BucketID, objectName)
+		return nil
+	}
+
+	return l.recordPart(ctx, upload, objectName, counter.n)
+}
+
+// commitPart 将分块写入存储并记录分片
+func (l *LogicsTus) commitPart(ctx context.Context, upload *interfaces.TusUpload, reader io.Reader, size int64) error {
+	objectName := l.partObjectName(upload.ID)
+	if err := l.storage.Upload(ctx, upload.BucketID, objectName, reader, size, "application/offset+octet-stream"); err != nil {
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to store upload chunk", []map[string]interface{}{
+			{"error": "Failed to store upload chunk", "message": err.Error()},
+		})
+	}
+
+	return l.recordPart(ctx, upload, objectName, size)
+}
+
+// recordPart 记录分片并推进偏移量，与其他请求并发写入冲突时删除本次分片
+func (l *LogicsTus) recordPart(ctx context.Context, upload *interfaces.TusUpload, objectName string, size int64) error {
+	part := &interfaces.TusPart{
+		UploadID:   upload.ID,
+		Offset:     upload.Offset,
+		Size:       size,
+		ObjectName: objectName,
+	}
+	expireAt := time.Now().Add(l.expiration).Unix()
+	if err := l.dbTusUpload.AppendTusPart(ctx, part, expireAt); err != nil {
+		l.storage.Delete(ctx, upload.BucketID, objectName)
+		if errors.Is(err, interfaces.ErrOffsetConflict) {
+			return common.NewHTTPError(http.StatusConflict, "Upload offset mismatch", []map[string]interface{}{
+				{"error": "Upload offset mismatch", "message": "upload was modified by another request"},
+			})
+		}
+		return common.NewHTTPError(http.StatusInternalServerError, "Failed to record upload chunk", []map[string]interface{}{
+			{"error": "Failed to record upload chunk", "message": err.Error()},
+		})
+	}
+
+	upload.Offset += size
+	upload.ExpireAt = expireAt
+	return nil
+}
+
+// complete 按顺序合并分片生成文件记录，并清理分片
+func (l *LogicsTus) complete(ctx context.Context, upload *interfaces.TusUpload) (*interfaces.TusUpload, error) {
+	parts, err := l.dbTusUpload.GetTusParts(ctx, upload.ID)
+	if err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to get upload parts", []map[string]interface{}{
+			{"error": "Failed to get upload parts", "message": err.Error()},
+		})
+	}
+
+	reader := &partsReader{ctx: ctx, storage: l.storage, bucketID: upload.BucketID, parts: parts}
+	defer reader.Close()
+
+	fileInfo, err := l.logicsFile.UploadFromReader(ctx, upload.Filename, upload.ContentType, upload.Size, "", upload.Conflict, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = l.dbTusUpload.CompleteTusUpload(ctx, upload.ID, fileInfo.ID); err != nil {
+		return nil, common.NewHTTPError(http.StatusInternalServerError, "Failed to complete upload", []map[string]interface{}{
+			{"error": "Failed to complete upload", "message": err.Error()},
+		})
+	}
+	upload.FileID = fileInfo.ID
+
+	l.deleteParts(ctx, upload.BucketID, parts)
+	return upload, nil
+}
+
+// sweep 定期清理过期的上传：删除上传记录后删除已上传的分片，已完成的上传只删除记录
+func (l *LogicsTus) sweep() {
+	ticker := time.NewTicker(tusSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.deleteExpiredUploads()
+	}
+}
+
+func (l *LogicsTus) deleteExpiredUploads() {
+	ctx, cancel := context.WithTimeout(context.Background(), tusSweepInterval)
+	defer cancel()
+
+	deleted := 0
+	for {
+		before := deleted
+		uploads, err := l.dbTusUpload.GetExpiredTusUploads(ctx, time.Now().Unix(), tusSweepBatch)
+		if err != nil {
+			log.Printf("[WARN] failed to get expired tus uploads: %v", err)
+			break
+		}
+
+		for _, upload := range uploads {
+			parts, err := l.dbTusUpload.GetTusParts(ctx, upload.ID)
+			if err == nil {
+				err = l.dbTusUpload.DeleteTusUpload(ctx, upload.ID)
+			}
+			if err != nil {
+				log.Printf("[WARN] failed to delete expired tus upload %s: %v", upload.ID, err)
+				continue
+			}
+			l.deleteParts(ctx, upload.BucketID, parts)
+			deleted++
+		}
+		// 本批全部删除失败时不再重复查询
+		if len(uploads) < tusSweepBatch || deleted == before {
+			break
+		}
+	}
+	if deleted > 0 {
+		log.Printf("[INFO] deleted %d expired tus uploads", deleted)
+	}
+}
+
+func (l *LogicsTus) deleteParts(ctx context.Context, bucketID string, parts []*interfaces.TusPart) {
+	for _, part := range parts {
+		if err := l.storage.Delete(ctx, bucketID, part.ObjectName); err != nil {
+			log.Printf("[WARN] failed to delete tus part %s: %v", part.ObjectName, err)
+		}
+	}
+}
+
+func (l *LogicsTus) partObjectName(uploadID string) string {
+	return fmt.Sprintf("%s/%s/%s", tusPartPrefix, uploadID, uuid.New().String())
+}
+
+func newChecksumHash(algorithm string) (hash.Hash, bool) {
+	switch algorithm {
+	case "md5":
+		return md5.New(), true
+	case "sha1":
+		return sha1.New(), true
+	case "sha256":
+		return sha256.New(), true
+	default:
+		return nil, false
+	}
+}
+
+// countingReader 统计已读取的字节数
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// partsReader 按顺序读取各分片对象，读到某个分片时才从存储中打开
+type partsReader struct {
+	ctx      context.Context
+	storage  interfaces.StorageAdapter
+	bucketID string
+	parts    []*interfaces.TusPart
+	current  io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			reader, err := r.storage.Download(r.ctx, r.bucketID, r.parts[0].ObjectName)
+			if err != nil {
+				return 0, fmt.Errorf("failed to open upload part %s: %w", r.parts[0].ObjectName, err)
+			}
+			r.current = reader
+			r.parts = r.parts[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package logics
+
+import (
+	"FileEngine/interfaces"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTusAppendOffsets(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	tus := e.tus()
+
+	upload, err := tus.CreateUpload(ctx, "a.txt", "text/plain", 10, "", "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		offset     int64
+		data       string
+		wantStatus int
+		wantOffset int64
+	}{
+		{"first chunk", 0, "hello", 0, 5},
+		{"stale offset", 0, "hello", http.StatusConflict, 5},
+		{"offset ahead", 7, "abc", http.StatusConflict, 5},
+		{"exceeds upload length", 5, "world and more", http.StatusRequestEntityTooLarge, 5},
+		{"empty chunk", 5, "", 0, 5},
+		{"last chunk", 5, "world", 0, 10},
+		{"after completion", 10, "", http.StatusConflict, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tus.AppendUpload(ctx, upload.ID, tt.offset, int64(len(tt.data)), strings.NewReader(tt.data), nil)
+			if httpStatus(err) != tt.wantStatus || (tt.wantStatus == 0) != (err == nil) {
+				t.Fatalf("AppendUpload() error = %v, want status %d", err, tt.wantStatus)
+			}
+			current, err := e.dbTusUpload.GetTusUpload(ctx, upload.ID)
+			if err != nil || current.Offset != tt.wantOffset {
+				t.Fatalf("offset = %v (err %v), want %d", current, err, tt.wantOffset)
+			}
+		})
+	}
+
+	current, err := tus.GetUpload(ctx, upload.ID)
+	if err != nil || current.FileID == "" {
+		t.Fatalf("GetUpload() = %+v, %v, want completed upload", current, err)
+	}
+	file, err := e.file.GetMeta(ctx, current.FileID)
+	if err != nil {
+		t.Fatalf("GetMeta() error = %v", err)
+	}
+	if got := e.content(t, file); got != "helloworld" {
+		t.Fatalf("file content = %q, want %q", got, "helloworld")
+	}
+	// 完成后分片被清理，只剩合并后的对象
+	if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != file.ObjectName {
+		t.Fatalf("objects = %v, want only %s", objects, file.ObjectName)
+	}
+}
+
+// 两个请求读取到相同的偏移量后并发写入，后记录的分片与已记录的分片冲突，返回409并删除本次写入的分片
+func TestTusConcurrentAppend(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	tus := e.tus()
+
+	upload, err := tus.CreateUpload(ctx, "a.txt", "text/plain", 10, "", "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	stale, err := tus.GetUpload(ctx, upload.ID)
+	if err != nil {
+		t.Fatalf("GetUpload() error = %v", err)
+	}
+	if _, err = tus.AppendUpload(ctx, upload.ID, 0, 5, strings.NewReader("hello"), nil); err != nil {
+		t.Fatalf("AppendUpload() error = %v", err)
+	}
+
+	err = tus.commitPart(ctx, stale, strings.NewReader("HELLO"), 5)
+	if httpStatus(err) != http.StatusConflict {
+		t.Fatalf("commitPart() with stale offset error = %v, want 409", err)
+	}
+	parts, err := e.dbTusUpload.GetTusParts(ctx, upload.ID)
+	if err != nil || len(parts) != 1 {
+		t.Fatalf("GetTusParts() = %d parts, %v, want 1", len(parts), err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != parts[0].ObjectName {
+		t.Fatalf("objects = %v, want only the recorded part %s", objects, parts[0].ObjectName)
+	}
+}
+
+func TestTusExpiration(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	tus := e.tus()
+
+	expired, err := tus.CreateUpload(ctx, "expired.txt", "text/plain", 10, "", "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	// 追加数据时延长有效期，有效期为负时追加后立即过期
+	tus.expiration = -time.Minute
+	if _, err = tus.AppendUpload(ctx, expired.ID, 0, 5, strings.NewReader("hello"), nil); err != nil {
+		t.Fatalf("AppendUpload() error = %v", err)
+	}
+	tus.expiration = time.Hour
+	live, err := tus.CreateUpload(ctx, "live.txt", "text/plain", 10, "", "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	if _, err = tus.AppendUpload(ctx, live.ID, 0, 5, strings.NewReader("hello"), nil); err != nil {
+		t.Fatalf("AppendUpload() error = %v", err)
+	}
+
+	if _, err = tus.GetUpload(ctx, expired.ID); httpStatus(err) != http.StatusGone {
+		t.Fatalf("GetUpload() of expired upload error = %v, want 410", err)
+	}
+	if _, err = tus.AppendUpload(ctx, expired.ID, 5, 5, strings.NewReader("world"), nil); httpStatus(err) != http.StatusGone {
+		t.Fatalf("AppendUpload() to expired upload error = %v, want 410", err)
+	}
+
+	tus.deleteExpiredUploads()
+	if upload, err := e.dbTusUpload.GetTusUpload(ctx, expired.ID); err != nil || upload != nil {
+		t.Fatalf("expired upload = %+v, %v, want deleted", upload, err)
+	}
+	if _, err = tus.GetUpload(ctx, live.ID); err != nil {
+		t.Fatalf("GetUpload() of live upload error = %v", err)
+	}
+	// 过期上传的分片被删除，未过期上传的分片保留
+	parts, err := e.dbTusUpload.GetTusParts(ctx, live.ID)
+	if err != nil || len(parts) != 1 {
+		t.Fatalf("GetTusParts() = %d parts, %v, want 1", len(parts), err)
+	}
+	if objects := e.storage.objects(t); len(objects) != 1 || objects[0] != parts[0].ObjectName {
+		t.Fatalf("objects = %v, want only the live part %s", objects, parts[0].ObjectName)
+	}
+}
+
+// 创建上传后同名文件被其他请求创建，完成时返回错误并保留已接收的数据，释放文件名后可重新完成
+func TestTusCompleteLosesNameRace(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	tus := e.tus()
+
+	upload, err := tus.CreateUpload(ctx, "a.txt", "text/plain", 5, interfaces.ConflictReject, "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	winner := e.upload(t, "a.txt", "other", "")
+
+	if _, err = tus.AppendUpload(ctx, upload.ID, 0, 5, strings.NewReader("hello"), nil); httpStatus(err) != http.StatusBadRequest {
+		t.Fatalf("AppendUpload() error = %v, want 400", err)
+	}
+	current, err := tus.GetUpload(ctx, upload.ID)
+	if err != nil || current.FileID != "" || current.Offset != 5 {
+		t.Fatalf("GetUpload() = %+v, %v, want incomplete upload at offset 5", current, err)
+	}
+
+	if err = e.file.Delete(ctx, winner.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	completed, err := tus.AppendUpload(ctx, upload.ID, 5, 0, strings.NewReader(""), nil)
+	if err != nil || completed.FileID == "" {
+		t.Fatalf("AppendUpload() retry = %+v, %v, want completed upload", completed, err)
+	}
+	file, err := e.file.GetMeta(ctx, completed.FileID)
+	if err != nil || file.Name != "a.txt" || e.content(t, file) != "hello" {
+		t.Fatalf("GetMeta() = %+v, %v, want a.txt with the uploaded content", file, err)
+	}
+}
+
+func TestTusCreateRejectsExistingName(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEnv(t)
+	tus := e.tus()
+	e.upload(t, "a.txt", "hello", "")
+
+	tests := []struct {
+		conflict   string
+		wantStatus int
+	}{
+		{"", http.StatusBadRequest},
+		{interfaces.ConflictReject, http.StatusBadRequest},
+		{interfaces.ConflictOverwrite, 0},
+		{interfaces.ConflictRename, 0},
+		{"replace", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.conflict, func(t *testing.T) {
+			_, err := tus.CreateUpload(ctx, "a.txt", "text/plain", 5, tt.conflict, "")
+			if httpStatus(err) != tt.wantStatus || (tt.wantStatus == 0) != (err == nil) {
+				t.Fatalf("CreateUpload() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
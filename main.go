@@ -8,15 +8,18 @@ import (
 	"FileEngine/interfaces"
 	"FileEngine/logics"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 type Server struct {
-	config      *common.Config
-	fileHandler interfaces.RESTHandler
+	config           *common.Config
+	fileHandler      interfaces.RESTHandler
+	objectHandler    interfaces.RESTHandler
+	tusHandler       interfaces.RESTHandler
+	multipartHandler interfaces.RESTHandler
+	importHandler    interfaces.RESTHandler
 }
 
 func (s *Server) Start() {
@@ -28,6 +31,10 @@ func (s *Server) Start() {
 		server.Use(gin.Logger())
 
 		s.fileHandler.RegisterPublic(server)
+		s.objectHandler.RegisterPublic(server)
+		s.tusHandler.RegisterPublic(server)
+		s.multipartHandler.RegisterPublic(server)
+		s.importHandler.RegisterPublic(server)
 
 		if err := server.Run(s.config.Server.PublicAddr); err != nil {
 			log.Fatalf("Failed to start server: %v", err)
@@ -38,42 +45,94 @@ func (s *Server) Start() {
 func main() {
 	config := common.NewConfig()
 
-	log.Printf("config: %+v", config.Server)
-
-	minioClient, err := minio.New(config.Minio.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.Minio.AccessKey, config.Minio.SecretKey, ""),
-		Secure: false,
-	})
-	if err != nil {
-		log.Fatalf("Failed to initialize Minio client: %v", err)
+	// 迁移子命令：file_engine migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(config, os.Args[2:])
+		return
 	}
 
-	dbPool, err := common.NewDB(config)
-	if err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+	log.Printf("config: %+v", config.Server)
 
 	// 依赖注入
-	dbaccess.SetDBPool(dbPool)
-
 	drivenadapters.SetConfig(config)
-	drivenadapters.SetMinioClient(minioClient)
 
 	logics.SetConfig(config)
 
 	// 控制反转
-	dbFile := dbaccess.NewDBFile()
+	var dbFile interfaces.DBFile
+	var dbTusUpload interfaces.DBTusUpload
+	var dbMultipartUpload interfaces.DBMultipartUpload
+	var dbBlob interfaces.DBBlob
+	var dbImportJob interfaces.DBImportJob
+	var dbIdempotencyKey interfaces.DBIdempotencyKey
+	if config.DB.Type == "memory" {
+		// 内存模式不依赖数据库，重启后数据丢失
+		dbFile = dbaccess.NewMemoryDBFile()
+		dbTusUpload = dbaccess.NewMemoryDBTusUpload()
+		dbMultipartUpload = dbaccess.NewMemoryDBMultipartUpload()
+		dbBlob = dbaccess.NewMemoryDBBlob()
+		dbImportJob = dbaccess.NewMemoryDBImportJob()
+		dbIdempotencyKey = dbaccess.NewMemoryDBIdempotencyKey()
+	} else {
+		dbPool, err := common.NewDB(config)
+		if err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+		dbaccess.SetDBPool(dbPool)
+		dbaccess.SetDBType(config.DB.Type)
+		if err = checkSchema(config); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+		dbFile = dbaccess.NewDBFile()
+		dbTusUpload = dbaccess.NewDBTusUpload()
+		dbMultipartUpload = dbaccess.NewDBMultipartUpload()
+		dbBlob = dbaccess.NewDBBlob()
+		dbImportJob = dbaccess.NewDBImportJob()
+		dbIdempotencyKey = dbaccess.NewDBIdempotencyKey()
+	}
 
-	storageAdapter := drivenadapters.NewMinioAdapter()
+	storageAdapter, err := newStorageAdapter(config, dbBlob)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	signer, ok := storageAdapter.(interfaces.URLSigningStorage)
+	if err := config.CheckSignKey(ok && signer.SignsURLs()); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	auditLogger, err := common.NewAuditLogger(config)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
 
 	logics.SetDBFile(dbFile)
+	logics.SetDBTusUpload(dbTusUpload)
+	logics.SetDBMultipartUpload(dbMultipartUpload)
+	logics.SetDBImportJob(dbImportJob)
+	logics.SetDBIdempotencyKey(dbIdempotencyKey)
 	logics.SetStorageAdapter(storageAdapter)
+	logics.SetAuditLogger(auditLogger)
 
 	server := &Server{
-		config:      config,
-		fileHandler: driveradapters.NewFileHandler(),
+		config:           config,
+		fileHandler:      driveradapters.NewFileHandler(),
+		objectHandler:    driveradapters.NewObjectHandler(),
+		tusHandler:       driveradapters.NewTusHandler(),
+		multipartHandler: driveradapters.NewMultipartHandler(),
+		importHandler:    driveradapters.NewImportHandler(),
 	}
 	server.Start()
 
 	select {}
 }
+
+// newStorageAdapter 按配置创建存储适配器，开启去重时包装为去重存储
+func newStorageAdapter(config *common.Config, dbBlob interfaces.DBBlob) (interfaces.StorageAdapter, error) {
+	storageAdapter, err := drivenadapters.NewStorageAdapter(config.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config.Storage.Dedup != drivenadapters.DedupNone {
+		return drivenadapters.NewDedupAdapter(storageAdapter, dbBlob, config.Storage.Dedup)
+	}
+	return storageAdapter, nil
+}
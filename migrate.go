@@ -0,0 +1,154 @@
+package main
+
+import (
+	"FileEngine/common"
+	"FileEngine/dbaccess"
+	"FileEngine/drivenadapters"
+	"FileEngine/logics"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const migrateUsage = `usage: file_engine migrate <command>
+
+commands:
+  up           执行全部未执行的迁移
+  down [n]     回滚最近执行的n个迁移，默认1个
+  status       查看迁移执行状态
+  objects      将以文件名等旧格式命名的存储对象迁移到按ID分片的对象名，需在表结构迁移完成后执行`
+
+// runMigrate 执行迁移子命令
+func runMigrate(config *common.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+
+	if config.DB.Type == "memory" {
+		log.Fatalf("Memory database does not need migrations")
+	}
+
+	dbPool, err := common.NewDB(config)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+	defer dbPool.Close()
+	migrationDB, err := common.NewMigrationDB(config)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+	defer migrationDB.Close()
+
+	dbaccess.SetDBPool(dbPool)
+	dbaccess.SetDBType(config.DB.Type)
+
+	migrator, err := dbaccess.NewMigrator(migrationDB)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up(ctx)
+		for _, migration := range done {
+			log.Printf("applied %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		if len(done) == 0 {
+			log.Printf("schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps: %s", args[1])
+			}
+		}
+		done, err := migrator.Down(ctx, steps)
+		for _, migration := range done {
+			log.Printf("rolled back %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to roll back migrations: %v", err)
+		}
+	case "objects":
+		if err = migrator.Check(ctx); err != nil {
+			log.Fatalf("%v, run `file_engine migrate up` first", err)
+		}
+		rekeyObjects(config)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied at " + common.FormatTime(*status.AppliedTime)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+}
+
+// rekeyObjects 迁移存储对象的对象名，对象数量多时耗时较长，不设超时
+func rekeyObjects(config *common.Config) {
+	drivenadapters.SetConfig(config)
+	logics.SetConfig(config)
+
+	storageAdapter, err := newStorageAdapter(config, dbaccess.NewDBBlob())
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	logics.SetDBFile(dbaccess.NewDBFile())
+	logics.SetStorageAdapter(storageAdapter)
+
+	result, err := logics.RekeyObjects(context.Background())
+	log.Printf("rekeyed %d objects, skipped %d missing objects", result.Rekeyed, result.Skipped)
+	if err != nil {
+		log.Fatalf("Failed to rekey objects: %v", err)
+	}
+}
+
+// checkSchema 启动时检查表结构版本，开启autoMigrate时自动执行未执行的迁移
+func checkSchema(config *common.Config) error {
+	migrationDB, err := common.NewMigrationDB(config)
+	if err != nil {
+		return err
+	}
+	defer migrationDB.Close()
+
+	migrator, err := dbaccess.NewMigrator(migrationDB)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if config.DB.AutoMigrate {
+		done, err := migrator.Up(ctx)
+		for _, migration := range done {
+			log.Printf("applied migration %d_%s", migration.Version, migration.Name)
+		}
+		return err
+	}
+
+	if err = migrator.Check(ctx); err != nil {
+		return fmt.Errorf("%w, run `file_engine migrate up` first", err)
+	}
+
+	return nil
+}